@@ -0,0 +1,90 @@
+package binding
+
+import (
+	"fmt"
+	"strings"
+
+	"cp2p/config"
+)
+
+// Warning is an advisory finding about a configuration
+type Warning struct {
+	Function string // Function or type the finding concerns
+	Type     string // C type involved
+	Message  string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Function, w.Message)
+}
+
+// typeSize gives the size of a C type on Windows (LLP64) and on 64-bit
+// Linux/macOS (LP64), with portable alternatives
+type typeSize struct {
+	windows, unix int
+	instead       string
+}
+
+// platformDependentTypes lists the C types whose size differs between the
+// platforms cp2p targets, so data exchanged across them can be silently truncated
+var platformDependentTypes = map[string]typeSize{
+	"long":          {4, 8, "int32_t or int64_t"},
+	"unsigned long": {4, 8, "uint32_t or uint64_t"},
+	"wchar_t":       {2, 4, "char16_t or char32_t"},
+	"long double":   {8, 16, "double"},
+}
+
+// CheckABISafety returns advisory warnings for the C types in cfg whose size
+// on goos differs from their size on other supported platforms, such as long
+// being 32 bits on Windows but 64 bits on Linux. Pointers to such types are
+// reported too, since the pointed-to data has the same problem.
+func CheckABISafety(cfg *config.Config, goos string) []Warning {
+	var warnings []Warning
+
+	check := func(owner, t string) {
+		base := abiBaseType(t)
+		size, ok := platformDependentTypes[base]
+		if !ok {
+			return
+		}
+
+		here, other, otherOS := size.unix, size.windows, "Windows"
+		if goos == "windows" {
+			here, other, otherOS = size.windows, size.unix, "64-bit Linux/macOS"
+		}
+		warnings = append(warnings, Warning{
+			Function: owner,
+			Type:     t,
+			Message: fmt.Sprintf("%s is %d bytes on %s but %d bytes on %s; consider %s instead",
+				base, here, goos, other, otherOS, size.instead),
+		})
+	}
+
+	for _, fn := range cfg.Functions {
+		check(fn.Name, fn.ReturnType)
+		for _, p := range fn.Parameters {
+			check(fn.Name, p.Type)
+		}
+	}
+	for _, typ := range cfg.Types {
+		if typ.BaseType != "" {
+			check(typ.Name, typ.BaseType)
+		}
+		for _, f := range typ.Fields {
+			check(typ.Name, f.Type)
+		}
+	}
+
+	return warnings
+}
+
+// abiBaseType strips qualifiers and pointer/reference markers from t
+func abiBaseType(t string) string {
+	t = NormalizeType(t)
+	if i := strings.IndexAny(t, "*&"); i >= 0 {
+		t = t[:i]
+	}
+	t = strings.TrimPrefix(t, "const ")
+	t = strings.TrimPrefix(t, "volatile ")
+	return strings.TrimSpace(t)
+}
@@ -0,0 +1,40 @@
+package binding
+
+import (
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestCheckABISafety(t *testing.T) {
+	cfg := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int32_t"}, {Name: "b", Type: "int32_t"}}, ReturnType: "int32_t"},
+			{Name: "sum", Parameters: []config.Param{{Name: "values", Type: "const long *"}, {Name: "n", Type: "size_t"}}, ReturnType: "long int"},
+		},
+		Types: []config.TypeConfig{
+			{Name: "Stats", Kind: "struct", Fields: []config.Field{{Name: "count", Type: "unsigned long"}}},
+		},
+	}
+
+	for _, goos := range []string{"linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			warnings := CheckABISafety(cfg, goos)
+			if len(warnings) != 3 {
+				t.Fatalf("CheckABISafety() returned %d warnings, want 3: %v", len(warnings), warnings)
+			}
+			for _, w := range warnings {
+				if w.Function == "add" {
+					t.Errorf("Unexpected warning for fixed-width types: %v", w)
+				}
+				if !strings.Contains(w.Message, "long") || !strings.Contains(w.Message, goos) {
+					t.Errorf("Warning message %q should name the type and platform", w.Message)
+				}
+			}
+			if warnings[2].Function != "Stats" {
+				t.Errorf("Expected the struct field to be reported, got %v", warnings[2])
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package binding
+
+import (
+	"fmt"
+	"strings"
+
+	"cp2p/config"
+)
+
+// Kinds of APISymbol besides the kinds of configured types
+const (
+	SymbolFunction      = "function"
+	SymbolAsyncFunction = "async function"
+)
+
+// APISymbol is a name the generated module exports
+type APISymbol struct {
+	Name      string // Python name
+	Kind      string // SymbolFunction, SymbolAsyncFunction or the type's kind, e.g. "struct"
+	Signature string // Python signature of functions, e.g. "add(a: int, b: int) -> int"; empty for types
+}
+
+// PublicAPI returns the functions and types the generated module exports,
+// computed from the config without rendering the module
+func (g *PythonGenerator) PublicAPI() []APISymbol {
+	var api []APISymbol
+	returnClasses := returnClasses(g.types)
+	for _, fn := range g.bindableFunctions(&GenerationResult{}) {
+		params, ret := g.signature(fn, returnClasses)
+		api = append(api, APISymbol{
+			Name:      fn.PyName(),
+			Kind:      SymbolFunction,
+			Signature: fmt.Sprintf("%s(%s) -> %s", fn.PyName(), params, ret),
+		})
+		if g.config.Async || fn.Async {
+			api = append(api, APISymbol{
+				Name:      fn.PyName() + "_async",
+				Kind:      SymbolAsyncFunction,
+				Signature: fmt.Sprintf("%s_async(%s) -> %s", fn.PyName(), params, ret),
+			})
+		}
+	}
+	for _, typ := range g.types {
+		if g.exportsType(typ.Kind) {
+			api = append(api, APISymbol{Name: typ.Name, Kind: typ.Kind})
+		}
+	}
+	return api
+}
+
+// signature returns fn's parameter list and return type hint as the module
+// template renders them
+func (g *PythonGenerator) signature(fn config.FunctionConfig, returnClasses map[string]bool) (string, string) {
+	var params []string
+	for _, p := range fn.Inputs() {
+		params = append(params, p.Name+": "+g.paramHint(p)+pythonDefault(p.Default))
+	}
+	return strings.Join(params, ", "), g.returnHint(fn, returnClasses)
+}
+
+// paramHint returns the type hint of the value passed for or returned from p
+func (g *PythonGenerator) paramHint(p config.Param) string {
+	if p.PyType != "" {
+		return p.PyType
+	}
+	_, hint, _ := g.registry.Lookup(p.ValueType())
+	return hint
+}
+
+// returnHint returns the type hint of fn's return value
+func (g *PythonGenerator) returnHint(fn config.FunctionConfig, returnClasses map[string]bool) string {
+	ret := fn.ReturnPyType
+	if ret == "" && returnClasses[fn.ReturnType] && g.config.OutputBackend != BackendCFFI {
+		ret = fn.ReturnType
+	}
+	if ret == "" {
+		_, ret, _ = g.registry.Lookup(fn.ReturnType)
+	}
+	if ret == "" {
+		ret = "Any"
+	}
+	return ret
+}
+
+// exportsType reports whether the module defines a class or alias for types
+// of kind. cffi modules declare all but enums to cffi instead.
+func (g *PythonGenerator) exportsType(kind string) bool {
+	if g.config.OutputBackend == BackendCFFI {
+		return kind == "enum"
+	}
+	switch kind {
+	case "struct", "enum", "union", "opaque", "handle":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package binding
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestPublicAPI(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int", Default: "2"}}, ReturnType: "int"},
+			{Name: "origin", PythonName: "make_origin", Parameters: []config.Param{}, ReturnType: "Point", Async: true},
+			{Name: "scale", Parameters: []config.Param{{Name: "v", Type: "vec3"}}, ReturnType: "void"},
+		},
+		Types: []config.TypeConfig{
+			{Name: "Point", Kind: "struct", Fields: []config.Field{{Name: "x", Type: "double"}, {Name: "y", Type: "double"}}},
+			{Name: "Color", Kind: "enum", Values: []string{"RED", "GREEN"}},
+		},
+	}
+	gen := NewGenerator("geometry", "libgeometry.so", "", testConfig)
+
+	// Functions using unmapped types are not exported
+	want := []APISymbol{
+		{Name: "add", Kind: SymbolFunction, Signature: "add(a: int, b: int = 2) -> int"},
+		{Name: "make_origin", Kind: SymbolFunction, Signature: "make_origin() -> Point"},
+		{Name: "make_origin_async", Kind: SymbolAsyncFunction, Signature: "make_origin_async() -> Point"},
+		{Name: "Point", Kind: "struct"},
+		{Name: "Color", Kind: "enum"},
+	}
+	api := gen.PublicAPI()
+	if !slices.Equal(api, want) {
+		t.Fatalf("PublicAPI() = %+v, want %+v", api, want)
+	}
+
+	// The signatures are those the module is rendered with
+	var module strings.Builder
+	if err := gen.writeModule(&module, &GenerationResult{}); err != nil {
+		t.Fatalf("writeModule() error = %v", err)
+	}
+	for _, symbol := range api {
+		var def string
+		switch symbol.Kind {
+		case SymbolFunction:
+			def = "def " + symbol.Signature + ":"
+		case SymbolAsyncFunction:
+			def = "async def " + symbol.Signature + ":"
+		default:
+			def = "class " + symbol.Name + "("
+		}
+		if !strings.Contains(module.String(), def) {
+			t.Errorf("Module does not define %q", def)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package binding
+
+import (
+	"strings"
+)
+
+// registerCallbacks maps every callback type declared by the configured
+// functions to a ctypes.CFUNCTYPE prototype (WINFUNCTYPE for stdcall), so
+// parameters naming it bind like any other mapped type. Callbacks using
+// unmapped types are left unregistered, which leaves the functions taking
+// them unbound.
+func (g *PythonGenerator) registerCallbacks() {
+	for _, fn := range g.config.Functions {
+		for _, cb := range fn.Callbacks {
+			name := NormalizeType(cb.Name)
+			if g.callbacks[name] {
+				continue // Shared by an earlier function
+			}
+
+			types := append([]string{cb.ReturnType}, cb.Parameters...)
+			ctypesArgs := make([]string, len(types))
+			hints := make([]string, len(types))
+			mapped := true
+			for i, t := range types {
+				expr, hint, ok := g.registry.Lookup(t)
+				if !ok {
+					mapped = false
+					break
+				}
+				ctypesArgs[i], hints[i] = expr, hint
+			}
+			if !mapped {
+				continue
+			}
+
+			g.registry.Register(name,
+				g.functionType()+"("+strings.Join(ctypesArgs, ", ")+")",
+				"Callable[["+strings.Join(hints[1:], ", ")+"], "+hints[0]+"]")
+			g.callbacks[name] = true
+		}
+	}
+}
+
+// functionType returns the ctypes factory for callback prototypes in the
+// configured calling convention
+func (g *PythonGenerator) functionType() string {
+	if g.config.CallingConvention == ConventionStdcall {
+		return "(ctypes.WINFUNCTYPE if sys.platform == 'win32' else ctypes.CFUNCTYPE)"
+	}
+	return "ctypes.CFUNCTYPE"
+}
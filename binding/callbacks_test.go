@@ -0,0 +1,83 @@
+package binding
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+// callbackConfig binds void each(void (*visit)(int), int n)
+var callbackConfig = &config.Config{
+	Functions: []config.FunctionConfig{
+		{
+			Name:       "each",
+			Parameters: []config.Param{{Name: "visit", Type: "visit_fn"}, {Name: "n", Type: "int"}},
+			ReturnType: "void",
+			Callbacks:  []config.CallbackConfig{{Name: "visit_fn", ReturnType: "void", Parameters: []string{"int"}}},
+		},
+	},
+}
+
+func TestGenerateCallback(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	result, err := GenerateBindings("test", "test.dll", tmpDir, callbackConfig)
+	if err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if result.FunctionsBound != 1 {
+		t.Errorf("FunctionsBound = %d, want 1 (unmapped %v)", result.FunctionsBound, result.UnmappedTypes)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"'visit_fn': ctypes.CFUNCTYPE(None, ctypes.c_int),",
+		"def each(visit: Callable[[int], None], n: int) -> None:",
+		`visit = TYPE_MAPPING["visit_fn"](visit)`,
+		`_callbacks[("each", "visit")] = visit`,
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestCallbackInvokesPython(t *testing.T) {
+	python, err := FindPython()
+	if err != nil {
+		t.Skipf("Skipping callback test: %v", err)
+	}
+
+	// A stdcall module loads with the cdecl ctypes API outside Windows
+	for _, convention := range []string{ConventionCdecl, ConventionStdcall} {
+		t.Run(convention, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			libPath := buildLibrary(t, tmpDir, "each.cpp",
+				`extern "C" void each(void (*visit)(int), int n) { for (int i = 0; i < n; i++) visit(i); }`+"\n")
+			testConfig := *callbackConfig
+			testConfig.CallingConvention = convention
+			if _, err := GenerateBindings("each", libPath, tmpDir, &testConfig); err != nil {
+				t.Fatalf("GenerateBindings() error = %v", err)
+			}
+
+			script := "import sys; sys.path.insert(0, sys.argv[1]); import each\n" +
+				"seen = []\n" +
+				"each.each(seen.append, 3)\n" +
+				"assert seen == [0, 1, 2], seen\n"
+			cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("Callback script failed: %v\n%s", err, output)
+			}
+		})
+	}
+}
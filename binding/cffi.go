@@ -0,0 +1,127 @@
+package binding
+
+import (
+	"fmt"
+)
+
+// checkCFFISupport reports configuration features the cffi backend cannot generate
+func (g *PythonGenerator) checkCFFISupport() error {
+	if g.config.ArrayMode {
+		return fmt.Errorf("array_mode is only supported by the %s backend", BackendCtypes)
+	}
+	if g.config.CallingConvention == ConventionStdcall {
+		return fmt.Errorf("calling_convention %s is only supported by the %s backend", ConventionStdcall, BackendCtypes)
+	}
+	for _, fn := range g.functions {
+		if len(fn.Callbacks) > 0 {
+			return fmt.Errorf("callback parameters of %s are only supported by the %s backend", fn.Name, BackendCtypes)
+		}
+		if fn.ErrCheck != "" {
+			return fmt.Errorf("errcheck of %s is only supported by the %s backend", fn.Name, BackendCtypes)
+		}
+		if len(fn.Outputs()) > 0 {
+			return fmt.Errorf("out and inout parameters of %s are only supported by the %s backend", fn.Name, BackendCtypes)
+		}
+	}
+	for _, typ := range g.types {
+		if typ.Kind == "handle" {
+			return fmt.Errorf("handle type %s is only supported by the %s backend", typ.Name, BackendCtypes)
+		}
+	}
+	return nil
+}
+
+// cffiBindingTemplate is the parsed cffi binding template
+var cffiBindingTemplate = newBindingTemplate("cffi", cffiTemplate)
+
+// cffiTemplate is the template for generating cffi-based Python bindings. The
+// C declarations are passed to ffi.cdef and the library is opened in ABI mode,
+// so no C compiler is needed at import time.
+const cffiTemplate = `{{template "header" .}}import contextlib
+import sys
+import os
+from typing import {{.TypingImports}}
+{{if .HasAsync}}import asyncio
+import concurrent.futures
+{{end}}
+from cffi import FFI
+
+ffi = FFI()
+ffi.cdef("""
+{{range .Types}}{{if eq .Kind "struct"}}
+typedef struct {
+    {{range .Fields}}{{.Type}} {{.Name}};
+    {{end}}
+} {{.Name}};
+{{else if eq .Kind "union"}}
+typedef union {
+    {{range .Fields}}{{.Type}} {{.Name}};
+    {{end}}
+} {{.Name}};
+{{else if eq .Kind "enum"}}
+typedef enum {
+    {{range .Values}}{{.}},
+    {{end}}
+} {{.Name}};
+{{else if eq .Kind "opaque"}}
+typedef void* {{.Name}};
+{{end}}{{end}}
+{{range .Functions}}{{.ReturnType}} {{.CSymbol}}({{range $i, $p := .Parameters}}{{if $i}}, {{end}}{{$p.Type}} {{$p.Name}}{{else}}void{{end}});
+{{end}}
+""")
+
+# Python type hints mapping (always included)
+PYTHON_TYPE_HINTS = {
+    {{range $key, $value := .PythonTypeHints}}
+    '{{$key}}': '{{$value}}',
+    {{end}}
+}
+
+{{range .Types}}{{if eq .Kind "enum"}}
+class {{.Name}}:
+    """
+    {{doc .Description}}
+    """
+    {{range members .Values}}
+    {{.Name}} = {{.Value}}
+    {{end}}
+{{end}}{{end}}
+
+{{template "constants" .}}# Load the shared library
+{{template "libname" .}}
+
+{{template "loader" .}}
+{{template "libs" .}}
+{{if .HasAsync}}
+# Runs the blocking library calls behind the *_async wrappers. cffi releases
+# the GIL for the duration of each call, so they run in parallel with Python code.
+_executor = concurrent.futures.ThreadPoolExecutor(thread_name_prefix="{{.ModuleName}}")
+{{end}}
+{{range .Functions}}
+def {{.PyName}}({{range $i, $p := .Parameters}}{{if $i}}, {{end}}{{$p.Name}}: {{if $p.PyType}}{{$p.PyType}}{{else}}{{index $.PythonTypeHints $p.Type}}{{end}}{{default $p.Default}}{{end}}) -> {{if .ReturnPyType}}{{.ReturnPyType}}{{else}}{{with index $.PythonTypeHints .ReturnType}}{{.}}{{else}}Any{{end}}{{end}}:
+    """
+    {{doc .Description}}
+    {{if .Docstring}}
+    {{doc .Docstring}}
+    {{end}}
+    {{range .Parameters}}
+    Args:
+        {{.Name}} ({{if .PyType}}{{.PyType}}{{else}}{{index $.PythonTypeHints .Type}}{{end}}): {{doc .Description}}
+    {{end}}
+    Returns:
+        {{if .ReturnPyType}}{{.ReturnPyType}}{{else}}{{with index $.PythonTypeHints .ReturnType}}{{.}}{{else}}Any{{end}}{{end}}: {{doc .Description}}
+    """
+    return {{lib .CSymbol}}({{range $i, $p := .Parameters}}{{if $i}}, {{end}}{{$p.Name}}{{end}})
+{{if or $.Async .Async}}
+async def {{.PyName}}_async({{range $i, $p := .Parameters}}{{if $i}}, {{end}}{{$p.Name}}: {{if $p.PyType}}{{$p.PyType}}{{else}}{{index $.PythonTypeHints $p.Type}}{{end}}{{default $p.Default}}{{end}}) -> {{if .ReturnPyType}}{{.ReturnPyType}}{{else}}{{with index $.PythonTypeHints .ReturnType}}{{.}}{{else}}Any{{end}}{{end}}:
+    """
+    Awaitable version of {{.PyName}}, run in a worker thread so the event loop
+    is not blocked.
+    """
+    loop = asyncio.get_running_loop()
+    return await loop.run_in_executor(_executor, {{.PyName}}{{range .Parameters}}, {{.Name}}{{end}})
+{{end}}
+{{end}}
+
+__all__ = [{{range $i, $name := .Exports}}{{if $i}}, {{end}}'{{$name}}'{{end}}]
+`
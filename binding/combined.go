@@ -0,0 +1,84 @@
+package binding
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"cp2p/config"
+)
+
+// LibraryGroup is a shared library and the functions a combined module binds
+// from it
+type LibraryGroup struct {
+	LibPath   string   // Path of the compiled library; the loader opens it by file name
+	Functions []string // C names of the config functions the library exports
+}
+
+// GenerateCombinedBindings generates one Python module binding functions from
+// several libraries that form one API. Each function listed in a group is
+// called through that group's library. The first group's library is the
+// module's main library, which also provides functions listed in no group and
+// handle constructors and destructors; cfg.LibFileName renames it as for
+// GenerateBindings.
+func GenerateCombinedBindings(moduleName, outputDir string, groups []LibraryGroup, cfg *config.Config) (*GenerationResult, error) {
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no libraries to bind")
+	}
+	if cfg.OutputLang != "" && cfg.OutputLang != LangPython {
+		return nil, fmt.Errorf("combined modules are only generated for %s", LangPython)
+	}
+	if cfg.VerifyChecksum && len(groups) > 1 {
+		return nil, fmt.Errorf("verify_checksum is not supported for modules binding several libraries")
+	}
+	if cfg.EmbedLibrary && len(groups) > 1 {
+		return nil, fmt.Errorf("embed_library is not supported for modules binding several libraries")
+	}
+
+	symbols, err := symbolLibraries(groups, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	libFileName := filepath.Base(groups[0].LibPath)
+	if cfg.LibFileName != "" {
+		libFileName = cfg.LibFileName
+	}
+	gen := NewGenerator(moduleName, libFileName, outputDir, cfg)
+	gen.libFile = groups[0].LibPath
+	gen.symbolLibraries = symbols
+	return gen.generate()
+}
+
+// symbolLibraries maps the symbols of the functions in every group but the
+// first to the file name of their group's library
+func symbolLibraries(groups []LibraryGroup, cfg *config.Config) (map[string]string, error) {
+	symbols := map[string]string{}
+	seen := map[string]string{} // Function name -> library path
+	for _, group := range groups {
+		for _, name := range group.Functions {
+			fn := cfg.GetFunctionConfig(name)
+			if fn == nil {
+				return nil, fmt.Errorf("library %s lists unknown function %s", group.LibPath, name)
+			}
+			if other, ok := seen[name]; ok {
+				return nil, fmt.Errorf("function %s is listed for both %s and %s", name, other, group.LibPath)
+			}
+			seen[name] = group.LibPath
+
+			if group.LibPath != groups[0].LibPath {
+				symbols[fn.CSymbol()] = filepath.Base(group.LibPath)
+			}
+		}
+	}
+
+	// Libraries are opened by file name, so two must not share one
+	names := map[string]string{}
+	for _, group := range groups {
+		base := filepath.Base(group.LibPath)
+		if other, ok := names[base]; ok && other != group.LibPath {
+			return nil, fmt.Errorf("libraries %s and %s have the same file name", other, group.LibPath)
+		}
+		names[base] = group.LibPath
+	}
+	return symbols, nil
+}
@@ -0,0 +1,92 @@
+package binding
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestGenerateCombinedBindings(t *testing.T) {
+	tmpDir := t.TempDir()
+	alpha := buildLibrary(t, tmpDir, "alpha.cpp", `extern "C" int add(int a, int b) { return a + b; }`+"\n")
+	beta := buildLibrary(t, tmpDir, "beta.cpp",
+		`extern "C" int mul(int a, int b) { return a * b; }`+"\n"+
+			`extern "C" int beta_neg(int a) { return -a; }`+"\n")
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+			{Name: "mul", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+			{Name: "neg", Symbol: "beta_neg", Parameters: []config.Param{{Name: "a", Type: "int"}}, ReturnType: "int"},
+		},
+	}
+	groups := []LibraryGroup{
+		{LibPath: alpha, Functions: []string{"add"}},
+		{LibPath: beta, Functions: []string{"mul", "neg"}},
+	}
+	if _, err := GenerateCombinedBindings("mathapi", tmpDir, groups, testConfig); err != nil {
+		t.Fatalf("GenerateCombinedBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "mathapi.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	for _, expected := range []string{
+		"_LIB_NAME = '" + filepath.Base(alpha) + "'",
+		"'beta_neg': '" + filepath.Base(beta) + "',",
+		"'mul': '" + filepath.Base(beta) + "',",
+		"_lib = _Libraries()",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(string(content), "'add': '") {
+		t.Error("Functions of the main library should not be routed")
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import mathapi\n" +
+		"assert mathapi.add(2, 3) == 5\n" +
+		"assert mathapi.mul(2, 3) == 6\n" +
+		"assert mathapi.neg(4) == -4\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Combined module script failed: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateCombinedBindingsErrors(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{}, ReturnType: "int"},
+			{Name: "mul", Parameters: []config.Param{}, ReturnType: "int"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		groups []LibraryGroup
+	}{
+		{"no libraries", nil},
+		{"unknown function", []LibraryGroup{{LibPath: "liba.so", Functions: []string{"add"}}, {LibPath: "libb.so", Functions: []string{"div"}}}},
+		{"function in two libraries", []LibraryGroup{{LibPath: "liba.so", Functions: []string{"add"}}, {LibPath: "libb.so", Functions: []string{"add"}}}},
+		{"same file name", []LibraryGroup{{LibPath: "a/libm.so", Functions: []string{"add"}}, {LibPath: "b/libm.so", Functions: []string{"mul"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := GenerateCombinedBindings("test", t.TempDir(), tt.groups, testConfig); err == nil {
+				t.Error("GenerateCombinedBindings() should fail")
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package binding
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// constant is a module-level constant as rendered by the templates
+type constant struct {
+	Name        string
+	Hint        string
+	Value       string // Python literal
+	Description string
+}
+
+var (
+	intLiteralRe   = regexp.MustCompile(`^([-+]?)(0[xX][0-9a-fA-F]+|0[bB][01]+|0[0-7]+|[0-9]+)[uUlL]*$`)
+	floatLiteralRe = regexp.MustCompile(`^[-+]?([0-9]+\.[0-9]*|\.[0-9]+|[0-9]+)([eE][-+]?[0-9]+)?[fFlL]?$`)
+)
+
+// constants converts the configured constants to Python assignments
+func (g *PythonGenerator) constants() ([]constant, error) {
+	var result []constant
+	for _, c := range g.config.Constants {
+		value, err := pythonLiteral(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("constant %s: %v", c.Name, err)
+		}
+		_, hint, _ := g.registry.Lookup(c.Type)
+		result = append(result, constant{
+			Name:        pythonName(c.Name),
+			Hint:        hint,
+			Value:       value,
+			Description: c.Description,
+		})
+	}
+	return result, nil
+}
+
+// pythonLiteral converts a C literal to the equivalent Python literal
+func pythonLiteral(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch value {
+	case "true":
+		return "True", nil
+	case "false":
+		return "False", nil
+	case "NULL", "nullptr":
+		return "None", nil
+	}
+
+	// C string and character escapes are a subset of Python's
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value, nil
+	}
+
+	if m := intLiteralRe.FindStringSubmatch(value); m != nil {
+		digits := m[2]
+		if len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '7' {
+			digits = "0o" + digits[1:] // C octal
+		}
+		return m[1] + digits, nil
+	}
+	if floatLiteralRe.MatchString(value) {
+		return strings.TrimRight(value, "fFlL"), nil
+	}
+
+	return "", fmt.Errorf("unsupported value %q (want a number, string, character or boolean literal)", value)
+}
+
+// validateDefaults checks that every parameter default is a literal
+// pythonLiteral can convert
+func (g *PythonGenerator) validateDefaults() error {
+	for _, fn := range g.functions {
+		for _, p := range fn.Parameters {
+			if p.Default == "" {
+				continue
+			}
+			if _, err := pythonLiteral(p.Default); err != nil {
+				return fmt.Errorf("default of parameter %s of function %s: %v", p.Name, fn.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// pythonDefault returns the " = value" suffix declaring a parameter's
+// default in a Python signature, or "" if it has none
+func pythonDefault(value string) string {
+	if value == "" {
+		return ""
+	}
+	literal, _ := pythonLiteral(value) // Checked by validateDefaults
+	return " = " + literal
+}
@@ -0,0 +1,84 @@
+package binding
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestPythonLiteral(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "100", want: "100"},
+		{value: "-42L", want: "-42"},
+		{value: "0x1Fu", want: "0x1F"},
+		{value: "0755", want: "0o755"},
+		{value: "0", want: "0"},
+		{value: "2.5f", want: "2.5"},
+		{value: "1e-3", want: "1e-3"},
+		{value: `"v1.2"`, want: `"v1.2"`},
+		{value: `'x'`, want: `'x'`},
+		{value: "true", want: "True"},
+		{value: "nullptr", want: "None"},
+		{value: "MAX * 2", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := pythonLiteral(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("pythonLiteral(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("pythonLiteral(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateConstants(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+		Constants: []config.ConstantConfig{
+			{Name: "MAX", Type: "int", Value: "100", Description: "Largest supported size"},
+			{Name: "VERSION", Type: "const char*", Value: `"1.2 <beta>"`},
+		},
+	}
+
+	for _, backend := range []string{BackendCtypes, BackendCFFI} {
+		testConfig.OutputBackend = backend
+		var buf bytes.Buffer
+		if err := GenerateBindingsTo(&buf, "test", "test.dll", testConfig); err != nil {
+			t.Fatalf("%s: GenerateBindingsTo() error = %v", backend, err)
+		}
+
+		expectedStrings := []string{
+			"MAX: int = 100  # Largest supported size\n",
+			`VERSION: str = "1.2 <beta>"` + "\n",
+			"__all__ = ['add', 'MAX', 'VERSION']",
+		}
+		for _, expected := range expectedStrings {
+			if !strings.Contains(buf.String(), expected) {
+				t.Errorf("%s: generated module missing expected content: %s", backend, expected)
+			}
+		}
+	}
+}
+
+func TestGenerateInvalidConstant(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{{Name: "add", ReturnType: "int"}},
+		Constants: []config.ConstantConfig{{Name: "TWICE", Type: "int", Value: "MAX * 2"}},
+	}
+	var buf bytes.Buffer
+	err := GenerateBindingsTo(&buf, "test", "test.dll", testConfig)
+	if err == nil || !strings.Contains(err.Error(), "TWICE") {
+		t.Errorf("GenerateBindingsTo() error = %v, want error naming TWICE", err)
+	}
+}
@@ -0,0 +1,51 @@
+package binding
+
+import (
+	"fmt"
+	"strings"
+
+	"cp2p/config"
+)
+
+// errCheckConditions are the Python conditions on result under which each
+// errcheck raises. ctypes returns None for NULL char and void pointers and a
+// false pointer instance for other NULL pointers.
+var errCheckConditions = map[string]string{
+	config.ErrCheckNull:     "result is None or isinstance(result, ctypes._Pointer) and not result",
+	config.ErrCheckNegative: "result < 0",
+	config.ErrCheckNonZero:  "result != 0",
+}
+
+// errCheckCondition returns the condition under which the errcheck check fails
+func errCheckCondition(check string) string {
+	return errCheckConditions[check]
+}
+
+// validateErrChecks checks that each function's errcheck suits its return
+// type: null needs a pointer, negative and nonzero an integer
+func (g *PythonGenerator) validateErrChecks() error {
+	for _, fn := range g.functions {
+		if fn.ErrCheck == "" {
+			continue
+		}
+		expr, _, _ := g.registry.Lookup(fn.ReturnType)
+		switch fn.ErrCheck {
+		case config.ErrCheckNull:
+			if !strings.HasSuffix(fn.ReturnType, "*") && !pointerCtypes[expr] && !strings.HasPrefix(expr, "ctypes.POINTER(") {
+				return fmt.Errorf("errcheck %s of function %s needs a pointer return type, not %s", fn.ErrCheck, fn.Name, fn.ReturnType)
+			}
+		case config.ErrCheckNegative, config.ErrCheckNonZero:
+			if !integerCtypes[expr] {
+				return fmt.Errorf("errcheck %s of function %s needs an integer return type, not %s", fn.ErrCheck, fn.Name, fn.ReturnType)
+			}
+		}
+	}
+	return nil
+}
+
+// pointerCtypes are the ctypes pointer types that are not ctypes.POINTER instances
+var pointerCtypes = map[string]bool{
+	"ctypes.c_void_p":  true,
+	"ctypes.c_char_p":  true,
+	"ctypes.c_wchar_p": true,
+}
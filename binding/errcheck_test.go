@@ -0,0 +1,88 @@
+package binding
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestGenerateErrCheck(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "parse", Parameters: []config.Param{{Name: "x", Type: "int"}}, ReturnType: "int", ErrCheck: config.ErrCheckNegative},
+			{Name: "lookup", Parameters: []config.Param{{Name: "i", Type: "int"}}, ReturnType: "const char*", ErrCheck: config.ErrCheckNull},
+			{Name: "plain", ReturnType: "int"},
+		},
+	}
+
+	var module strings.Builder
+	gen := NewGenerator("checked", "libchecked.so", "", testConfig)
+	if err := gen.writeModule(&module, &GenerationResult{}); err != nil {
+		t.Fatalf("writeModule() error = %v", err)
+	}
+	for _, expected := range []string{
+		"def _errcheck_parse(result, func, args):",
+		"    if result < 0:",
+		"_lib.parse.errcheck = _errcheck_parse",
+		"def _errcheck_lookup(result, func, args):",
+		"_lib.lookup.errcheck = _errcheck_lookup",
+	} {
+		if !strings.Contains(module.String(), expected) {
+			t.Errorf("Generated module missing %q", expected)
+		}
+	}
+	if strings.Contains(module.String(), "_errcheck_plain") {
+		t.Error("Generated module has an errcheck for a function without one configured")
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	tmpDir := t.TempDir()
+	libPath := buildLibrary(t, tmpDir, "checked.cpp", `extern "C" int parse(int x) { return x < 0 ? -1 : x * 2; }
+extern "C" const char* lookup(int i) { return i == 1 ? "one" : 0; }
+extern "C" int plain(void) { return -5; }
+`)
+	if _, err := GenerateBindings("checked", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import checked\n" +
+		"assert checked.parse(21) == 42\n" +
+		"try:\n    checked.parse(-3)\n    raise AssertionError('parse(-3) did not raise')\nexcept RuntimeError as e:\n    assert 'parse returned -1' in str(e), e\n" +
+		"assert checked.lookup(1) == b'one'\n" +
+		"try:\n    checked.lookup(2)\n    raise AssertionError('lookup(2) did not raise')\nexcept RuntimeError:\n    pass\n" +
+		"assert checked.plain() == -5\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("errcheck script failed: %v\n%s", err, output)
+	}
+}
+
+func TestValidateErrChecks(t *testing.T) {
+	tests := []struct {
+		name       string
+		returnType string
+		check      string
+		wantErr    bool
+	}{
+		{"null on pointer", "void*", config.ErrCheckNull, false},
+		{"nonzero on integer", "int32_t", config.ErrCheckNonZero, false},
+		{"null on integer", "int", config.ErrCheckNull, true},
+		{"negative on double", "double", config.ErrCheckNegative, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Functions: []config.FunctionConfig{{Name: "f", ReturnType: tt.returnType, ErrCheck: tt.check}},
+			}
+			err := NewGenerator("m", "libm.so", "", cfg).validateErrChecks()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateErrChecks() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
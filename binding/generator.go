@@ -1,84 +1,399 @@
 package binding
 
 import (
+	"encoding/base64"
 	"fmt"
-	"html/template"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"text/template"
+	"time"
 
 	"cp2p/config"
+	"cp2p/util"
 )
 
-// Generator handles the generation of Python bindings
-type Generator struct {
-	moduleName string
-	libPath    string
-	outputDir  string
-	config     *config.Config
+// PythonGenerator generates Python bindings using ctypes or cffi
+type PythonGenerator struct {
+	moduleName  string
+	libPath     string
+	outputDir   string
+	config      *config.Config
+	functions   []config.FunctionConfig // Configured functions with normalized types
+	types       []config.TypeConfig     // Configured types with normalized types
+	registry    *TypeRegistry           // Type mappings, including config-declared ones
+	arrayDtypes map[string]string       // C pointer type -> numpy dtype, in array mode
+	callbacks   map[string]bool         // Registered callback type names
+	structPtrs  map[string]string       // Pointer to a declared struct or union -> its class, for ctypes
+	libFile     string                  // Path of the compiled library, for its checksum
+	libSHA256   string                  // Hex SHA-256 of libFile, if VerifyChecksum is set
+	libBase64   []string                // Base64 of libFile split into lines, if EmbedLibrary is set
+
+	// symbolLibraries maps the symbols of a combined module that are not
+	// bound from libPath to the file name of their library
+	symbolLibraries map[string]string
 }
 
 // NewGenerator creates a new binding generator
-func NewGenerator(moduleName, libPath, outputDir string, cfg *config.Config) *Generator {
-	return &Generator{
-		moduleName: moduleName,
-		libPath:    libPath,
-		outputDir:  outputDir,
-		config:     cfg,
+func NewGenerator(moduleName, libPath, outputDir string, cfg *config.Config) *PythonGenerator {
+	g := &PythonGenerator{
+		moduleName:  moduleName,
+		libPath:     libPath,
+		outputDir:   outputDir,
+		config:      cfg,
+		registry:    DefaultTypeRegistry(),
+		arrayDtypes: map[string]string{},
+		callbacks:   map[string]bool{},
+		structPtrs:  map[string]string{},
+	}
+
+	if cfg.ArrayMode {
+		for cType, array := range arrayTypes {
+			g.registry.Register(cType, array.ctype, "np.ndarray")
+			g.arrayDtypes[cType] = array.dtype
+		}
+	}
+
+	for _, m := range cfg.TypeMappings {
+		g.registry.Register(m.CType, m.Ctypes, m.PythonHint)
+	}
+	for _, typ := range cfg.Types {
+		if typ.Kind == "opaque" {
+			// ctypes accepts and returns c_void_p values as int or None
+			g.registry.Register(typ.Name, "ctypes.c_void_p", g.optionalHint("int"))
+		}
 	}
+	g.registerStructPointers()
+	g.registerCallbacks()
+
+	for _, fn := range cfg.Functions {
+		if cfg.IsExcluded(fn) {
+			continue
+		}
+		g.functions = append(g.functions, sanitizeNames(normalizeFunction(fn)))
+	}
+	for _, typ := range cfg.Types {
+		g.types = append(g.types, normalizeTypeConfig(typ))
+	}
+
+	return g
+}
+
+// GenerationResult summarizes what a generation run produced
+type GenerationResult struct {
+	FunctionsBound   int      // Functions with a generated wrapper
+	TypesGenerated   int      // Types with a generated class
+	SkippedFunctions []string // Functions skipped because they use an unmapped type
+	UnmappedTypes    []string // Distinct C types with no ctypes mapping
+	FilesWritten     []string // Paths of all files written
 }
 
-// GenerateBindings generates Python bindings for the C++ library
-func GenerateBindings(moduleName, libPath, outputDir string, cfg *config.Config) error {
-	gen := NewGenerator(moduleName, filepath.Base(libPath), outputDir, cfg)
-	return gen.generate()
+// Output backends, selecting the Python FFI the generated module uses
+const (
+	BackendCtypes = "ctypes"
+	BackendCFFI   = "cffi"
+)
+
+// Calling conventions of the bound functions. They differ only on 32-bit
+// Windows, where stdcall functions are loaded with ctypes.WinDLL; elsewhere,
+// where ctypes has no WinDLL, stdcall modules fall back to ctypes.CDLL.
+const (
+	ConventionCdecl   = "cdecl"
+	ConventionStdcall = "stdcall"
+)
+
+// arrayTypes lists the pointer types that array mode accepts as numpy arrays
+var arrayTypes = map[string]struct{ ctype, dtype string }{
+	"int*":    {"ctypes.POINTER(ctypes.c_int)", "np.intc"},
+	"float*":  {"ctypes.POINTER(ctypes.c_float)", "np.float32"},
+	"double*": {"ctypes.POINTER(ctypes.c_double)", "np.float64"},
 }
 
-func (g *Generator) generate() error {
+// GenerateBindings generates Python bindings for the C++ library. The loader
+// opens the library by the file name of libPath, or by cfg.LibFileName if set,
+// e.g. to load a versioned "libfoo.so.1".
+func GenerateBindings(moduleName, libPath, outputDir string, cfg *config.Config) (*GenerationResult, error) {
+	gen, err := NewLanguageGenerator(cfg.OutputLang, moduleName, libPath, outputDir, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return gen.Generate()
+}
+
+// GenerateBindingsTo writes the Python module for the C++ library to w instead
+// of a file, e.g. to preview it. The library is named as for GenerateBindings;
+// no requirements file is written.
+func GenerateBindingsTo(w io.Writer, moduleName, libPath string, cfg *config.Config) error {
+	libFileName := filepath.Base(libPath)
+	if cfg.LibFileName != "" {
+		libFileName = cfg.LibFileName
+	}
+	gen := NewGenerator(moduleName, libFileName, "", cfg)
+	gen.libFile = libPath
+	return gen.writeModule(w, &GenerationResult{})
+}
+
+// writeFile atomically writes a generated file with the configured line endings
+func (g *PythonGenerator) writeFile(path string, write func(io.Writer) error) error {
+	return util.WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		return write(util.NewlineWriter(w, g.config.LineEndings == config.LineEndingsCRLF))
+	})
+}
+
+func (g *PythonGenerator) generate() (*GenerationResult, error) {
 	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(g.outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %v", err)
+	if err := util.EnsureWritableDir(g.outputDir); err != nil {
+		return nil, fmt.Errorf("failed to prepare output directory: %w", err)
 	}
 
-	// Generate the Python binding file
+	// Generate the Python binding file, atomically so a failure never leaves a
+	// truncated module behind
+	result := &GenerationResult{}
 	outputPath := filepath.Join(g.outputDir, g.moduleName+".py")
-	file, err := os.Create(outputPath)
+	err := g.writeFile(outputPath, func(w io.Writer) error {
+		return g.writeModule(w, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	result.FilesWritten = append(result.FilesWritten, outputPath)
+
+	// Write the Python dependencies of the features used; empty if there are none
+	requirementsPath := filepath.Join(g.outputDir, "requirements.txt")
+	err = g.writeFile(requirementsPath, func(w io.Writer) error {
+		for _, req := range g.requirements() {
+			if _, err := fmt.Fprintln(w, req); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write requirements: %w", err)
+	}
+	result.FilesWritten = append(result.FilesWritten, requirementsPath)
+
+	if g.config.GenerateTests {
+		testsPath := filepath.Join(g.outputDir, "test_"+g.moduleName+".py")
+		err = g.writeFile(testsPath, func(w io.Writer) error {
+			return g.writeTests(w, g.bindableFunctions(&GenerationResult{}))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to write tests: %w", err)
+		}
+		result.FilesWritten = append(result.FilesWritten, testsPath)
+	}
+
+	if err := g.writeManifest(result); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return result, nil
+}
+
+// writeModule writes the Python module to w, recording what it bound in result
+func (g *PythonGenerator) writeModule(w io.Writer, result *GenerationResult) error {
+	if err := g.validateTypes(); err != nil {
+		return err
+	}
+	if err := g.validateIdentifiers(); err != nil {
+		return err
+	}
+	if err := g.validateDefaults(); err != nil {
+		return err
+	}
+	if err := g.validateOutputs(); err != nil {
+		return err
+	}
+	if err := g.validateErrChecks(); err != nil {
+		return err
+	}
+	switch g.config.CallingConvention {
+	case "", ConventionCdecl, ConventionStdcall:
+	default:
+		return fmt.Errorf("unknown calling convention %q (expected %s or %s)", g.config.CallingConvention, ConventionCdecl, ConventionStdcall)
+	}
+	if g.config.PythonVersion != "" {
+		if _, err := parsePythonVersion(g.config.PythonVersion); err != nil {
+			return err
+		}
+	}
+
+	tmpl, err := g.template()
 	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
+		return err
 	}
-	defer file.Close()
 
-	// Generate the binding code
-	if err := g.generateBindingCode(file); err != nil {
+	if err := util.ValidateLibrarySearchOrder(g.config.LibrarySearchPath); err != nil {
 		return err
 	}
 
-	return nil
+	if g.config.VerifyChecksum {
+		if g.libSHA256, err = util.FileSHA256(g.libFile); err != nil {
+			return fmt.Errorf("failed to checksum library: %v", err)
+		}
+	}
+
+	if g.config.EmbedLibrary {
+		if len(g.platformLibNames()) > 0 {
+			return fmt.Errorf("embed_library cannot be combined with per-platform library names")
+		}
+		lib, err := os.ReadFile(g.libFile)
+		if err != nil {
+			return fmt.Errorf("failed to read library to embed: %v", err)
+		}
+		g.libBase64 = base64Lines(lib)
+	}
+
+	result.TypesGenerated = len(g.types)
+	functions := g.bindableFunctions(result)
+	result.FunctionsBound = len(functions)
+
+	return g.generateBindingCode(w, tmpl, functions)
 }
 
-func (g *Generator) generateBindingCode(file *os.File) error {
-	// Define the template for the Python binding using html/template for security
-	tmpl := template.Must(template.New("binding").Parse(pythonBindingTemplate))
+// base64Lines encodes data as base64 split into lines short enough to keep
+// the module readable by editors
+func base64Lines(data []byte) []string {
+	const lineLength = 76
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var lines []string
+	for len(encoded) > lineLength {
+		lines = append(lines, encoded[:lineLength])
+		encoded = encoded[lineLength:]
+	}
+	return append(lines, encoded)
+}
 
-	// Define type mappings
-	typeMappings := map[string]string{
-		"int":         "ctypes.c_int",
-		"float":       "ctypes.c_float",
-		"double":      "ctypes.c_double",
-		"char":        "ctypes.c_char",
-		"bool":        "ctypes.c_bool",
-		"void":        "None",
-		"const char*": "ctypes.c_char_p",
+// integerCtypes are the ctypes integer types, whose arguments strict_int
+// checks for overflow
+var integerCtypes = map[string]bool{
+	"ctypes.c_byte": true, "ctypes.c_ubyte": true,
+	"ctypes.c_short": true, "ctypes.c_ushort": true,
+	"ctypes.c_int": true, "ctypes.c_uint": true,
+	"ctypes.c_long": true, "ctypes.c_ulong": true,
+	"ctypes.c_longlong": true, "ctypes.c_ulonglong": true,
+	"ctypes.c_size_t": true, "ctypes.c_ssize_t": true,
+	"ctypes.c_int8": true, "ctypes.c_uint8": true,
+	"ctypes.c_int16": true, "ctypes.c_uint16": true,
+	"ctypes.c_int32": true, "ctypes.c_uint32": true,
+	"ctypes.c_int64": true, "ctypes.c_uint64": true,
+}
+
+// checkedIntegerTypes returns the C types whose arguments are checked for
+// overflow: those mapped to a ctypes integer type if StrictInt is set, and
+// none otherwise
+func (g *PythonGenerator) checkedIntegerTypes() map[string]bool {
+	if !g.config.StrictInt {
+		return nil
+	}
+	types := map[string]bool{}
+	for cType, expr := range g.registry.ctypes {
+		if integerCtypes[expr] {
+			types[cType] = true
+		}
 	}
+	return types
+}
 
-	pythonTypeHints := map[string]string{
-		"int":         "int",
-		"float":       "float",
-		"double":      "float",
-		"char":        "str",
-		"bool":        "bool",
-		"void":        "None",
-		"const char*": "str",
+// requirements returns the third-party Python packages the generated module imports
+func (g *PythonGenerator) requirements() []string {
+	var reqs []string
+	if g.config.ArrayMode {
+		reqs = append(reqs, "numpy")
+	}
+	if g.config.OutputBackend == BackendCFFI {
+		reqs = append(reqs, "cffi")
+	}
+	return reqs
+}
+
+// libOpen returns the Python expression the loader opens the library with
+func (g *PythonGenerator) libOpen() string {
+	if g.config.OutputBackend == BackendCFFI {
+		return "ffi.dlopen"
+	}
+	if g.config.CallingConvention == ConventionStdcall {
+		return "(ctypes.WinDLL if sys.platform == 'win32' else ctypes.CDLL)"
+	}
+	return "ctypes.CDLL"
+}
+
+// librarySearchOrder returns the locations the loader searches for the
+// library, in order
+func (g *PythonGenerator) librarySearchOrder() []string {
+	if len(g.config.LibrarySearchPath) == 0 {
+		return util.DefaultLibrarySearchOrder
+	}
+	return g.config.LibrarySearchPath
+}
+
+// template returns the module template for the configured output backend
+func (g *PythonGenerator) template() (*template.Template, error) {
+	switch g.config.OutputBackend {
+	case "", BackendCtypes:
+		return bindingTemplate, nil
+	case BackendCFFI:
+		if err := g.checkCFFISupport(); err != nil {
+			return nil, err
+		}
+		return cffiBindingTemplate, nil
+	default:
+		return nil, fmt.Errorf("unknown output backend %q (expected %s or %s)", g.config.OutputBackend, BackendCtypes, BackendCFFI)
+	}
+}
+
+// bindableFunctions returns the configured functions whose types all have a
+// ctypes mapping, recording the rest in result. Declared structs and unions
+// may also be returned by value.
+func (g *PythonGenerator) bindableFunctions(result *GenerationResult) []config.FunctionConfig {
+	var functions []config.FunctionConfig
+	unmapped := make(map[string]bool)
+	returnClasses := returnClasses(g.types)
+
+	for _, fn := range g.functions {
+		var types []string
+		if !returnClasses[fn.ReturnType] {
+			types = append(types, fn.ReturnType)
+		}
+		for _, p := range fn.Parameters {
+			types = append(types, p.ValueType())
+		}
+
+		ok := true
+		for _, t := range types {
+			if !g.registry.Has(t) {
+				ok = false
+				if !unmapped[t] {
+					unmapped[t] = true
+					result.UnmappedTypes = append(result.UnmappedTypes, t)
+				}
+			}
+		}
+
+		if ok {
+			if len(fn.Outputs()) > 0 {
+				fn.ReturnPyType = g.outputReturnHint(fn, returnClasses)
+			}
+			functions = append(functions, fn)
+		} else {
+			result.SkippedFunctions = append(result.SkippedFunctions, fn.Name)
+		}
+	}
+
+	return functions
+}
+
+func (g *PythonGenerator) generateBindingCode(w io.Writer, tmpl *template.Template, functions []config.FunctionConfig) error {
+	constants, err := g.constants()
+	if err != nil {
+		return err
+	}
+	types, err := orderTypes(g.types)
+	if err != nil {
+		return err
 	}
 
 	// Prepare template data
@@ -88,32 +403,389 @@ func (g *Generator) generateBindingCode(file *os.File) error {
 		Functions       []config.FunctionConfig
 		Platform        string
 		Types           []config.TypeConfig
-		TypeMappings    map[string]string
-		PythonTypeHints map[string]string
+		TypeMappings    map[string]string // Templates range over maps in sorted key order,
+		PythonTypeHints map[string]string // so the emitted dicts are stable across runs
+		ArrayMode       bool
+		ArrayDtypes     map[string]string
+		DeclaredTypes   map[string]bool
+		ReturnClasses   map[string]bool
+		Async           bool
+		HasAsync        bool
+		Callbacks       map[string]bool
+		StructPointers  map[string]string
+		IntegerTypes    map[string]bool
+		LibNames        []platformLibName
+		LibOpen         string
+		TypingImports   string
+		LibSHA256       string
+		LibBase64       []string
+		LibSearch       []string
+		SymbolLibraries map[string]string
+		DependencyDirs  []string
+		Constants       []constant
+		Exports         []string
+		SourceFile      string
+		Timestamp       string
+		Docstring       string
 	}{
 		ModuleName:      g.moduleName,
 		LibPath:         g.libPath,
-		Functions:       g.config.Functions,
+		Functions:       functions,
 		Platform:        runtime.GOOS,
-		Types:           g.config.Types,
-		TypeMappings:    typeMappings,
-		PythonTypeHints: pythonTypeHints,
+		Types:           types,
+		TypeMappings:    g.typeMappings(),
+		PythonTypeHints: g.registry.hints,
+		ArrayMode:       g.config.ArrayMode,
+		ArrayDtypes:     g.arrayDtypes,
+		DeclaredTypes:   declaredTypes(g.types),
+		ReturnClasses:   returnClasses(g.types),
+		Async:           g.config.Async,
+		HasAsync:        hasAsync(g.config.Async, functions),
+		Callbacks:       g.callbacks,
+		StructPointers:  g.structPtrs,
+		IntegerTypes:    g.checkedIntegerTypes(),
+		LibNames:        g.platformLibNames(),
+		LibOpen:         g.libOpen(),
+		TypingImports:   strings.Join(g.typingImports(), ", "),
+		LibSHA256:       g.libSHA256,
+		LibBase64:       g.libBase64,
+		LibSearch:       g.librarySearchOrder(),
+		SymbolLibraries: g.symbolLibraries,
+		DependencyDirs:  g.config.DependencyDirs,
+		Constants:       constants,
+		Exports:         g.exports(functions, constants, types),
+		SourceFile:      g.sourceFile(),
+		Timestamp:       g.timestamp(),
+		Docstring:       g.docstring(),
 	}
 
 	// Execute the template
-	if err := tmpl.Execute(file, data); err != nil {
+	if err := tmpl.Execute(w, data); err != nil {
 		return fmt.Errorf("failed to generate binding code: %v", err)
 	}
 
 	return nil
 }
 
+// exports returns the names the module lists in __all__: the wrappers with
+// their async variants, the constants, and the classes generated for types.
+// cffi modules declare their types to cffi, except enums.
+func (g *PythonGenerator) exports(functions []config.FunctionConfig, constants []constant, types []config.TypeConfig) []string {
+	var names []string
+	for _, fn := range functions {
+		names = append(names, fn.PyName())
+		if g.config.Async || fn.Async {
+			names = append(names, fn.PyName()+"_async")
+		}
+	}
+	for _, c := range constants {
+		names = append(names, c.Name)
+	}
+	for _, typ := range types {
+		if g.config.OutputBackend != BackendCFFI || typ.Kind == "enum" {
+			names = append(names, typ.Name)
+		}
+	}
+	return names
+}
+
+// now returns the current time; tests replace it
+var now = time.Now
+
+// timestamp returns the generation time for the module header, or "" if it
+// is omitted
+func (g *PythonGenerator) timestamp() string {
+	if g.config.OmitTimestamp || g.config.Reproducible {
+		return ""
+	}
+	return now().UTC().Format(time.RFC3339)
+}
+
+// sourceFile returns the source file named in the module header. Reproducible
+// builds name an absolute path by its base name, and use forward slashes so
+// the header does not depend on the host.
+func (g *PythonGenerator) sourceFile() string {
+	src := g.config.SourceFile
+	if !g.config.Reproducible {
+		return src
+	}
+	if filepath.IsAbs(src) {
+		src = filepath.Base(src)
+	}
+	return filepath.ToSlash(src)
+}
+
+// docstring returns the module docstring
+func (g *PythonGenerator) docstring() string {
+	if g.config.ModuleDocstring != "" {
+		return g.config.ModuleDocstring
+	}
+	return fmt.Sprintf("Python bindings for the %s library.", g.moduleName)
+}
+
+// hasAsync reports whether any function gets an async wrapper
+func hasAsync(all bool, functions []config.FunctionConfig) bool {
+	if all {
+		return len(functions) > 0
+	}
+	for _, fn := range functions {
+		if fn.Async {
+			return true
+		}
+	}
+	return false
+}
+
+// bindingTemplate is the parsed Python binding template
+var bindingTemplate = newBindingTemplate("binding", pythonBindingTemplate)
+
+// Escapers for text from the config, which templates render verbatim
+var (
+	docstringEscaper    = strings.NewReplacer(`\`, `\\`, `"""`, `\"\"\"`)
+	commentEscaper      = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+	pythonStringEscaper = strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`)
+)
+
+// templateFuncs escape config text for where it appears in the module: doc
+// for triple-quoted docstrings, comment for line comments and pystr for
+// single-quoted string literals. lib refers to a symbol of the library.
+var templateFuncs = template.FuncMap{
+	"doc":      docstringEscaper.Replace,
+	"comment":  commentEscaper.Replace,
+	"pystr":    pythonStringEscaper.Replace,
+	"lib":      libAttr,
+	"default":  pythonDefault,
+	"results":  outputResults,
+	"errcheck": errCheckCondition,
+	"members":  enumMembers,
+}
+
+// newBindingTemplate parses a module template together with the shared
+// library loader templates. Text is rendered verbatim, so templates pass
+// config text through the escapers in templateFuncs.
+func newBindingTemplate(name, text string) *template.Template {
+	t := template.Must(template.New(name).Funcs(templateFuncs).Parse(text))
+	t = template.Must(t.Parse(headerTemplate))
+	t = template.Must(t.Parse(constantsTemplate))
+	return template.Must(t.Parse(libraryLoaderTemplate))
+}
+
+// headerTemplate defines the "header" template, a provenance comment followed
+// by the module docstring
+const headerTemplate = `{{define "header"}}# Generated by cp2p{{with .SourceFile}} from {{comment .}}{{end}}{{with .Timestamp}} on {{.}}{{end}}. Do not edit.
+"""
+{{doc .Docstring}}
+"""
+{{end}}`
+
+// constantsTemplate defines the "constants" template, which assigns the
+// configured constants at module level
+const constantsTemplate = `{{define "constants"}}{{if .Constants}}# Constants
+{{range .Constants}}{{.Name}}{{with .Hint}}: {{.}}{{end}} = {{.Value}}{{with .Description}}  # {{comment .}}{{end}}
+{{end}}
+{{end}}{{end}}`
+
+// libraryLoaderTemplate defines the "libname" template, which sets the library
+// file name, and the "loader" template, which finds and opens the library with
+// the backend's LibOpen function, verifying its checksum first if one is set.
+// Locations are searched in LibSearch order; the dynamic loader's search path
+// is skipped when there is a checksum, since the file opened is unknown. The
+// loader also makes the libraries in DependencyDirs loadable, and defines how
+// to extract a library embedded as LibBase64. The "libs" template loads the
+// libraries into _lib, routing symbols listed in SymbolLibraries to their
+// library.
+const libraryLoaderTemplate = `{{define "libname"}}{{if .LibNames}}# Library file name for each sys.platform
+_LIB_NAMES = {
+    {{range .LibNames}}
+    '{{.Platform}}': '{{pystr .FileName}}',
+    {{end}}
+}
+_LIB_NAME = _LIB_NAMES.get(sys.platform, '{{pystr .LibPath}}'){{else}}_LIB_NAME = '{{pystr .LibPath}}'{{end}}{{end}}
+{{define "loader"}}{{if .LibSHA256}}# SHA-256 of the library this module was generated for
+_LIB_SHA256 = '{{.LibSHA256}}'
+
+def _verify_library(path):
+    """
+    Raise ImportError if the library at path does not match _LIB_SHA256.
+    """
+    import hashlib
+    digest = hashlib.sha256()
+    with open(path, 'rb') as f:
+        for chunk in iter(lambda: f.read(65536), b''):
+            digest.update(chunk)
+    if digest.hexdigest() != _LIB_SHA256:
+        raise ImportError("Checksum mismatch for shared library %r: expected %s, got %s" % (path, _LIB_SHA256, digest.hexdigest()))
+
+{{end}}# Keeps libraries extracted from zipped packages on disk for the life of the process
+_extracted_resources = contextlib.ExitStack()
+
+# Environment variable listing library directories on this platform
+_LIB_PATH_VAR = {'win32': 'PATH', 'darwin': 'DYLD_LIBRARY_PATH'}.get(sys.platform, 'LD_LIBRARY_PATH')
+
+def _try_library(path, attempted):
+    """
+    Open the library at path if it exists, recording the attempt.
+    """
+    attempted.append(path)
+    if not os.path.exists(path):
+        return None
+    {{if .LibSHA256}}_verify_library(path)
+    {{end}}return {{.LibOpen}}(path)
+
+def _load_library(name):
+    """
+    Load the shared library, searching {{range $i, $l := .LibSearch}}{{if $i}}, {{end}}{{$l}}{{end}} in that order.
+    Raises ImportError listing every location tried.
+    """
+    attempted = []
+
+    try:
+        module_dir = os.path.dirname(os.path.abspath(__file__))
+    except NameError:
+        module_dir = None  # __file__ is undefined for frozen or embedded modules
+{{range .LibSearch}}{{if eq . "module"}}
+    # Next to this module
+    if module_dir is not None:
+        lib = _try_library(os.path.join(module_dir, name), attempted)
+        if lib is not None:
+            return lib
+{{else if eq . "lib"}}
+    # In a lib directory next to this module
+    if module_dir is not None:
+        lib = _try_library(os.path.join(module_dir, 'lib', name), attempted)
+        if lib is not None:
+            return lib
+{{else if eq . "resources"}}
+    # Among the package resources, for zipped imports
+    if __package__:
+        try:
+            from importlib import resources
+            resource = resources.files(__package__).joinpath(name)
+            if resource.is_file():
+                path = _extracted_resources.enter_context(resources.as_file(resource))
+                return _try_library(str(path), attempted)
+        except (ImportError, AttributeError):
+            pass  # importlib.resources.files needs Python 3.9+
+{{else if eq . "env"}}
+    # In the directories on the platform's library path variable
+    for directory in os.environ.get(_LIB_PATH_VAR, '').split(os.pathsep):
+        if directory:
+            lib = _try_library(os.path.join(directory, name), attempted)
+            if lib is not None:
+                return lib
+{{else if eq . "system"}}{{if not $.LibSHA256}}
+    # On the dynamic loader's default search path
+    attempted.append(name)
+    try:
+        return {{$.LibOpen}}(name)
+    except OSError:
+        pass
+{{end}}{{end}}{{end}}
+    raise ImportError("Could not load shared library %r; tried: %s" % (name, ", ".join(attempted)))
+{{if .DependencyDirs}}
+# Directories holding the libraries the shared library depends on; relative
+# ones are taken from this module's directory
+_DEPENDENCY_DIRS = [{{range $i, $d := .DependencyDirs}}{{if $i}}, {{end}}'{{pystr $d}}'{{end}}]
+
+# Keeps the directories added with os.add_dll_directory registered
+_dll_directories = []
+
+def _add_dependency_dirs():
+    """
+    Make the dependencies in _DEPENDENCY_DIRS loadable. Windows searches the
+    directories added with os.add_dll_directory; elsewhere the dynamic loader
+    reads its search path only at startup, so the dependencies are loaded up
+    front and found by name when the library needs them.
+    """
+    import ctypes
+    try:
+        base = os.path.dirname(os.path.abspath(__file__))
+    except NameError:
+        base = os.getcwd()
+    pending = []
+    for directory in _DEPENDENCY_DIRS:
+        directory = os.path.join(base, directory)
+        if not os.path.isdir(directory):
+            continue
+        if sys.platform == 'win32':
+            if hasattr(os, 'add_dll_directory'):
+                _dll_directories.append(os.add_dll_directory(directory))
+            else:
+                os.environ['PATH'] = directory + os.pathsep + os.environ.get('PATH', '')
+            continue
+        for entry in sorted(os.listdir(directory)):
+            if '.so' in entry or entry.endswith('.dylib'):
+                pending.append(os.path.join(directory, entry))
+    # Dependencies may need each other, so retry until no more load
+    while pending:
+        failed = []
+        for path in pending:
+            try:
+                ctypes.CDLL(path, mode=ctypes.RTLD_GLOBAL)
+            except OSError:
+                failed.append(path)
+        if len(failed) == len(pending):
+            break
+        pending = failed
+
+_add_dependency_dirs()
+{{end}}{{if .LibBase64}}
+# The shared library itself, base64-encoded, so the module is self-contained
+_LIB_DATA = ({{range .LibBase64}}
+    '{{.}}'{{end}}
+)
+
+def _load_embedded_library(name):
+    """
+    Write the embedded library to a temporary directory, which is removed at
+    exit where the platform allows it, and load it from there.
+    """
+    import atexit
+    import base64
+    import shutil
+    import tempfile
+    directory = tempfile.mkdtemp(prefix='{{pystr .ModuleName}}-')
+    atexit.register(shutil.rmtree, directory, True)
+    path = os.path.join(directory, name)
+    with open(path, 'wb') as f:
+        f.write(base64.b64decode(_LIB_DATA))
+    {{if .LibSHA256}}_verify_library(path)
+    {{end}}return {{.LibOpen}}(path)
+{{end}}{{end}}
+{{define "libs"}}{{if .SymbolLibraries}}# Library file name of each symbol bound from a library other than _LIB_NAME
+_SYMBOL_LIBRARIES = {
+    {{range $symbol, $lib := .SymbolLibraries}}
+    '{{$symbol}}': '{{pystr $lib}}',
+    {{end}}
+}
+
+class _Libraries:
+    """
+    Loads every library the module binds and looks up each symbol in the
+    library that exports it.
+    """
+    def __init__(self):
+        self._libs = {}
+        for name in [_LIB_NAME] + list(_SYMBOL_LIBRARIES.values()):
+            if name not in self._libs:
+                self._libs[name] = _load_library(name)
+
+    def __getattr__(self, symbol):
+        return getattr(self._libs[_SYMBOL_LIBRARIES.get(symbol, _LIB_NAME)], symbol)
+
+_lib = _Libraries(){{else if .LibBase64}}_lib = _load_embedded_library(_LIB_NAME){{else}}_lib = _load_library(_LIB_NAME){{end}}{{end}}`
+
 // pythonBindingTemplate is the template for generating Python bindings
-const pythonBindingTemplate = `import ctypes
+const pythonBindingTemplate = `{{template "header" .}}import contextlib
+import ctypes
 import sys
 import os
-from typing import Any, Union, Optional, List, Dict, Tuple
-
+from typing import {{.TypingImports}}
+{{if .Callbacks}}from typing import Callable
+{{end}}{{if .HasAsync}}import asyncio
+import concurrent.futures
+{{end}}{{if .ArrayMode}}import numpy as np
+{{end}}
 # Basic type mapping (always included)
 TYPE_MAPPING = {
     {{range $key, $value := .TypeMappings}}
@@ -132,65 +804,160 @@ PYTHON_TYPE_HINTS = {
 {{if eq .Kind "struct"}}
 class {{.Name}}(ctypes.Structure):
     """
-    {{.Description}}
+    {{doc .Description}}
     """
     _fields_ = [
         {{range .Fields}}
-        ("{{.Name}}", TYPE_MAPPING["{{.Type}}"]),  # {{.Description}}
+        ("{{.Name}}", {{if index $.DeclaredTypes .Type}}{{.Type}}{{else}}TYPE_MAPPING["{{.Type}}"]{{end}}),  # {{comment .Description}}
         {{end}}
     ]
+
+    def __repr__(self) -> str:
+        fields = ", ".join(f"{name}={getattr(self, name)!r}" for name, _ in self._fields_)
+        return f"{type(self).__name__}({fields})"
+
+    def __eq__(self, other: object) -> bool:
+        if not isinstance(other, type(self)):
+            return NotImplemented
+        return all(getattr(self, name) == getattr(other, name) for name, _ in self._fields_)
 {{else if eq .Kind "enum"}}
-class {{.Name}}(ctypes.c_int):
+class {{.Name}}({{with index $.TypeMappings .BaseType}}{{.}}{{else}}ctypes.c_int{{end}}):
     """
-    {{.Description}}
+    {{doc .Description}}
     """
-    {{range .Values}}
-    {{.}} = {{.}}
+    {{range members .Values}}
+    {{.Name}} = {{.Value}}
     {{end}}
 {{else if eq .Kind "union"}}
 class {{.Name}}(ctypes.Union):
     """
-    {{.Description}}
+    {{doc .Description}}
     """
     _fields_ = [
         {{range .Fields}}
-        ("{{.Name}}", TYPE_MAPPING["{{.Type}}"]),  # {{.Description}}
+        ("{{.Name}}", {{if index $.DeclaredTypes .Type}}{{.Type}}{{else}}TYPE_MAPPING["{{.Type}}"]{{end}}),  # {{comment .Description}}
         {{end}}
     ]
+{{else if eq .Kind "opaque"}}
+# {{comment .Description}}
+# Opaque handle, passed as an address (int) or None
+{{.Name}} = ctypes.c_void_p
 {{end}}
 
 {{end}}
+{{if .StructPointers}}# Pointers to the structs and unions above
+{{range $cType, $class := .StructPointers}}TYPE_MAPPING["{{$cType}}"] = ctypes.POINTER({{$class}})
+{{end}}
+{{end}}
+{{template "constants" .}}# Load the shared library
+{{template "libname" .}}
 
-# Load the shared library based on the OS
-_lib = None
-if sys.platform.startswith('win'):
-    _lib = ctypes.CDLL(os.path.join(os.path.dirname(__file__), '{{.LibPath}}'))
-elif sys.platform.startswith('linux'):
-    _lib = ctypes.CDLL(os.path.join(os.path.dirname(__file__), '{{.LibPath}}'))
-elif sys.platform.startswith('darwin'):
-    _lib = ctypes.CDLL(os.path.join(os.path.dirname(__file__), '{{.LibPath}}'))
+{{template "loader" .}}
+{{template "libs" .}}
+{{if .IntegerTypes}}
+# Bounds of each C integer type checked so far
+_int_bounds = {}
 
+def _check_int(name, value, ctype):
+    """
+    Raise OverflowError if value does not fit the C integer type ctype, which
+    ctypes would otherwise silently truncate it to.
+    """
+    bounds = _int_bounds.get(ctype)
+    if bounds is None:
+        bits = 8 * ctypes.sizeof(ctype)
+        if ctype(-1).value < 0:
+            bounds = (-(1 << (bits - 1)), (1 << (bits - 1)) - 1)
+        else:
+            bounds = (0, (1 << bits) - 1)
+        _int_bounds[ctype] = bounds
+    if not bounds[0] <= value <= bounds[1]:
+        raise OverflowError("%s=%r is out of range for %s [%d, %d]" % (name, value, ctype.__name__, bounds[0], bounds[1]))
+{{end}}{{if .Callbacks}}
+# The ctypes callback objects most recently passed to each callback parameter.
+# C code may call them after the call returns, so they must not be collected.
+_callbacks = {}
+{{end}}{{if .HasAsync}}
+# Runs the blocking library calls behind the *_async wrappers. ctypes releases
+# the GIL for the duration of each call, so they run in parallel with Python code.
+_executor = concurrent.futures.ThreadPoolExecutor(thread_name_prefix="{{.ModuleName}}")
+{{end}}
 {{range .Functions}}
 # Configure function signature for {{.Name}}
-_lib.{{.Name}}.argtypes = [{{range $i, $p := .Parameters}}{{if $i}}, {{end}}TYPE_MAPPING["{{$p.Type}}"]{{end}}]
-_lib.{{.Name}}.restype = TYPE_MAPPING["{{.ReturnType}}"]
+{{lib .CSymbol}}.argtypes = [{{range $i, $p := .Parameters}}{{if $i}}, {{end}}{{if $p.IsOutput}}ctypes.POINTER(TYPE_MAPPING["{{$p.ValueType}}"]){{else}}TYPE_MAPPING["{{$p.Type}}"]{{end}}{{end}}]
+{{lib .CSymbol}}.restype = {{if index $.ReturnClasses .ReturnType}}{{.ReturnType}}{{else}}TYPE_MAPPING["{{.ReturnType}}"]{{end}}
+{{if .ErrCheck}}
+def _errcheck_{{.PyName}}(result, func, args):
+    if {{errcheck .ErrCheck}}:
+        raise RuntimeError('{{pystr .Name}} returned %r' % (result,))
+    return result
 
-def {{.Name}}({{range $i, $p := .Parameters}}{{if $i}}, {{end}}{{$p.Name}}: {{index $.PythonTypeHints $p.Type}}{{end}}) -> {{index $.PythonTypeHints .ReturnType}}:
+{{lib .CSymbol}}.errcheck = _errcheck_{{.PyName}}
+{{end}}
+def {{.PyName}}({{range $i, $p := .Inputs}}{{if $i}}, {{end}}{{$p.Name}}: {{if $p.PyType}}{{$p.PyType}}{{else}}{{index $.PythonTypeHints $p.ValueType}}{{end}}{{default $p.Default}}{{end}}) -> {{if .ReturnPyType}}{{.ReturnPyType}}{{else if index $.ReturnClasses .ReturnType}}{{.ReturnType}}{{else}}{{index $.PythonTypeHints .ReturnType}}{{end}}:
     """
-    {{.Description}}
+    {{doc .Description}}
     {{if .Docstring}}
-    {{.Docstring}}
+    {{doc .Docstring}}
     {{end}}
-    {{range .Parameters}}
+    {{range .Inputs}}
     Args:
-        {{.Name}} ({{index $.PythonTypeHints .Type}}): {{.Description}}
+        {{.Name}} ({{if .PyType}}{{.PyType}}{{else}}{{index $.PythonTypeHints .ValueType}}{{end}}): {{doc .Description}}
     {{end}}
     Returns:
-        {{index $.PythonTypeHints .ReturnType}}: {{.Description}}
+        {{if .ReturnPyType}}{{.ReturnPyType}}{{else if index $.ReturnClasses .ReturnType}}{{.ReturnType}}{{else}}{{index $.PythonTypeHints .ReturnType}}{{end}}: {{doc .Description}}
+    """
+    {{range .Inputs}}{{if index $.IntegerTypes .ValueType}}
+    _check_int('{{.Name}}', {{.Name}}, TYPE_MAPPING["{{.ValueType}}"])
+    {{end}}{{end}}{{range .Outputs}}
+    {{.Name}} = TYPE_MAPPING["{{.ValueType}}"]({{if eq .Direction "inout"}}{{.Name}}{{end}})
+    {{end}}{{range .Parameters}}{{if and (not .IsOutput) (index $.ArrayDtypes .Type)}}
+    {{.Name}} = np.ascontiguousarray({{.Name}}, dtype={{index $.ArrayDtypes .Type}})
+    {{end}}{{end}}{{$fn := .}}{{range .Parameters}}{{if index $.Callbacks .Type}}
+    if not isinstance({{.Name}}, TYPE_MAPPING["{{.Type}}"]):
+        {{.Name}} = TYPE_MAPPING["{{.Type}}"]({{.Name}})
+    _callbacks[("{{$fn.PyName}}", "{{.Name}}")] = {{.Name}}
+    {{end}}{{end}}
+    {{if not .Outputs}}return {{else if ne .ReturnType "void"}}_result = {{end}}{{lib .CSymbol}}({{range $i, $p := .Parameters}}{{if $i}}, {{end}}{{if $p.IsOutput}}ctypes.byref({{$p.Name}}){{else if index $.ArrayDtypes $p.Type}}{{$p.Name}}.ctypes.data_as(TYPE_MAPPING["{{$p.Type}}"]){{else if index $.StructPointers $p.Type}}None if {{$p.Name}} is None else ctypes.byref({{$p.Name}}){{else}}{{$p.Name}}{{end}}{{end}})
+{{if .Outputs}}    return {{results .}}
+{{end}}{{if or $.Async .Async}}
+async def {{.PyName}}_async({{range $i, $p := .Inputs}}{{if $i}}, {{end}}{{$p.Name}}: {{if $p.PyType}}{{$p.PyType}}{{else}}{{index $.PythonTypeHints $p.ValueType}}{{end}}{{default $p.Default}}{{end}}) -> {{if .ReturnPyType}}{{.ReturnPyType}}{{else if index $.ReturnClasses .ReturnType}}{{.ReturnType}}{{else}}{{index $.PythonTypeHints .ReturnType}}{{end}}:
     """
-    return _lib.{{.Name}}({{range $i, $p := .Parameters}}{{if $i}}, {{end}}{{$p.Name}}{{end}})
+    Awaitable version of {{.PyName}}, run in a worker thread so the event loop
+    is not blocked.
+    """
+    loop = asyncio.get_running_loop()
+    return await loop.run_in_executor(_executor, {{.PyName}}{{range .Inputs}}, {{.Name}}{{end}})
+{{end}}
+{{end}}
+{{range .Types}}
+{{if eq .Kind "handle"}}
+{{lib .Constructor}}.restype = ctypes.c_void_p
+{{lib .Destructor}}.argtypes = [ctypes.c_void_p]
+{{lib .Destructor}}.restype = None
+
+class {{.Name}}:
+    """
+    {{doc .Description}}
+    """
+    def __init__(self, *args):
+        self._handle = {{lib .Constructor}}(*args)
+        if not self._handle:
+            raise RuntimeError("{{.Constructor}} returned NULL")
+
+    def close(self):
+        if self._handle:
+            {{lib .Destructor}}(self._handle)
+            self._handle = None
 
+    def __enter__(self):
+        return self
+
+    def __exit__(self, exc_type, exc_value, traceback):
+        self.close()
+        return False
+{{end}}
 {{end}}
 
-__all__ = [{{range $i, $f := .Functions}}{{if $i}}, {{end}}'{{$f.Name}}'{{end}}]
+__all__ = [{{range $i, $name := .Exports}}{{if $i}}, {{end}}'{{$name}}'{{end}}]
 `
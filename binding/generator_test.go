@@ -1,12 +1,26 @@
 package binding
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"maps"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"text/template"
+	"time"
 
+	"cp2p/compiler"
 	"cp2p/config"
+	"cp2p/util"
 )
 
 func TestGenerateBindings(t *testing.T) {
@@ -38,7 +52,7 @@ func TestGenerateBindings(t *testing.T) {
 	}
 
 	// Test generating bindings
-	err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
+	_, err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
 	if err != nil {
 		t.Fatalf("GenerateBindings() error = %v", err)
 	}
@@ -64,7 +78,7 @@ func TestGenerateBindings(t *testing.T) {
 		"TYPE_MAPPING = {",
 		"'int': ctypes.c_int",
 		"'double': ctypes.c_double",
-		"_lib = None",
+		"_lib = _load_library(_LIB_NAME)",
 		"def add(a: int, b: int) -> int:",
 		"def multiply(a: float, b: float) -> float:",
 		"__all__ = ['add', 'multiply']",
@@ -97,7 +111,7 @@ func TestNewGenerator(t *testing.T) {
 	}
 
 	// Test generating bindings using NewGenerator
-	err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
+	_, err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
 	if err != nil {
 		t.Fatalf("GenerateBindings() error = %v", err)
 	}
@@ -108,3 +122,1554 @@ func TestNewGenerator(t *testing.T) {
 		t.Fatalf("Output file not created: %v", err)
 	}
 }
+
+func TestGenerateHandleContextManager(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name:        "widget_size",
+				Description: "Returns the widget size",
+				Parameters:  []config.Param{},
+				ReturnType:  "int",
+			},
+		},
+		Types: []config.TypeConfig{
+			{
+				Name:        "Widget",
+				Kind:        "handle",
+				Description: "An opaque widget",
+				Constructor: "widget_create",
+				Destructor:  "widget_destroy",
+			},
+		},
+	}
+
+	if _, err := GenerateBindings("test", "test.dll", tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"class Widget:",
+		"_lib.widget_create.restype = ctypes.c_void_p",
+		"self._handle = _lib.widget_create(*args)",
+		"_lib.widget_destroy(self._handle)",
+		"def __enter__(self):",
+		"def __exit__(self, exc_type, exc_value, traceback):",
+		"__all__ = ['widget_size', 'Widget']",
+	}
+
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerationResult(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name:       "add",
+				Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+				ReturnType: "int",
+			},
+			{
+				Name:       "scale",
+				Parameters: []config.Param{{Name: "v", Type: "vec3"}},
+				ReturnType: "vec3",
+			},
+			{
+				Name:       "length",
+				Parameters: []config.Param{{Name: "v", Type: "vec3"}},
+				ReturnType: "double",
+			},
+		},
+		Types: []config.TypeConfig{
+			{Name: "Point", Kind: "struct", Fields: []config.Field{{Name: "x", Type: "int"}}},
+		},
+	}
+
+	result, err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
+	if err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	if result.FunctionsBound != 1 {
+		t.Errorf("FunctionsBound = %d, want 1", result.FunctionsBound)
+	}
+	if result.TypesGenerated != 1 {
+		t.Errorf("TypesGenerated = %d, want 1", result.TypesGenerated)
+	}
+	if !slices.Equal(result.SkippedFunctions, []string{"scale", "length"}) {
+		t.Errorf("SkippedFunctions = %v, want [scale length]", result.SkippedFunctions)
+	}
+	if !slices.Equal(result.UnmappedTypes, []string{"vec3"}) {
+		t.Errorf("UnmappedTypes = %v, want [vec3]", result.UnmappedTypes)
+	}
+	wantFiles := []string{filepath.Join(tmpDir, "test.py"), filepath.Join(tmpDir, "requirements.txt"), filepath.Join(tmpDir, ManifestFile)}
+	if !slices.Equal(result.FilesWritten, wantFiles) {
+		t.Errorf("FilesWritten = %v", result.FilesWritten)
+	}
+}
+
+func TestGenerateOverloads(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name:       "print",
+				PythonName: "print_int",
+				Symbol:     "_Z5printi",
+				Parameters: []config.Param{{Name: "value", Type: "int"}},
+				ReturnType: "void",
+			},
+			{
+				Name:       "print",
+				PythonName: "print_str",
+				Symbol:     "_Z5printPKc",
+				Parameters: []config.Param{{Name: "value", Type: "const char*"}},
+				ReturnType: "void",
+			},
+		},
+	}
+
+	if _, err := GenerateBindings("test", "test.dll", tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"_lib._Z5printi.argtypes = [TYPE_MAPPING[\"int\"]]",
+		"def print_int(value: int) -> None:",
+		"return _lib._Z5printi(value)",
+		"_lib._Z5printPKc.argtypes = [TYPE_MAPPING[\"const char*\"]]",
+		"def print_str(value: str) -> None:",
+		"return _lib._Z5printPKc(value)",
+		"__all__ = ['print_int', 'print_str']",
+	}
+
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(string(content), "def print(") {
+		t.Error("Generated file should not define the overloaded C++ name")
+	}
+}
+
+func TestGenerateSymbolOverride(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name:       "add",
+				Symbol:     "cpp_add_v2",
+				Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+				ReturnType: "int",
+			},
+		},
+	}
+
+	if _, err := GenerateBindings("test", "test.dll", tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"_lib.cpp_add_v2.argtypes",
+		"_lib.cpp_add_v2.restype",
+		"def add(a: int, b: int) -> int:",
+		"return _lib.cpp_add_v2(a, b)",
+		"__all__ = ['add']",
+	}
+
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(string(content), "_lib.add") {
+		t.Error("Generated file should not look up the Python name in the library")
+	}
+}
+
+func TestGenerateLeavesNoPartialFile(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Swap in a template that writes some output before failing
+	orig := bindingTemplate
+	defer func() { bindingTemplate = orig }()
+	bindingTemplate = template.Must(template.New("binding").Parse("import ctypes\n{{.NoSuchField}}"))
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{}, ReturnType: "int"},
+		},
+	}
+
+	if _, err := GenerateBindings("test", "test.dll", tmpDir, testConfig); err == nil {
+		t.Fatal("Expected GenerateBindings() to fail")
+	}
+
+	entries, err := os.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to read output directory: %v", err)
+	}
+	for _, entry := range entries {
+		t.Errorf("Unexpected file left behind: %s", entry.Name())
+	}
+}
+
+func TestGenerateRequirements(t *testing.T) {
+	newConfig := func(arrayMode bool) *config.Config {
+		return &config.Config{
+			ArrayMode: arrayMode,
+			Functions: []config.FunctionConfig{
+				{
+					Name:       "sum",
+					Parameters: []config.Param{{Name: "values", Type: "double*"}, {Name: "n", Type: "int"}},
+					ReturnType: "double",
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		arrayMode    bool
+		requirements string
+	}{
+		{name: "Plain", arrayMode: false, requirements: ""},
+		{name: "Array mode", arrayMode: true, requirements: "numpy\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			if _, err := GenerateBindings("test", "test.dll", tmpDir, newConfig(tt.arrayMode)); err != nil {
+				t.Fatalf("GenerateBindings() error = %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(tmpDir, "requirements.txt"))
+			if err != nil {
+				t.Fatalf("Failed to read requirements: %v", err)
+			}
+			if string(content) != tt.requirements {
+				t.Errorf("requirements.txt = %q, want %q", content, tt.requirements)
+			}
+		})
+	}
+}
+
+func TestGenerateArrayMode(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		ArrayMode: true,
+		Functions: []config.FunctionConfig{
+			{
+				Name:       "sum",
+				Parameters: []config.Param{{Name: "values", Type: "double*"}, {Name: "n", Type: "int"}},
+				ReturnType: "double",
+			},
+		},
+	}
+
+	if _, err := GenerateBindings("test", "test.dll", tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"import numpy as np",
+		"'double*': ctypes.POINTER(ctypes.c_double)",
+		"def sum(values: np.ndarray, n: int) -> float:",
+		"values = np.ascontiguousarray(values, dtype=np.float64)",
+		"return _lib.sum(values.ctypes.data_as(TYPE_MAPPING[\"double*\"]), n)",
+	}
+
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateEnumBaseType(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseType string
+		want     string
+	}{
+		{name: "Default", baseType: "", want: "class Color(ctypes.c_int):"},
+		{name: "Unsigned char", baseType: "unsigned char", want: "class Color(ctypes.c_ubyte):"},
+		{name: "Int64", baseType: "int64_t", want: "class Color(ctypes.c_int64):"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			testConfig := &config.Config{
+				Functions: []config.FunctionConfig{
+					{Name: "get", Parameters: []config.Param{}, ReturnType: "int"},
+				},
+				Types: []config.TypeConfig{
+					{Name: "Color", Kind: "enum", BaseType: tt.baseType, Values: []string{"RED", "GREEN"}},
+				},
+			}
+
+			if _, err := GenerateBindings("test", "test.dll", tmpDir, testConfig); err != nil {
+				t.Fatalf("GenerateBindings() error = %v", err)
+			}
+
+			content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+			if err != nil {
+				t.Fatalf("Failed to read generated file: %v", err)
+			}
+			for _, expected := range []string{tt.want, "RED = 0", "GREEN = 1"} {
+				if !strings.Contains(string(content), expected) {
+					t.Errorf("Generated file missing expected content: %s", expected)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateEnumValues(t *testing.T) {
+	values := []string{"READ = 1", "WRITE", "EXEC = 010", "ALL = READ | WRITE | EXEC", "NEXT"}
+	for _, backend := range []string{BackendCtypes, BackendCFFI} {
+		t.Run(backend, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			testConfig := &config.Config{
+				OutputBackend: backend,
+				Functions:     []config.FunctionConfig{{Name: "get", Parameters: []config.Param{}, ReturnType: "int"}},
+				Types:         []config.TypeConfig{{Name: "Mode", Kind: "enum", Values: values}},
+			}
+			if _, err := GenerateBindings("test", "test.dll", tmpDir, testConfig); err != nil {
+				t.Fatalf("GenerateBindings() error = %v", err)
+			}
+
+			path := filepath.Join(tmpDir, "test.py")
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read generated file: %v", err)
+			}
+			expected := []string{"READ = 1\n", "WRITE = 2\n", "EXEC = 8\n", "ALL = READ | WRITE | EXEC\n", "NEXT = (READ | WRITE | EXEC) + 1\n", "__all__ = ['get', 'Mode']"}
+			if backend == BackendCFFI {
+				expected = append(expected, "EXEC = 010,", "NEXT,")
+			}
+			for _, want := range expected {
+				if !strings.Contains(string(content), want) {
+					t.Errorf("Generated file missing expected content: %s", want)
+				}
+			}
+
+			python, err := FindPython()
+			if err != nil {
+				return
+			}
+			cmd := exec.CommandContext(context.Background(), python, "-m", "py_compile", path)
+			if output, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("Generated module does not compile: %v\n%s", err, output)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"A =", "1A", "class"} {
+		testConfig := &config.Config{
+			Functions: []config.FunctionConfig{{Name: "get", Parameters: []config.Param{}, ReturnType: "int"}},
+			Types:     []config.TypeConfig{{Name: "Mode", Kind: "enum", Values: []string{invalid}}},
+		}
+		if _, err := GenerateBindings("test", "test.dll", t.TempDir(), testConfig); err == nil {
+			t.Errorf("Expected an error for enum value %q", invalid)
+		}
+	}
+}
+
+func TestGenerateEnumUnmappedBaseType(t *testing.T) {
+	testConfig := &config.Config{
+		Types: []config.TypeConfig{
+			{Name: "Color", Kind: "enum", BaseType: "my_int", Values: []string{"RED"}},
+		},
+	}
+
+	if _, err := GenerateBindings("test", "test.dll", t.TempDir(), testConfig); err == nil {
+		t.Error("Expected an error for an unmapped enum base type")
+	}
+}
+
+func TestGenerateNestedStructOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		Types: []config.TypeConfig{
+			{
+				Name:   "Line",
+				Kind:   "struct",
+				Fields: []config.Field{{Name: "start", Type: "Point"}, {Name: "end", Type: "Point"}},
+			},
+			{
+				Name:   "Point",
+				Kind:   "struct",
+				Fields: []config.Field{{Name: "x", Type: "double"}, {Name: "y", Type: "double"}},
+			},
+		},
+	}
+
+	if _, err := GenerateBindings("test", "test.dll", tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	point := strings.Index(string(content), "class Point(ctypes.Structure):")
+	line := strings.Index(string(content), "class Line(ctypes.Structure):")
+	if point < 0 || line < 0 {
+		t.Fatal("Generated file missing struct classes")
+	}
+	if point > line {
+		t.Error("Expected Point to be defined before Line")
+	}
+	if !strings.Contains(string(content), `("start", Point)`) {
+		t.Error("Expected the start field to reference the Point class")
+	}
+}
+
+func TestGenerateStructReturn(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "make_point", Parameters: []config.Param{{Name: "x", Type: "double"}, {Name: "y", Type: "double"}}, ReturnType: "Point"},
+		},
+		Types: []config.TypeConfig{
+			{
+				Name:   "Point",
+				Kind:   "struct",
+				Fields: []config.Field{{Name: "x", Type: "double"}, {Name: "y", Type: "double"}},
+			},
+		},
+	}
+
+	result, err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
+	if err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if result.FunctionsBound != 1 {
+		t.Errorf("FunctionsBound = %d, want 1 (skipped %v)", result.FunctionsBound, result.SkippedFunctions)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"_lib.make_point.restype = Point\n",
+		"def make_point(x: float, y: float) -> Point:",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateAsyncWrappers(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int", Async: true},
+			{Name: "sub", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+	}
+
+	var content bytes.Buffer
+	if err := GenerateBindingsTo(&content, "test", "test.dll", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+
+	expectedStrings := []string{
+		"import asyncio",
+		"_executor = concurrent.futures.ThreadPoolExecutor(",
+		"def add(a: int, b: int) -> int:",
+		"async def add_async(a: int, b: int) -> int:",
+		"return await loop.run_in_executor(_executor, add, a, b)",
+		"__all__ = ['add', 'add_async', 'sub']",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(content.String(), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(content.String(), "sub_async") {
+		t.Error("Expected no async wrapper for sub")
+	}
+}
+
+func TestGenerateCFFIBackend(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		OutputBackend: BackendCFFI,
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+			{Name: "greet", Parameters: []config.Param{{Name: "name", Type: "char const *"}}, ReturnType: "void", Symbol: "greet_v2"},
+			{Name: "origin", Parameters: []config.Param{}, ReturnType: "Point"},
+			{Name: "scale", Parameters: []config.Param{{Name: "p", Type: "Point*"}, {Name: "by", Type: "double"}}, ReturnType: "void"},
+		},
+		Types: []config.TypeConfig{
+			{Name: "Point", Kind: "struct", Fields: []config.Field{{Name: "x", Type: "double"}, {Name: "y", Type: "double"}}},
+		},
+	}
+
+	result, err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
+	if err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if result.FunctionsBound != 4 {
+		t.Errorf("FunctionsBound = %d, want 4 (skipped %v)", result.FunctionsBound, result.SkippedFunctions)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"from cffi import FFI",
+		"ffi.cdef(",
+		"typedef struct {\n    double x;\n    double y;",
+		"} Point;",
+		"int add(int a, int b);",
+		"void greet_v2(const char* name);",
+		"Point origin(void);",
+		"void scale(Point* p, double by);",
+		"def scale(p: Any, by: float) -> None:",
+		"return ffi.dlopen(name)",
+		"__all__ = ['add', 'greet', 'origin', 'scale']", // Point is declared to cffi, not a class
+		"def add(a: int, b: int) -> int:",
+		"return _lib.greet_v2(name)",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(string(content), "import ctypes") {
+		t.Error("Expected the cffi backend not to import ctypes")
+	}
+
+	requirements, err := os.ReadFile(filepath.Join(tmpDir, "requirements.txt"))
+	if err != nil {
+		t.Fatalf("Failed to read requirements: %v", err)
+	}
+	if string(requirements) != "cffi\n" {
+		t.Errorf("requirements.txt = %q, want %q", requirements, "cffi\n")
+	}
+}
+
+func TestGenerateUnknownBackend(t *testing.T) {
+	testConfig := &config.Config{
+		OutputBackend: "swig",
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{}, ReturnType: "int"},
+		},
+	}
+
+	if _, err := GenerateBindings("test", "test.dll", t.TempDir(), testConfig); err == nil {
+		t.Error("Expected an error for an unknown output backend")
+	}
+}
+
+func TestGenerateLibFileName(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		LibFileName: "libfoo.so.1",
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{}, ReturnType: "int"},
+		},
+	}
+
+	if _, err := GenerateBindings("foo", filepath.Join("build", "libfoo.so"), tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "foo.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if !strings.Contains(string(content), "_LIB_NAME = 'libfoo.so.1'") {
+		t.Error("Expected the loader to open the configured library file name")
+	}
+}
+
+func TestGenerateCrossPlatformLoader(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		CrossPlatformLoader: true,
+		LibFileNames:        map[string]string{"linux": "libfoo.so.1"},
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{}, ReturnType: "int"},
+		},
+	}
+
+	if _, err := GenerateBindings("foo", "libfoo.so", tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "foo.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"'darwin': 'libfoo.dylib',",
+		"'linux': 'libfoo.so.1',",
+		"'win32': 'foo.dll',",
+		"_LIB_NAME = _LIB_NAMES.get(sys.platform, 'libfoo.so')",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateChecksum(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	libPath := filepath.Join(tmpDir, "libfoo.so")
+	if err := os.WriteFile(libPath, []byte("not really a library"), 0644); err != nil {
+		t.Fatalf("Failed to create library: %v", err)
+	}
+	sum := sha256.Sum256([]byte("not really a library"))
+
+	testConfig := &config.Config{
+		VerifyChecksum: true,
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{}, ReturnType: "int"},
+		},
+	}
+
+	if _, err := GenerateBindings("foo", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "foo.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"_LIB_SHA256 = '" + hex.EncodeToString(sum[:]) + "'",
+		"_verify_library(path)\n    return ctypes.CDLL(path)",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(string(content), "return ctypes.CDLL(name)") {
+		t.Error("Expected no unverified fallback to the loader's search path")
+	}
+
+	if _, err := GenerateBindings("foo", filepath.Join(tmpDir, "missing.so"), tmpDir, testConfig); err == nil {
+		t.Error("Expected an error when the library to checksum is missing")
+	}
+}
+
+func TestGenerateUnknownFieldType(t *testing.T) {
+	testConfig := &config.Config{
+		Types: []config.TypeConfig{
+			{Name: "Line", Kind: "struct", Fields: []config.Field{{Name: "start", Type: "Vertex"}}},
+		},
+	}
+
+	if _, err := GenerateBindings("test", "test.dll", t.TempDir(), testConfig); err == nil {
+		t.Error("Expected an error for a field of undeclared type")
+	}
+}
+
+func TestGenerateLibraryLoader(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{}, ReturnType: "int"},
+		},
+	}
+
+	if _, err := GenerateBindings("loader", "libmissing.so", tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "loader.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"_LIB_NAME = 'libmissing.so'",
+		"os.path.abspath(__file__)",
+		"resources.files(__package__)",
+		"return ctypes.CDLL(name)",
+		"raise ImportError(\"Could not load shared library %r; tried: %s\"",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+
+	// With Python available, check the error names the attempted path
+	if _, err := FindPython(); err != nil {
+		return
+	}
+	err = Verify(tmpDir, "loader")
+	if err == nil {
+		t.Fatal("Expected import to fail without the library")
+	}
+	for _, expected := range []string{"ImportError", filepath.Join(tmpDir, "libmissing.so")} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected error to mention %s, got: %v", expected, err)
+		}
+	}
+}
+
+func TestGenerateStructReprAndEquality(t *testing.T) {
+	python, err := FindPython()
+	if err != nil {
+		t.Skipf("Skipping struct test: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+		Types: []config.TypeConfig{
+			{Name: "Point", Kind: "struct", Fields: []config.Field{{Name: "x", Type: "int"}, {Name: "y", Type: "double"}}},
+			{Name: "Segment", Kind: "struct", Fields: []config.Field{{Name: "start", Type: "Point"}, {Name: "end", Type: "Point"}}},
+		},
+	}
+	if _, err := GenerateBindings("shapes", buildTestLibrary(t, tmpDir), tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import shapes\n" +
+		"p = shapes.Point(3, 4.5)\n" +
+		"assert repr(p) == 'Point(x=3, y=4.5)', repr(p)\n" +
+		"assert p == shapes.Point(3, 4.5)\n" +
+		"assert p != shapes.Point(3, 5.0)\n" +
+		"s = shapes.Segment(p, shapes.Point(0, 0.0))\n" +
+		"assert repr(s) == 'Segment(start=Point(x=3, y=4.5), end=Point(x=0, y=0.0))', repr(s)\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Struct script failed: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateBindingsTo(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "preview", "build/libpreview.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+
+	// Matches what GenerateBindings writes to the module file
+	tmpDir := t.TempDir()
+	if _, err := GenerateBindings("preview", "build/libpreview.so", tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	written, err := os.ReadFile(filepath.Join(tmpDir, "preview.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if buf.String() != string(written) {
+		t.Error("GenerateBindingsTo() output differs from the file GenerateBindings writes")
+	}
+	for _, expected := range []string{"_LIB_NAME = 'libpreview.so'", "def add(a: int, b: int) -> int:"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateExcludedFunctions(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+			{Name: "debug_dump", Parameters: []config.Param{}, ReturnType: "void"},
+			{Name: "cpp_sub", PythonName: "sub", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+		Exclude: []string{"debug_dump", "sub"},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "test", "test.dll", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	content := buf.String()
+
+	if !strings.Contains(content, "def add(a: int, b: int) -> int:") {
+		t.Error("Generated module missing add")
+	}
+	for _, excluded := range []string{"debug_dump", "cpp_sub", "def sub("} {
+		if strings.Contains(content, excluded) {
+			t.Errorf("Generated module contains excluded %s", excluded)
+		}
+	}
+	if !strings.Contains(content, "__all__ = ['add']") {
+		t.Error("Expected only add in __all__")
+	}
+}
+
+func TestGenerateOpaqueType(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "widget_open", Parameters: []config.Param{{Name: "id", Type: "int"}}, ReturnType: "WidgetHandle"},
+			{Name: "widget_id", Parameters: []config.Param{{Name: "w", Type: "WidgetHandle"}}, ReturnType: "int"},
+		},
+		Types: []config.TypeConfig{
+			{Name: "WidgetHandle", Kind: "opaque", Description: "A widget owned by the library"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "widgets", "libwidgets.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	expectedStrings := []string{
+		"WidgetHandle = ctypes.c_void_p\n",
+		"def widget_open(id: int) -> Optional[int]:",
+		"def widget_id(w: Optional[int]) -> int:",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		t.Skipf("Skipping opaque handle round trip: %v", err)
+	}
+	tmpDir := t.TempDir()
+	libPath := buildLibrary(t, tmpDir, "widgets.cpp", `
+struct Widget { int id; };
+static Widget widgets[2];
+extern "C" Widget* widget_open(int id) { widgets[id].id = id * 10; return &widgets[id]; }
+extern "C" int widget_id(Widget* w) { return w->id; }
+`)
+	if _, err := GenerateBindings("widgets", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import widgets\n" +
+		"w = widgets.widget_open(1)\n" +
+		"assert isinstance(w, int), w\n" +
+		"assert widgets.widget_id(w) == 10\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Opaque handle script failed: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateModuleHeader(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+		ModuleDocstring: `Math helpers.
+
+Wraps "add" from C:\math.`,
+		OmitTimestamp: true,
+		SourceFile:    "src/math.cpp",
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "math", "libmath.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+
+	want := `# Generated by cp2p from src/math.cpp. Do not edit.
+"""
+Math helpers.
+
+Wraps "add" from C:\\math.
+"""
+import contextlib
+`
+	if !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("Generated module starts with\n%s\nwant\n%s", buf.String()[:len(want)], want)
+	}
+
+	// By default the header records the generation time and the docstring
+	// names the module
+	testConfig.ModuleDocstring, testConfig.OmitTimestamp = "", false
+	buf.Reset()
+	if err := GenerateBindingsTo(&buf, "math", "libmath.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	header, _, _ := strings.Cut(buf.String(), "\n")
+	if !strings.HasPrefix(header, "# Generated by cp2p from src/math.cpp on ") {
+		t.Errorf("Expected a timestamp in header %q", header)
+	}
+	if !strings.Contains(buf.String(), "\"\"\"\nPython bindings for the math library.\n\"\"\"\n") {
+		t.Error("Expected the default module docstring")
+	}
+}
+
+func TestGenerateReproducible(t *testing.T) {
+	origNow := now
+	defer func() { now = origNow }()
+
+	srcDir := t.TempDir()
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "myint"}}, ReturnType: "int"},
+			{Name: "origin", Parameters: []config.Param{}, ReturnType: "Point", Async: true},
+		},
+		Types: []config.TypeConfig{
+			{Name: "Point", Kind: "struct", Fields: []config.Field{{Name: "x", Type: "double"}, {Name: "y", Type: "double"}}},
+			{Name: "Color", Kind: "enum", Values: []string{"RED", "GREEN"}},
+		},
+		TypeMappings: []config.TypeMapping{{CType: "myint", Ctypes: "ctypes.c_int32", PythonHint: "int"}},
+		Reproducible: true,
+		SourceFile:   filepath.Join(srcDir, "geometry.cpp"),
+	}
+
+	generate := func(at time.Time) map[string]string {
+		now = func() time.Time { return at }
+		outDir := t.TempDir()
+		result, err := GenerateBindings("geometry", "libgeometry.so", outDir, testConfig)
+		if err != nil {
+			t.Fatalf("GenerateBindings() error = %v", err)
+		}
+		files := make(map[string]string)
+		for _, path := range result.FilesWritten {
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Failed to read %s: %v", path, err)
+			}
+			files[filepath.Base(path)] = string(content)
+		}
+		return files
+	}
+
+	first := generate(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	second := generate(time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC))
+	if !maps.Equal(first, second) {
+		t.Error("Reproducible generation produced different files across runs")
+	}
+
+	module := first["geometry.py"]
+	if strings.Contains(module, srcDir) {
+		t.Error("Reproducible module contains an absolute source path")
+	}
+	if !strings.HasPrefix(module, "# Generated by cp2p from geometry.cpp. Do not edit.\n") {
+		t.Errorf("Unexpected reproducible header %q", strings.SplitN(module, "\n", 2)[0])
+	}
+}
+
+func TestGenerateTypeMappingOrder(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "zint"}, {Name: "b", Type: "aint"}}, ReturnType: "int"},
+		},
+		TypeMappings: []config.TypeMapping{
+			{CType: "zint", Ctypes: "ctypes.c_int", PythonHint: "int"},
+			{CType: "aint", Ctypes: "ctypes.c_int", PythonHint: "int"},
+		},
+	}
+
+	typeMapping := func() string {
+		var buf bytes.Buffer
+		if err := GenerateBindingsTo(&buf, "test", "test.dll", testConfig); err != nil {
+			t.Fatalf("GenerateBindingsTo() error = %v", err)
+		}
+		_, block, _ := strings.Cut(buf.String(), "TYPE_MAPPING = {")
+		block, _, _ = strings.Cut(block, "}")
+		return block
+	}
+
+	first := typeMapping()
+	for range 5 {
+		if block := typeMapping(); block != first {
+			t.Fatalf("TYPE_MAPPING differs between runs:\n%s\nvs\n%s", first, block)
+		}
+	}
+
+	// Entries are sorted by C type, so new mappings only add lines
+	var keys []string
+	for _, line := range strings.Split(first, "\n") {
+		if key, _, ok := strings.Cut(strings.TrimSpace(line), "':"); ok {
+			keys = append(keys, strings.TrimPrefix(key, "'"))
+		}
+	}
+	if len(keys) == 0 || !slices.IsSorted(keys) {
+		t.Errorf("TYPE_MAPPING keys not sorted: %v", keys)
+	}
+}
+
+func TestGenerateZeroParameterFunction(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "get_count", Parameters: []config.Param{}, ReturnType: "int"},
+			{Name: "reset", ReturnType: "void"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "counter", "libcounter.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	for _, expected := range []string{
+		"_lib.get_count.argtypes = []",
+		"def get_count() -> int:",
+		"return _lib.get_count()",
+		"_lib.reset.argtypes = []",
+		"def reset() -> None:",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	tmpDir := t.TempDir()
+	libPath := buildLibrary(t, tmpDir, "counter.cpp",
+		"static int count = 3;\n"+
+			"extern \"C\" int get_count() { return count; }\n"+
+			"extern \"C\" void reset() { count = 0; }\n")
+	if _, err := GenerateBindings("counter", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import counter\n" +
+		"assert counter.get_count() == 3\n" +
+		"counter.reset()\n" +
+		"assert counter.get_count() == 0\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Zero-parameter script failed: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateLibrarySearchPath(t *testing.T) {
+	python, err := FindPython()
+	if err != nil {
+		t.Skipf("Skipping loader test: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	libPath := buildTestLibrary(t, t.TempDir())
+	libName := filepath.Base(libPath)
+	envDir := t.TempDir()
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+	}
+	if _, err := GenerateBindings("search", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	importModule := func() (string, error) {
+		script := "import sys; sys.path.insert(0, sys.argv[1]); import search; print(search.add(2, 3))"
+		cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+		cmd.Env = append(os.Environ(), util.LibraryPathVar(runtime.GOOS)+"="+envDir)
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	// Without the library, every location is tried in order before failing
+	output, err := importModule()
+	if err == nil {
+		t.Fatalf("Expected import to fail without the library:\n%s", output)
+	}
+	want := []string{
+		filepath.Join(tmpDir, libName),
+		filepath.Join(tmpDir, "lib", libName),
+		filepath.Join(envDir, libName),
+		libName,
+	}
+	tried := output[strings.LastIndex(output, "tried: ")+len("tried: "):]
+	tried = strings.TrimSpace(tried)
+	if got := strings.Split(tried, ", "); !slices.Equal(got, want) {
+		t.Errorf("Loader tried %v, want %v:\n%s", got, want, output)
+	}
+
+	// A library in the lib directory or on the library path loads
+	copyLibrary := func(dir string) {
+		data, err := os.ReadFile(libPath)
+		if err != nil {
+			t.Fatalf("Failed to read library: %v", err)
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", dir, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, libName), data, 0755); err != nil {
+			t.Fatalf("Failed to copy library: %v", err)
+		}
+	}
+	copyLibrary(envDir)
+	if output, err := importModule(); err != nil || strings.TrimSpace(output) != "5" {
+		t.Errorf("Import with the library on %s failed: %v\n%s", util.LibraryPathVar(runtime.GOOS), err, output)
+	}
+	copyLibrary(filepath.Join(tmpDir, "lib"))
+	if output, err := importModule(); err != nil || strings.TrimSpace(output) != "5" {
+		t.Errorf("Import with the library in lib failed: %v\n%s", err, output)
+	}
+
+	// The search order comes from the config
+	testConfig.LibrarySearchPath = []string{"lib"}
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "search", libPath, testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	for _, unexpected := range []string{"os.path.join(module_dir, name)", "_LIB_PATH_VAR, ''", "ctypes.CDLL(name)"} {
+		if strings.Contains(buf.String(), unexpected) {
+			t.Errorf("Loader searching only lib contains %s", unexpected)
+		}
+	}
+
+	testConfig.LibrarySearchPath = []string{"cwd"}
+	if err := GenerateBindingsTo(&buf, "search", libPath, testConfig); err == nil {
+		t.Error("Expected an error for an unknown search location")
+	}
+}
+
+func TestGeneratePythonTypeOverride(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name:         "load_image",
+				Parameters:   []config.Param{{Name: "path", Type: "const char*"}, {Name: "flags", Type: "ImageFlags", PyType: "Flags"}},
+				ReturnType:   "ImageHandle",
+				ReturnPyType: "Image",
+			},
+		},
+		Types: []config.TypeConfig{
+			{Name: "ImageHandle", Kind: "opaque"},
+			{Name: "ImageFlags", Kind: "opaque"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "images", "libimages.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	for _, expected := range []string{
+		"def load_image(path: str, flags: Flags) -> Image:",
+		"flags (Flags):",
+		"Image: ",
+		// The ctypes types still come from the C types
+		`_lib.load_image.argtypes = [TYPE_MAPPING["const char*"], TYPE_MAPPING["ImageFlags"]]`,
+		`_lib.load_image.restype = TYPE_MAPPING["ImageHandle"]`,
+		"'ImageHandle': ctypes.c_void_p,",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+}
+
+func TestGenerateVerbatimDescriptions(t *testing.T) {
+	tmpDir := t.TempDir()
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name:        "clamp",
+				Description: `Returns lo if x < lo, hi if x > hi && lo <= hi, else x`,
+				Docstring:   `Never returns """ or a \ escape`,
+				Parameters:  []config.Param{{Name: "x", Type: "int", Description: "value & 'bound'"}},
+				ReturnType:  "int",
+			},
+		},
+		Types: []config.TypeConfig{
+			{Name: "Range", Kind: "struct", Fields: []config.Field{{Name: "lo", Type: "int", Description: "lower <inclusive>\nbound"}}},
+		},
+		LibFileName: "lib'clamp.so",
+	}
+	if _, err := GenerateBindings("clamp", "libclamp.so", tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	path := filepath.Join(tmpDir, "clamp.py")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	for _, expected := range []string{
+		"    Returns lo if x < lo, hi if x > hi && lo <= hi, else x\n",
+		`    Never returns \"\"\" or a \\ escape`,
+		"x (int): value & 'bound'",
+		"# lower <inclusive> bound\n",
+		`_LIB_NAME = 'lib\'clamp.so'`,
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+
+	// The escaped module is still valid Python
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	cmd := exec.CommandContext(context.Background(), python, "-m", "py_compile", path)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated module does not compile: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateKeywordArguments(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "scale", ReturnType: "double", Parameters: []config.Param{
+				{Name: "value", Type: "double"},
+				{Name: "factor", Type: "double", Default: "2.0f"},
+				{Name: "offset", Type: "int", Default: "0"},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "scaler", "libscaler.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	expected := "def scale(value: float, factor: float = 2.0, offset: int = 0) -> float:"
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Generated module missing expected content: %s", expected)
+	}
+
+	bad := &config.Config{Functions: []config.FunctionConfig{
+		{Name: "f", ReturnType: "int", Parameters: []config.Param{{Name: "x", Type: "int", Default: "x + 1"}}},
+	}}
+	if err := GenerateBindingsTo(io.Discard, "bad", "libbad.so", bad); err == nil {
+		t.Error("GenerateBindingsTo() should reject a default that is not a literal")
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	tmpDir := t.TempDir()
+	libPath := buildLibrary(t, tmpDir, "scaler.cpp",
+		"extern \"C\" double scale(double value, double factor, int offset) { return value * factor + offset; }\n")
+	if _, err := GenerateBindings("scaler", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import scaler\n" +
+		"assert scaler.scale(3.0) == 6.0\n" +
+		"assert scaler.scale(offset=1, factor=10.0, value=2.0) == 21.0\n" +
+		"assert scaler.scale(2.0, offset=5) == 9.0\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Keyword argument script failed: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateDependencyDirs(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "dep_twice", Parameters: []config.Param{{Name: "x", Type: "int"}}, ReturnType: "int"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "withdeps", "libwithdeps.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "_add_dependency_dirs") {
+		t.Error("Loader without dependency_dirs adds dependency directories")
+	}
+
+	testConfig.DependencyDirs = []string{"deps"}
+	buf.Reset()
+	if err := GenerateBindingsTo(&buf, "withdeps", "libwithdeps.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	for _, expected := range []string{
+		"_DEPENDENCY_DIRS = ['deps']",
+		"if sys.platform == 'win32':",
+		"_dll_directories.append(os.add_dll_directory(directory))",
+		"\n_add_dependency_dirs()\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+	if strings.Index(buf.String(), "\n_add_dependency_dirs()") > strings.Index(buf.String(), "_lib = _load_library") {
+		t.Error("Dependency directories are added after the library is loaded")
+	}
+
+	python, err := FindPython()
+	if err != nil || runtime.GOOS != "linux" {
+		return
+	}
+
+	// The library links against libdep.so, which is only in deps
+	tmpDir := t.TempDir()
+	depDir := filepath.Join(tmpDir, "deps")
+	if err := os.Mkdir(depDir, 0755); err != nil {
+		t.Fatalf("Failed to create deps: %v", err)
+	}
+	cc, err := compiler.DetectCompiler(compiler.CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+	compile := func(dir, name, code string, opts *compiler.CompileOptions) string {
+		src := filepath.Join(t.TempDir(), name)
+		if err := os.WriteFile(src, []byte(code), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		libPath, err := compiler.CompileWithOptions(src, dir, cc, opts)
+		if err != nil {
+			t.Fatalf("CompileWithOptions() error = %v", err)
+		}
+		return libPath
+	}
+
+	// Preloaded dependencies are matched by their soname
+	opts := compiler.DefaultCompileOptions()
+	opts.LinkerFlags = []string{"-Wl,-soname,libdep.so"}
+	compile(depDir, "dep.cpp", "extern \"C\" int dep_value() { return 21; }\n", opts)
+
+	opts = compiler.DefaultCompileOptions()
+	opts.LibraryPaths = []string{depDir}
+	opts.Libraries = []string{"dep"}
+	libPath := compile(tmpDir, "withdeps.cpp", "extern \"C\" int dep_value();\nextern \"C\" int dep_twice(int x) { return x * dep_value(); }\n", opts)
+
+	importModule := func() (string, error) {
+		if _, err := GenerateBindings("withdeps", libPath, tmpDir, testConfig); err != nil {
+			t.Fatalf("GenerateBindings() error = %v", err)
+		}
+		script := "import sys; sys.path.insert(0, sys.argv[1]); import withdeps; print(withdeps.dep_twice(2))"
+		output, err := exec.CommandContext(context.Background(), python, "-c", script, tmpDir).CombinedOutput()
+		return string(output), err
+	}
+	if output, err := importModule(); err != nil || strings.TrimSpace(output) != "42" {
+		t.Errorf("Import with dependency_dirs failed: %v\n%s", err, output)
+	}
+	testConfig.DependencyDirs = nil
+	if output, err := importModule(); err == nil {
+		t.Errorf("Expected import to fail without dependency_dirs:\n%s", output)
+	}
+}
+
+func TestGenerateEmbeddedLibrary(t *testing.T) {
+	libPath := buildTestLibrary(t, t.TempDir())
+	lib, err := os.ReadFile(libPath)
+	if err != nil {
+		t.Fatalf("Failed to read library: %v", err)
+	}
+	testConfig := &config.Config{
+		EmbedLibrary: true,
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+	}
+
+	// The module is generated away from the library, which it does not need
+	tmpDir := t.TempDir()
+	if _, err := GenerateBindings("embedded", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, "embedded.py"))
+	if err != nil {
+		t.Fatalf("Failed to read module: %v", err)
+	}
+	encoded := base64Lines(lib)
+	for _, expected := range []string{
+		"_LIB_DATA = (\n    '" + encoded[0] + "'\n",
+		"'" + encoded[len(encoded)-1] + "'\n)\n",
+		"def _load_embedded_library(name):",
+		"directory = tempfile.mkdtemp(prefix='embedded-')",
+		"_lib = _load_embedded_library(_LIB_NAME)",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+	if got := strings.Join(encoded, ""); got != base64.StdEncoding.EncodeToString(lib) {
+		t.Error("base64Lines() lines do not join to the library's encoding")
+	}
+
+	testConfig.CrossPlatformLoader = true
+	if err := GenerateBindingsTo(io.Discard, "embedded", libPath, testConfig); err == nil {
+		t.Error("Expected an error embedding a library with per-platform names")
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import embedded; print(embedded.add(2, 3))"
+	output, err := exec.CommandContext(context.Background(), python, "-c", script, tmpDir).CombinedOutput()
+	if err != nil || strings.TrimSpace(string(output)) != "5" {
+		t.Errorf("Import of the embedded library failed: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateStdcall(t *testing.T) {
+	testConfig := *callbackConfig
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "winapi", "winapi.dll", &testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "WinDLL") || strings.Contains(buf.String(), "WINFUNCTYPE") {
+		t.Error("cdecl module uses stdcall loading")
+	}
+
+	testConfig.CallingConvention = ConventionStdcall
+	buf.Reset()
+	if err := GenerateBindingsTo(&buf, "winapi", "winapi.dll", &testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	// ctypes has WinDLL and WINFUNCTYPE only on Windows
+	for _, expected := range []string{
+		"return (ctypes.WinDLL if sys.platform == 'win32' else ctypes.CDLL)(name)",
+		"return (ctypes.WinDLL if sys.platform == 'win32' else ctypes.CDLL)(path)",
+		"'visit_fn': (ctypes.WINFUNCTYPE if sys.platform == 'win32' else ctypes.CFUNCTYPE)(",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+	if strings.Contains(buf.String(), "return ctypes.CDLL(") || strings.Contains(buf.String(), "ctypes.CFUNCTYPE(") {
+		t.Error("stdcall module uses cdecl loading")
+	}
+
+	testConfig.OutputBackend = BackendCFFI
+	if err := GenerateBindingsTo(io.Discard, "winapi", "winapi.dll", &testConfig); err == nil {
+		t.Error("Expected an error for stdcall with the cffi backend")
+	}
+	testConfig.OutputBackend = ""
+	testConfig.CallingConvention = "fastcall"
+	if err := GenerateBindingsTo(io.Discard, "winapi", "winapi.dll", &testConfig); err == nil {
+		t.Error("Expected an error for an unknown calling convention")
+	}
+}
+
+func TestGenerateStrictInt(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "widen", Parameters: []config.Param{{Name: "x", Type: "uint8_t"}, {Name: "scale", Type: "double"}}, ReturnType: "double"},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "strict", "libstrict.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "_check_int") {
+		t.Error("Module without strict_int checks integer arguments")
+	}
+
+	testConfig.StrictInt = true
+	buf.Reset()
+	if err := GenerateBindingsTo(&buf, "strict", "libstrict.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `_check_int('x', x, TYPE_MAPPING["uint8_t"])`) {
+		t.Error("Generated module does not check the uint8_t argument")
+	}
+	if strings.Contains(buf.String(), "_check_int('scale'") {
+		t.Error("Generated module checks the double argument")
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	tmpDir := t.TempDir()
+	libPath := buildLibrary(t, tmpDir, "strict.cpp",
+		"#include <cstdint>\nextern \"C\" double widen(uint8_t x, double scale) { return x * scale; }\n")
+	if _, err := GenerateBindings("strict", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import strict\n" +
+		"assert strict.widen(255, 1.0) == 255.0\n" +
+		"for value in (256, 300, -1):\n" +
+		"    try:\n" +
+		"        strict.widen(value, 1.0)\n" +
+		"    except OverflowError:\n" +
+		"        pass\n" +
+		"    else:\n" +
+		"        raise AssertionError('%d did not overflow' % value)\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Strict int script failed: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateLineEndings(t *testing.T) {
+	for _, lineEndings := range []string{"", config.LineEndingsCRLF} {
+		t.Run("line_endings="+lineEndings, func(t *testing.T) {
+			testConfig := &config.Config{
+				Functions: []config.FunctionConfig{
+					{Name: "add", Description: "Adds two integers\r\nwith CRLF in the config", Parameters: []config.Param{{Name: "a", Type: "int"}}, ReturnType: "int"},
+				},
+				GenerateTests: true,
+				LineEndings:   lineEndings,
+			}
+			result, err := GenerateBindings("endings", "test.dll", t.TempDir(), testConfig)
+			if err != nil {
+				t.Fatalf("GenerateBindings() error = %v", err)
+			}
+			for _, path := range result.FilesWritten {
+				content, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatalf("Failed to read %s: %v", path, err)
+				}
+				text := string(content)
+				if lineEndings == config.LineEndingsCRLF {
+					text = strings.ReplaceAll(text, "\r\n", "\n")
+					if strings.Count(string(content), "\r\n") != strings.Count(text, "\n") {
+						t.Errorf("%s has line endings other than CRLF", filepath.Base(path))
+					}
+				}
+				if strings.Contains(text, "\r") {
+					t.Errorf("%s contains a stray \\r", filepath.Base(path))
+				}
+			}
+		})
+	}
+}
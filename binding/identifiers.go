@@ -0,0 +1,79 @@
+package binding
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+
+	"cp2p/config"
+)
+
+// pythonKeywords are the reserved words of Python 3, which cannot name a
+// function, parameter or variable. Soft keywords such as match are usable.
+var pythonKeywords = []string{
+	"False", "None", "True", "and", "as", "assert", "async", "await",
+	"break", "class", "continue", "def", "del", "elif", "else", "except",
+	"finally", "for", "from", "global", "if", "import", "in", "is",
+	"lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try",
+	"while", "with", "yield",
+}
+
+var identifierRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// isPythonKeyword reports whether name is a Python reserved word
+func isPythonKeyword(name string) bool {
+	return slices.Contains(pythonKeywords, name)
+}
+
+// pythonName returns name usable as a Python identifier: reserved words get a
+// trailing underscore, as PEP 8 suggests, and other names are kept
+func pythonName(name string) string {
+	if isPythonKeyword(name) {
+		return name + "_"
+	}
+	return name
+}
+
+// sanitizeNames renames the Python functions and parameters of fn that are
+// reserved words. The C symbol is kept, and C calls pass arguments by
+// position, so the bound function is unaffected.
+func sanitizeNames(fn config.FunctionConfig) config.FunctionConfig {
+	if name := pythonName(fn.PyName()); name != fn.PyName() {
+		fn.PythonName = name
+	}
+	for i := range fn.Parameters {
+		fn.Parameters[i].Name = pythonName(fn.Parameters[i].Name)
+	}
+	return fn
+}
+
+// validateIdentifiers checks that every name the module defines is a valid
+// Python identifier
+func (g *PythonGenerator) validateIdentifiers() error {
+	for _, fn := range g.functions {
+		if !identifierRe.MatchString(fn.PyName()) {
+			return fmt.Errorf("function %s: %q is not a valid Python identifier (set python_name)", fn.Name, fn.PyName())
+		}
+		for i, p := range fn.Parameters {
+			if !identifierRe.MatchString(p.Name) {
+				return fmt.Errorf("parameter %d of function %s: %q is not a valid Python identifier", i, fn.Name, p.Name)
+			}
+		}
+	}
+	for _, c := range g.config.Constants {
+		if !identifierRe.MatchString(pythonName(c.Name)) {
+			return fmt.Errorf("constant %q is not a valid Python identifier", c.Name)
+		}
+	}
+	return nil
+}
+
+// libAttr returns the Python expression for symbol on the loaded library,
+// using getattr for symbols that are not usable as attribute names, such as
+// reserved words
+func libAttr(symbol string) string {
+	if isPythonKeyword(symbol) || !identifierRe.MatchString(symbol) {
+		return "getattr(_lib, '" + pythonStringEscaper.Replace(symbol) + "')"
+	}
+	return "_lib." + symbol
+}
@@ -0,0 +1,91 @@
+package binding
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestGeneratePythonKeywords(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "scale", Parameters: []config.Param{{Name: "class", Type: "int"}, {Name: "lambda", Type: "int"}}, ReturnType: "int"},
+			{Name: "from", Parameters: []config.Param{{Name: "a", Type: "int"}}, ReturnType: "int"},
+		},
+		Constants: []config.ConstantConfig{{Name: "import", Type: "int", Value: "3"}},
+	}
+
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "keywords", "libkeywords.so", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	for _, expected := range []string{
+		"def scale(class_: int, lambda_: int) -> int:",
+		"return _lib.scale(class_, lambda_)",
+		"def from_(a: int) -> int:",
+		"getattr(_lib, 'from').argtypes = [",
+		"return getattr(_lib, 'from')(a)",
+		"import_: int = 3",
+		"'from_'",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Generated module missing expected content: %s", expected)
+		}
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	tmpDir := t.TempDir()
+	libPath := buildLibrary(t, tmpDir, "keywords.cpp",
+		"extern \"C\" int scale(int a, int b) { return a * b; }\n"+
+			"extern \"C\" int from(int a) { return a + 1; }\n")
+	if _, err := GenerateBindings("keywords", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import keywords\n" +
+		"assert keywords.scale(3, 4) == 12\n" +
+		"assert keywords.scale(class_=3, lambda_=5) == 15\n" +
+		"assert keywords.from_(1) == 2\n" +
+		"assert keywords.import_ == 3\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Keyword script failed: %v\n%s", err, output)
+	}
+}
+
+func TestGenerateInvalidIdentifiers(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   config.FunctionConfig
+	}{
+		{"function name", config.FunctionConfig{Name: "operator+", ReturnType: "int"}},
+		{"python name", config.FunctionConfig{Name: "add", PythonName: "add-ints", ReturnType: "int"}},
+		{"parameter name", config.FunctionConfig{Name: "add", Parameters: []config.Param{{Name: "a b", Type: "int"}}, ReturnType: "int"}},
+		{"empty parameter name", config.FunctionConfig{Name: "add", Parameters: []config.Param{{Type: "int"}}, ReturnType: "int"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testConfig := &config.Config{Functions: []config.FunctionConfig{tt.fn}}
+			var buf bytes.Buffer
+			if err := GenerateBindingsTo(&buf, "test", "libtest.so", testConfig); err == nil {
+				t.Error("Expected an error for an invalid Python identifier")
+			}
+		})
+	}
+
+	// A symbol that is not an identifier is looked up by name
+	testConfig := &config.Config{Functions: []config.FunctionConfig{{Name: "add", Symbol: "?add@@YAHHH@Z", ReturnType: "int"}}}
+	var buf bytes.Buffer
+	if err := GenerateBindingsTo(&buf, "test", "test.dll", testConfig); err != nil {
+		t.Fatalf("GenerateBindingsTo() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "getattr(_lib, '?add@@YAHHH@Z')") {
+		t.Error("Expected the mangled symbol to be looked up with getattr")
+	}
+}
@@ -0,0 +1,48 @@
+package binding
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"cp2p/config"
+)
+
+// Output languages, selecting the language the bindings are generated for
+const (
+	LangPython = "python"
+)
+
+// Generator generates bindings for a compiled library in one output language.
+// Each language implements it; PythonGenerator is the first.
+type Generator interface {
+	// Generate writes the bindings to the output directory
+	Generate() (*GenerationResult, error)
+	// KnowsType reports whether functions using the C type t can be bound
+	KnowsType(t string) bool
+	// PublicAPI returns the functions and types the bindings export
+	PublicAPI() []APISymbol
+}
+
+// NewLanguageGenerator returns the generator for lang, where "" means
+// Python, binding the library at libPath. The library is named in the
+// bindings by its file name, or by cfg.LibFileName if set.
+func NewLanguageGenerator(lang, moduleName, libPath, outputDir string, cfg *config.Config) (Generator, error) {
+	libFileName := filepath.Base(libPath)
+	if cfg.LibFileName != "" {
+		libFileName = cfg.LibFileName
+	}
+
+	switch lang {
+	case "", LangPython:
+		gen := NewGenerator(moduleName, libFileName, outputDir, cfg)
+		gen.libFile = libPath
+		return gen, nil
+	default:
+		return nil, fmt.Errorf("unknown output language %q (expected %s)", lang, LangPython)
+	}
+}
+
+// Generate writes the Python module and its requirements file
+func (g *PythonGenerator) Generate() (*GenerationResult, error) {
+	return g.generate()
+}
@@ -0,0 +1,62 @@
+package binding
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"cp2p/config"
+)
+
+// The Python generator is the first Generator implementation
+var _ Generator = (*PythonGenerator)(nil)
+
+func TestNewLanguageGenerator(t *testing.T) {
+	testConfig := &config.Config{
+		Reproducible: true,
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+	}
+	libPath := filepath.Join(t.TempDir(), "libmath.so")
+
+	for _, lang := range []string{"", LangPython} {
+		outputDir := t.TempDir()
+		gen, err := NewLanguageGenerator(lang, "math", libPath, outputDir, testConfig)
+		if err != nil {
+			t.Fatalf("NewLanguageGenerator(%q) error = %v", lang, err)
+		}
+		if !gen.KnowsType("int") || gen.KnowsType("std::string") {
+			t.Errorf("NewLanguageGenerator(%q) KnowsType does not match the Python type registry", lang)
+		}
+		result, err := gen.Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if result.FunctionsBound != 1 {
+			t.Errorf("FunctionsBound = %d, want 1", result.FunctionsBound)
+		}
+
+		// The module matches the one GenerateBindingsTo writes directly
+		got, err := os.ReadFile(filepath.Join(outputDir, "math.py"))
+		if err != nil {
+			t.Fatalf("Failed to read module: %v", err)
+		}
+		var want bytes.Buffer
+		if err := GenerateBindingsTo(&want, "math", libPath, testConfig); err != nil {
+			t.Fatalf("GenerateBindingsTo() error = %v", err)
+		}
+		if !bytes.Equal(got, want.Bytes()) {
+			t.Errorf("Module generated for %q differs from GenerateBindingsTo output", lang)
+		}
+	}
+
+	if _, err := NewLanguageGenerator("rust", "math", libPath, t.TempDir(), testConfig); err == nil {
+		t.Error("Expected an error for an unknown output language")
+	}
+	testConfig.OutputLang = "rust"
+	if _, err := GenerateBindings("math", libPath, t.TempDir(), testConfig); err == nil {
+		t.Error("Expected GenerateBindings() to reject an unknown output language")
+	}
+}
@@ -0,0 +1,169 @@
+package binding
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+
+	"cp2p/config"
+	"cp2p/util"
+)
+
+// ManifestFile is written next to the generated bindings, recording what they
+// were generated from so unchanged inputs need not be generated again
+const ManifestFile = ".cp2p-manifest.json"
+
+// templateSources are the templates the generated files depend on
+var templateSources = []string{
+	headerTemplate,
+	constantsTemplate,
+	libraryLoaderTemplate,
+	pythonBindingTemplate,
+	cffiTemplate,
+	testsTemplate,
+}
+
+// manifest is the content of ManifestFile
+type manifest struct {
+	Inputs  string            `json:"inputs"`  // SHA-256 of the config, names and templates
+	Library string            `json:"library"` // SHA-256 of the library, empty if it could not be read
+	Result  *GenerationResult `json:"result"`  // Result of the generation, with files relative to the output directory
+
+	BuildFiles []string `json:"build_files,omitempty"` // Names of the files the build wrote next to the bindings
+}
+
+// UpToDate reports whether outputDir holds bindings that GenerateBindings
+// would generate again unchanged, because the config and library have not
+// changed since, and returns the result of that generation if so
+func UpToDate(moduleName, libPath, outputDir string, cfg *config.Config) (*GenerationResult, bool) {
+	gen, err := NewLanguageGenerator(cfg.OutputLang, moduleName, libPath, outputDir, cfg)
+	if err != nil {
+		return nil, false
+	}
+	py, ok := gen.(*PythonGenerator)
+	if !ok {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(filepath.Join(outputDir, ManifestFile))
+	if err != nil {
+		return nil, false
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil || m.Result == nil {
+		return nil, false
+	}
+
+	current, err := py.manifest(m.Result)
+	if err != nil || current.Library == "" || current.Library != m.Library || current.Inputs != m.Inputs {
+		return nil, false
+	}
+	for i, name := range m.Result.FilesWritten {
+		path := filepath.Join(outputDir, name)
+		if !util.FileExists(path) {
+			return nil, false
+		}
+		m.Result.FilesWritten[i] = path
+	}
+	return m.Result, true
+}
+
+// OutputFiles returns the files GenerateBindings writes to outputDir for the
+// module, including ManifestFile
+func OutputFiles(moduleName, outputDir string, cfg *config.Config) []string {
+	files := []string{
+		filepath.Join(outputDir, moduleName+".py"),
+		filepath.Join(outputDir, "requirements.txt"),
+	}
+	if cfg.GenerateTests {
+		files = append(files, filepath.Join(outputDir, "test_"+moduleName+".py"))
+	}
+	return append(files, filepath.Join(outputDir, ManifestFile))
+}
+
+// UserFiles returns those of paths in outputDir that exist but were not
+// written by an earlier generation there, as recorded in its manifest, so
+// that writing them would overwrite the user's own files, e.g. when the
+// output directory is the source directory
+func UserFiles(outputDir string, paths []string) []string {
+	generated := map[string]bool{ManifestFile: true}
+	if m, ok := readManifest(outputDir); ok {
+		if m.Result != nil {
+			for _, name := range m.Result.FilesWritten {
+				generated[name] = true
+			}
+		}
+		for _, name := range m.BuildFiles {
+			generated[name] = true
+		}
+	}
+
+	var user []string
+	for _, path := range paths {
+		if !generated[filepath.Base(path)] && util.FileExists(path) {
+			user = append(user, path)
+		}
+	}
+	return user
+}
+
+// readManifest reads the ManifestFile in outputDir, reporting false if there
+// is none or it cannot be parsed
+func readManifest(outputDir string) (*manifest, bool) {
+	data, err := os.ReadFile(filepath.Join(outputDir, ManifestFile))
+	if err != nil {
+		return nil, false
+	}
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, false
+	}
+	return &m, true
+}
+
+// manifest returns the manifest recording result for the generator's inputs
+func (g *PythonGenerator) manifest(result *GenerationResult) (*manifest, error) {
+	inputs, err := json.Marshal(struct {
+		Config     *config.Config
+		SourceFile string // Not part of the config's JSON
+		ModuleName string
+		LibPath    string
+		Templates  []string
+	}{g.config, g.config.SourceFile, g.moduleName, g.libPath, templateSources})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(inputs)
+
+	// A library that cannot be read, e.g. when only previewing, never matches
+	library, _ := util.FileSHA256(g.libFile)
+	return &manifest{Inputs: hex.EncodeToString(sum[:]), Library: library, Result: result}, nil
+}
+
+// writeManifest records result in ManifestFile, adding it to the files written.
+// Files are recorded by name, keeping reproducible output free of absolute paths.
+func (g *PythonGenerator) writeManifest(result *GenerationResult) error {
+	path := filepath.Join(g.outputDir, ManifestFile)
+	result.FilesWritten = append(result.FilesWritten, path)
+
+	recorded := *result
+	recorded.FilesWritten = nil
+	for _, written := range result.FilesWritten {
+		recorded.FilesWritten = append(recorded.FilesWritten, filepath.Base(written))
+	}
+	m, err := g.manifest(&recorded)
+	if err != nil {
+		return err
+	}
+	for _, built := range g.config.BuildFiles {
+		m.BuildFiles = append(m.BuildFiles, filepath.Base(built))
+	}
+	return g.writeFile(path, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(m)
+	})
+}
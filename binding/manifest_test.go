@@ -0,0 +1,99 @@
+package binding
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestUpToDate(t *testing.T) {
+	tmpDir := t.TempDir()
+	outputDir := filepath.Join(tmpDir, "out")
+	libPath := filepath.Join(tmpDir, "libmath.so")
+	if err := os.WriteFile(libPath, []byte("library"), 0644); err != nil {
+		t.Fatalf("Failed to create library: %v", err)
+	}
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}, ReturnType: "int"},
+		},
+	}
+
+	if _, ok := UpToDate("math", libPath, outputDir, testConfig); ok {
+		t.Fatal("UpToDate() = true before generation")
+	}
+	generated, err := GenerateBindings("math", libPath, outputDir, testConfig)
+	if err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	cached, ok := UpToDate("math", libPath, outputDir, testConfig)
+	if !ok {
+		t.Fatal("UpToDate() = false after generation")
+	}
+	if !slices.Equal(cached.FilesWritten, generated.FilesWritten) || cached.FunctionsBound != generated.FunctionsBound {
+		t.Errorf("UpToDate() result = %+v, want %+v", cached, generated)
+	}
+
+	changed := *testConfig
+	changed.StrictInt = true
+	if _, ok := UpToDate("math", libPath, outputDir, &changed); ok {
+		t.Error("UpToDate() = true after the config changed")
+	}
+	if _, ok := UpToDate("other", libPath, outputDir, testConfig); ok {
+		t.Error("UpToDate() = true for another module name")
+	}
+
+	if err := os.Remove(filepath.Join(outputDir, "math.py")); err != nil {
+		t.Fatalf("Failed to remove module: %v", err)
+	}
+	if _, ok := UpToDate("math", libPath, outputDir, testConfig); ok {
+		t.Error("UpToDate() = true with the module missing")
+	}
+	if _, err := GenerateBindings("math", libPath, outputDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	if err := os.WriteFile(libPath, []byte("rebuilt library"), 0644); err != nil {
+		t.Fatalf("Failed to rewrite library: %v", err)
+	}
+	if _, ok := UpToDate("math", libPath, outputDir, testConfig); ok {
+		t.Error("UpToDate() = true after the library changed")
+	}
+}
+
+func TestUserFiles(t *testing.T) {
+	outputDir := t.TempDir()
+	libPath := filepath.Join(outputDir, "libmath.so")
+	userFile := filepath.Join(outputDir, "math.py")
+	for _, path := range []string{libPath, userFile} {
+		if err := os.WriteFile(path, []byte("user"), 0644); err != nil {
+			t.Fatalf("Failed to create %s: %v", path, err)
+		}
+	}
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}}, ReturnType: "int"},
+		},
+	}
+	outputs := append([]string{libPath}, OutputFiles("math", outputDir, testConfig)...)
+
+	// Without a manifest every existing file is the user's; missing ones are not
+	if user := UserFiles(outputDir, outputs); !slices.Equal(user, []string{libPath, userFile}) {
+		t.Errorf("UserFiles() = %v, want [%s %s]", user, libPath, userFile)
+	}
+
+	testConfig.BuildFiles = []string{libPath}
+	generated, err := GenerateBindings("math", libPath, outputDir, testConfig)
+	if err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if !slices.Equal(generated.FilesWritten, outputs[1:]) {
+		t.Errorf("FilesWritten = %v, want OutputFiles() %v", generated.FilesWritten, outputs[1:])
+	}
+	if user := UserFiles(outputDir, outputs); len(user) != 0 {
+		t.Errorf("UserFiles() after generation = %v, want none", user)
+	}
+}
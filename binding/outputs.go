@@ -0,0 +1,56 @@
+package binding
+
+import (
+	"fmt"
+	"strings"
+
+	"cp2p/config"
+)
+
+// validateOutputs checks that out and inout parameters point to plain values.
+// Struct pointers are already passed by reference, so the caller's instance
+// sees what the function writes.
+func (g *PythonGenerator) validateOutputs() error {
+	declared := declaredTypes(g.types)
+	for _, fn := range g.functions {
+		for _, p := range fn.Outputs() {
+			if declared[p.ValueType()] {
+				return fmt.Errorf("%s parameter %s of function %s points to declared type %s; pass an instance instead", p.Direction, p.Name, fn.Name, p.ValueType())
+			}
+		}
+	}
+	return nil
+}
+
+// outputReturnHint returns the hint for what a wrapper with output parameters
+// returns: the value alone when there is just one, otherwise a tuple of the
+// declared return followed by the outputs
+func (g *PythonGenerator) outputReturnHint(fn config.FunctionConfig, returnClasses map[string]bool) string {
+	var hints []string
+	if fn.ReturnType != "void" {
+		hints = append(hints, g.returnHint(fn, returnClasses))
+	}
+	for _, p := range fn.Outputs() {
+		hints = append(hints, g.paramHint(p))
+	}
+	if len(hints) == 1 {
+		return hints[0]
+	}
+	return "Tuple[" + strings.Join(hints, ", ") + "]"
+}
+
+// outputResults returns the expression a wrapper with output parameters
+// returns, matching outputReturnHint
+func outputResults(fn config.FunctionConfig) string {
+	var values []string
+	if fn.ReturnType != "void" {
+		values = append(values, "_result")
+	}
+	for _, p := range fn.Outputs() {
+		values = append(values, p.Name+".value")
+	}
+	if len(values) == 1 {
+		return values[0]
+	}
+	return "(" + strings.Join(values, ", ") + ")"
+}
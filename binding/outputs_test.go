@@ -0,0 +1,87 @@
+package binding
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestGenerateOutputParameters(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name: "divmod",
+				Parameters: []config.Param{
+					{Name: "a", Type: "int"},
+					{Name: "b", Type: "int"},
+					{Name: "q", Type: "int*", Direction: config.DirectionOut},
+					{Name: "r", Type: "int*", Direction: config.DirectionOut},
+				},
+				ReturnType: "void",
+			},
+			{
+				Name: "bump",
+				Parameters: []config.Param{
+					{Name: "counter", Type: "long *", Direction: config.DirectionInOut},
+				},
+				ReturnType: "int",
+			},
+		},
+	}
+
+	var module strings.Builder
+	gen := NewGenerator("outputs", "liboutputs.so", "", testConfig)
+	result := &GenerationResult{}
+	if err := gen.writeModule(&module, result); err != nil {
+		t.Fatalf("writeModule() error = %v", err)
+	}
+	if result.FunctionsBound != 2 {
+		t.Errorf("FunctionsBound = %d, want 2 (skipped %v)", result.FunctionsBound, result.SkippedFunctions)
+	}
+	for _, expected := range []string{
+		`ctypes.POINTER(TYPE_MAPPING["int"]), ctypes.POINTER(TYPE_MAPPING["int"])]`,
+		"def divmod(a: int, b: int) -> Tuple[int, int]:",
+		"return (q.value, r.value)",
+		"def bump(counter: int) -> Tuple[int, int]:",
+		`counter = TYPE_MAPPING["long"](counter)`,
+	} {
+		if !strings.Contains(module.String(), expected) {
+			t.Errorf("Generated module missing %q", expected)
+		}
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	tmpDir := t.TempDir()
+	libPath := buildLibrary(t, tmpDir, "outputs.cpp", `extern "C" void divmod(int a, int b, int* q, int* r) { *q = a / b; *r = a % b; }
+extern "C" int bump(long* counter) { return (int)(*counter)++; }
+`)
+	if _, err := GenerateBindings("outputs", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import outputs\n" +
+		"assert outputs.divmod(7, 2) == (3, 1), outputs.divmod(7, 2)\n" +
+		"assert outputs.bump(41) == (41, 42), outputs.bump(41)\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Output parameter script failed: %v\n%s", err, output)
+	}
+}
+
+func TestValidateOutputParametersRejectsStructs(t *testing.T) {
+	cfg := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "load", Parameters: []config.Param{{Name: "cfg", Type: "Config*", Direction: config.DirectionOut}}, ReturnType: "void"},
+		},
+		Types: []config.TypeConfig{{Name: "Config", Kind: "struct", Fields: []config.Field{{Name: "width", Type: "int"}}}},
+	}
+	err := NewGenerator("settings", "libsettings.so", "", cfg).writeModule(&strings.Builder{}, &GenerationResult{})
+	if err == nil || !strings.Contains(err.Error(), "points to declared type Config") {
+		t.Errorf("writeModule() error = %v, want a declared type error", err)
+	}
+}
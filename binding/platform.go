@@ -0,0 +1,61 @@
+package binding
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// platformLibName is the library file name the loader uses on one platform
+type platformLibName struct {
+	Platform string // Python's sys.platform value
+	FileName string
+}
+
+// sysPlatforms maps GOOS values to Python's sys.platform
+var sysPlatforms = map[string]string{
+	"windows": "win32",
+	"linux":   "linux",
+	"darwin":  "darwin",
+}
+
+// platformLibNames returns the per-platform library file names for a
+// cross-platform loader, sorted by platform, or nil if the loader uses a single name
+func (g *PythonGenerator) platformLibNames() []platformLibName {
+	if !g.config.CrossPlatformLoader && len(g.config.LibFileNames) == 0 {
+		return nil
+	}
+
+	byPlatform := make(map[string]string)
+	if g.config.CrossPlatformLoader {
+		base := libBaseName(g.libPath)
+		byPlatform["win32"] = base + ".dll"
+		byPlatform["linux"] = "lib" + base + ".so"
+		byPlatform["darwin"] = "lib" + base + ".dylib"
+	}
+	for goos, name := range g.config.LibFileNames {
+		platform, ok := sysPlatforms[goos]
+		if !ok {
+			platform = goos // Taken as a sys.platform value
+		}
+		byPlatform[platform] = name
+	}
+
+	names := make([]platformLibName, 0, len(byPlatform))
+	for platform, name := range byPlatform {
+		names = append(names, platformLibName{Platform: platform, FileName: name})
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].Platform < names[j].Platform })
+	return names
+}
+
+// libBaseName strips the platform prefix and extension from a library file
+// name, e.g. "libfoo.so" and "foo.dll" both give "foo"
+func libBaseName(libName string) string {
+	ext := filepath.Ext(libName)
+	base := strings.TrimSuffix(libName, ext)
+	if ext == ".so" || ext == ".dylib" {
+		base = strings.TrimPrefix(base, "lib")
+	}
+	return base
+}
@@ -0,0 +1,65 @@
+package binding
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parsePythonVersion returns the minor version of a "3.N" Python version
+func parsePythonVersion(version string) (int, error) {
+	major, minor, ok := strings.Cut(version, ".")
+	n, err := strconv.Atoi(minor)
+	if !ok || major != "3" || err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid python_version %q (expected 3.N, e.g. 3.8)", version)
+	}
+	return n, nil
+}
+
+// unionSyntax reports whether type hints may use the X | None syntax, which
+// needs Python 3.10. Without a PythonVersion, hints stay compatible with
+// older versions.
+func (g *PythonGenerator) unionSyntax() bool {
+	minor, err := parsePythonVersion(g.config.PythonVersion)
+	return err == nil && minor >= 10
+}
+
+// optionalHint returns the type hint for hint or None
+func (g *PythonGenerator) optionalHint(hint string) string {
+	if g.unionSyntax() {
+		return hint + " | None"
+	}
+	return "Optional[" + hint + "]"
+}
+
+// typingImports returns the names the module imports from typing. Union and
+// Optional are left out where the X | None syntax replaces them, unless a
+// configured type hint uses them.
+func (g *PythonGenerator) typingImports() []string {
+	if !g.unionSyntax() || g.usesTyping("Union[", "Optional[") {
+		return []string{"Any", "Union", "Optional", "List", "Dict", "Tuple"}
+	}
+	return []string{"Any", "List", "Dict", "Tuple"}
+}
+
+// usesTyping reports whether any type hint contains one of names
+func (g *PythonGenerator) usesTyping(names ...string) bool {
+	hints := []string{}
+	for _, hint := range g.registry.hints {
+		hints = append(hints, hint)
+	}
+	for _, fn := range g.functions {
+		hints = append(hints, fn.ReturnPyType)
+		for _, p := range fn.Parameters {
+			hints = append(hints, p.PyType)
+		}
+	}
+	for _, hint := range hints {
+		for _, name := range names {
+			if strings.Contains(hint, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
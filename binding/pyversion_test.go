@@ -0,0 +1,68 @@
+package binding
+
+import (
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestGeneratePythonVersion(t *testing.T) {
+	tests := []struct {
+		version   string
+		hint      string
+		imports   string
+		wantError bool
+	}{
+		{version: "", hint: "-> Optional[int]:", imports: "from typing import Any, Union, Optional, List, Dict, Tuple\n"},
+		{version: "3.8", hint: "-> Optional[int]:", imports: "from typing import Any, Union, Optional, List, Dict, Tuple\n"},
+		{version: "3.11", hint: "-> int | None:", imports: "from typing import Any, List, Dict, Tuple\n"},
+		{version: "2.7", wantError: true},
+		{version: "3", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			testConfig := &config.Config{
+				Functions: []config.FunctionConfig{
+					{Name: "open_handle", Parameters: []config.Param{{Name: "flags", Type: "int"}}, ReturnType: "Handle"},
+				},
+				Types:         []config.TypeConfig{{Name: "Handle", Kind: "opaque"}},
+				PythonVersion: tt.version,
+			}
+			var module strings.Builder
+			err := NewGenerator("handles", "libhandles.so", "", testConfig).writeModule(&module, &GenerationResult{})
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("writeModule() with python_version %q succeeded, want an error", tt.version)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("writeModule() error = %v", err)
+			}
+			if !strings.Contains(module.String(), "def open_handle(flags: int) "+tt.hint) {
+				t.Errorf("Module does not use the hint %q", tt.hint)
+			}
+			if !strings.Contains(module.String(), tt.imports) {
+				t.Errorf("Module does not import %q", tt.imports)
+			}
+		})
+	}
+}
+
+func TestGeneratePythonVersionKeepsConfiguredHints(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "name", Parameters: []config.Param{}, ReturnType: "const char*", ReturnPyType: "Optional[str]"},
+		},
+		PythonVersion: "3.12",
+	}
+	var module strings.Builder
+	if err := NewGenerator("names", "libnames.so", "", testConfig).writeModule(&module, &GenerationResult{}); err != nil {
+		t.Fatalf("writeModule() error = %v", err)
+	}
+	if !strings.Contains(module.String(), "Optional") || !strings.Contains(module.String(), "from typing import Any, Union, Optional") {
+		t.Error("Module using Optional in a configured hint does not import it")
+	}
+}
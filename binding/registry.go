@@ -0,0 +1,143 @@
+package binding
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+// TypeRegistry maps C types to the ctypes expressions used in argtypes/restype
+// and the Python type hints used in wrapper signatures
+type TypeRegistry struct {
+	ctypes map[string]string
+	hints  map[string]string
+}
+
+// NewTypeRegistry creates an empty type registry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		ctypes: map[string]string{},
+		hints:  map[string]string{},
+	}
+}
+
+// DefaultTypeRegistry creates a registry seeded with the built-in mappings
+func DefaultTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		ctypes: maps.Clone(typeMappings),
+		hints:  maps.Clone(pythonTypeHints),
+	}
+}
+
+// Register adds or replaces the mapping for cType, under its normalized
+// spelling (see NormalizeType). An empty pyHint falls back to "Any".
+func (r *TypeRegistry) Register(cType, ctypesExpr, pyHint string) {
+	cType = NormalizeType(cType)
+	if pyHint == "" {
+		pyHint = "Any"
+	}
+	r.ctypes[cType] = ctypesExpr
+	r.hints[cType] = pyHint
+}
+
+// Lookup returns the ctypes expression and Python type hint registered for cType
+func (r *TypeRegistry) Lookup(cType string) (ctypesExpr, pyHint string, ok bool) {
+	cType = NormalizeType(cType)
+	ctypesExpr, ok = r.ctypes[cType]
+	return ctypesExpr, r.hints[cType], ok
+}
+
+// Has reports whether cType has a registered mapping
+func (r *TypeRegistry) Has(cType string) bool {
+	_, ok := r.ctypes[NormalizeType(cType)]
+	return ok
+}
+
+// Types returns the registered C types in sorted order
+func (r *TypeRegistry) Types() []string {
+	return slices.Sorted(maps.Keys(r.ctypes))
+}
+
+// WriteTable writes one "ctype -> ctypes (hint)" line per registered C type, sorted by C type
+func (r *TypeRegistry) WriteTable(w io.Writer) error {
+	for _, cType := range r.Types() {
+		if _, err := fmt.Fprintf(w, "%s -> %s (%s)\n", cType, r.ctypes[cType], r.hints[cType]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// typeMappings maps C types to their ctypes equivalents
+var typeMappings = map[string]string{
+	"int":                "ctypes.c_int",
+	"float":              "ctypes.c_float",
+	"double":             "ctypes.c_double",
+	"char":               "ctypes.c_char",
+	"bool":               "ctypes.c_bool",
+	"void":               "None",
+	"const char*":        "ctypes.c_char_p",
+	"signed char":        "ctypes.c_byte",
+	"unsigned char":      "ctypes.c_ubyte",
+	"short":              "ctypes.c_short",
+	"unsigned short":     "ctypes.c_ushort",
+	"unsigned int":       "ctypes.c_uint",
+	"long":               "ctypes.c_long",
+	"unsigned long":      "ctypes.c_ulong",
+	"long long":          "ctypes.c_longlong",
+	"unsigned long long": "ctypes.c_ulonglong",
+	"size_t":             "ctypes.c_size_t",
+	"int8_t":             "ctypes.c_int8",
+	"uint8_t":            "ctypes.c_uint8",
+	"int16_t":            "ctypes.c_int16",
+	"uint16_t":           "ctypes.c_uint16",
+	"int32_t":            "ctypes.c_int32",
+	"uint32_t":           "ctypes.c_uint32",
+	"int64_t":            "ctypes.c_int64",
+	"uint64_t":           "ctypes.c_uint64",
+}
+
+// pythonTypeHints maps C types to the Python type hints used in wrapper signatures
+var pythonTypeHints = map[string]string{
+	"int":                "int",
+	"float":              "float",
+	"double":             "float",
+	"char":               "str",
+	"bool":               "bool",
+	"void":               "None",
+	"const char*":        "str",
+	"signed char":        "int",
+	"unsigned char":      "int",
+	"short":              "int",
+	"unsigned short":     "int",
+	"unsigned int":       "int",
+	"long":               "int",
+	"unsigned long":      "int",
+	"long long":          "int",
+	"unsigned long long": "int",
+	"size_t":             "int",
+	"int8_t":             "int",
+	"uint8_t":            "int",
+	"int16_t":            "int",
+	"uint16_t":           "int",
+	"int32_t":            "int",
+	"uint32_t":           "int",
+	"int64_t":            "int",
+	"uint64_t":           "int",
+}
+
+// TypeMappings returns a copy of the built-in C type to ctypes mappings
+func TypeMappings() map[string]string {
+	return maps.Clone(typeMappings)
+}
+
+// PythonTypeHints returns a copy of the built-in C type to Python type hint mappings
+func PythonTypeHints() map[string]string {
+	return maps.Clone(pythonTypeHints)
+}
+
+// WriteTypeTable writes the built-in type mappings, see TypeRegistry.WriteTable
+func WriteTypeTable(w io.Writer) error {
+	return DefaultTypeRegistry().WriteTable(w)
+}
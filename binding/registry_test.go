@@ -0,0 +1,96 @@
+package binding
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestWriteTypeTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTypeTable(&buf); err != nil {
+		t.Fatalf("WriteTypeTable() error = %v", err)
+	}
+
+	for _, expected := range []string{"int -> ctypes.c_int (int)", "const char* -> ctypes.c_char_p (str)"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Type table missing expected line: %s", expected)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(TypeMappings()) {
+		t.Errorf("Expected %d lines, got %d", len(TypeMappings()), len(lines))
+	}
+}
+
+func TestTypeRegistry(t *testing.T) {
+	registry := DefaultTypeRegistry()
+	if !registry.Has("int") {
+		t.Error("Expected default registry to map int")
+	}
+	if registry.Has("myfloat") {
+		t.Error("Expected myfloat to be unmapped before registration")
+	}
+
+	registry.Register("myfloat", "ctypes.c_float", "float")
+	ctypesExpr, hint, ok := registry.Lookup("myfloat")
+	if !ok || ctypesExpr != "ctypes.c_float" || hint != "float" {
+		t.Errorf("Lookup(myfloat) = %q, %q, %v", ctypesExpr, hint, ok)
+	}
+
+	registry.Register("handle_t", "ctypes.c_void_p", "")
+	if _, hint, _ := registry.Lookup("handle_t"); hint != "Any" {
+		t.Errorf("Expected empty hint to default to Any, got %q", hint)
+	}
+
+	// Registering on one registry must not leak into the defaults
+	if DefaultTypeRegistry().Has("myfloat") {
+		t.Error("Register() modified the built-in mappings")
+	}
+}
+
+func TestGenerateCustomTypeMapping(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		TypeMappings: []config.TypeMapping{
+			{CType: "myfloat", Ctypes: "ctypes.c_float", PythonHint: "float"},
+		},
+		Functions: []config.FunctionConfig{
+			{
+				Name:       "scale",
+				Parameters: []config.Param{{Name: "v", Type: "myfloat"}},
+				ReturnType: "myfloat",
+			},
+		},
+	}
+
+	result, err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
+	if err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if result.FunctionsBound != 1 {
+		t.Errorf("FunctionsBound = %d, want 1", result.FunctionsBound)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expectedStrings := []string{
+		"'myfloat': ctypes.c_float",
+		"_lib.scale.argtypes = [TYPE_MAPPING[\"myfloat\"]]",
+		"def scale(v: float) -> float:",
+	}
+	for _, expected := range expectedStrings {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated file missing expected content: %s", expected)
+		}
+	}
+}
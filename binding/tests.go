@@ -0,0 +1,82 @@
+package binding
+
+import (
+	"io"
+	"strings"
+	"text/template"
+
+	"cp2p/config"
+)
+
+// smokeArguments are the arguments generated smoke tests pass for each
+// numeric Python hint. One rather than zero, so a call does not divide by zero.
+var smokeArguments = map[string]string{
+	"int":   "1",
+	"float": "1.0",
+	"bool":  "True",
+}
+
+// smokeTest is a bound function as rendered by the test template
+type smokeTest struct {
+	Name string
+	Call string // Arguments of a trivial call
+	Safe bool   // Whether the function takes and returns only numbers, so Call is made
+}
+
+// writeTests writes a pytest module checking that every bound function is
+// exposed by the module, and calling those with purely numeric signatures
+func (g *PythonGenerator) writeTests(w io.Writer, functions []config.FunctionConfig) error {
+	var tests []smokeTest
+	for _, fn := range functions {
+		args, safe := g.smokeCall(fn)
+		tests = append(tests, smokeTest{Name: fn.PyName(), Call: args, Safe: safe})
+	}
+	data := struct {
+		ModuleName string
+		Tests      []smokeTest
+	}{g.moduleName, tests}
+	return testModuleTemplate.Execute(w, data)
+}
+
+// smokeCall returns the arguments of a trivial call to fn, and whether fn
+// takes and returns only numbers, so that such a call is meaningful.
+// Parameters with a default are left to it.
+func (g *PythonGenerator) smokeCall(fn config.FunctionConfig) (string, bool) {
+	if fn.ReturnType != "void" {
+		if _, hint, _ := g.registry.Lookup(fn.ReturnType); smokeArguments[hint] == "" {
+			return "", false
+		}
+	}
+	var args []string
+	for _, p := range fn.Inputs() {
+		_, hint, _ := g.registry.Lookup(p.ValueType())
+		value := smokeArguments[hint]
+		if value == "" {
+			return "", false
+		}
+		if p.Default == "" {
+			args = append(args, value)
+		}
+	}
+	return strings.Join(args, ", "), true
+}
+
+var testModuleTemplate = template.Must(template.New("tests").Parse(testsTemplate))
+
+// testsTemplate is the template for the pytest smoke test module
+const testsTemplate = `# Generated by cp2p. Smoke tests for the {{.ModuleName}} bindings; run with pytest.
+import os
+import sys
+
+sys.path.insert(0, os.path.dirname(os.path.abspath(__file__)))
+
+import {{.ModuleName}}
+{{range .Tests}}
+
+def test_{{.Name}}_is_callable():
+    assert callable({{$.ModuleName}}.{{.Name}})
+{{if .Safe}}
+
+def test_{{.Name}}_call():
+    {{$.ModuleName}}.{{.Name}}({{.Call}})
+{{end}}{{end}}`
@@ -0,0 +1,95 @@
+package binding
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestGenerateTests(t *testing.T) {
+	tmpDir := t.TempDir()
+	libPath := filepath.Join(tmpDir, "libmathlib.so")
+	testConfig := &config.Config{
+		GenerateTests: true,
+		Functions: []config.FunctionConfig{
+			{Name: "add", ReturnType: "int", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int", Default: "2"}}},
+			{Name: "greet", ReturnType: "const char*", Parameters: []config.Param{{Name: "name", Type: "const char*"}}},
+			{Name: "reset", ReturnType: "void"},
+		},
+	}
+
+	result, err := GenerateBindings("mathlib", libPath, tmpDir, testConfig)
+	if err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	testsPath := filepath.Join(tmpDir, "test_mathlib.py")
+	if !slices.Contains(result.FilesWritten, testsPath) {
+		t.Errorf("FilesWritten = %v, want %s", result.FilesWritten, testsPath)
+	}
+
+	content, err := os.ReadFile(testsPath)
+	if err != nil {
+		t.Fatalf("Failed to read tests: %v", err)
+	}
+	for _, expected := range []string{
+		"import mathlib",
+		"assert callable(mathlib.add)",
+		"assert callable(mathlib.greet)",
+		"assert callable(mathlib.reset)",
+		"mathlib.add(1)",
+		"mathlib.reset()",
+	} {
+		if !strings.Contains(string(content), expected) {
+			t.Errorf("Generated tests missing expected content: %s\n%s", expected, content)
+		}
+	}
+	if strings.Contains(string(content), "mathlib.greet(") {
+		t.Errorf("Generated tests call greet, which takes a string:\n%s", content)
+	}
+
+	testConfig.GenerateTests = false
+	if _, err := GenerateBindings("plain", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "test_plain.py")); !os.IsNotExist(err) {
+		t.Errorf("Tests written without GenerateTests: %v", err)
+	}
+}
+
+func TestGeneratedTestsPass(t *testing.T) {
+	python, err := FindPython()
+	if err != nil {
+		t.Skipf("Skipping generated tests: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	libPath := buildLibrary(t, tmpDir, "mathlib.cpp",
+		"extern \"C\" int add(int a, int b) { return a + b; }\n"+
+			"extern \"C\" double half(double x) { return x / 2; }\n")
+	testConfig := &config.Config{
+		GenerateTests: true,
+		Functions: []config.FunctionConfig{
+			{Name: "add", ReturnType: "int", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}},
+			{Name: "half", ReturnType: "double", Parameters: []config.Param{{Name: "x", Type: "double"}}},
+		},
+	}
+	if _, err := GenerateBindings("mathlib", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	// Run the test functions without requiring pytest
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import test_mathlib\n" +
+		"tests = [f for name, f in vars(test_mathlib).items() if name.startswith('test_')]\n" +
+		"assert len(tests) == 4, tests\n" +
+		"for test in tests: test()\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Generated tests failed: %v\n%s", err, output)
+	}
+}
@@ -0,0 +1,268 @@
+package binding
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cp2p/config"
+)
+
+// NormalizeType canonicalizes the spelling of a C type so equivalent spellings
+// share one mapping key: whitespace is collapsed, pointer and reference markers
+// attach to the type, cv-qualifiers of the base type move to the front, and
+// redundant "int"/"signed" spellings are dropped. For example "char const *",
+// "const char *" and "const  char*" all become "const char*".
+func NormalizeType(t string) string {
+	// Split the base type from its pointer/reference suffix
+	base, suffix := t, ""
+	if i := strings.IndexAny(t, "*&"); i >= 0 {
+		base, suffix = t[:i], t[i:]
+	}
+
+	var qualifiers, words []string
+	for _, word := range strings.Fields(base) {
+		if word == "const" || word == "volatile" {
+			if !slices.Contains(qualifiers, word) {
+				qualifiers = append(qualifiers, word)
+			}
+			continue
+		}
+		words = append(words, word)
+	}
+	slices.Sort(qualifiers) // "const" before "volatile"
+
+	words = normalizeIntegerWords(words)
+
+	suffix = strings.Join(strings.Fields(suffix), "")
+	suffix = strings.ReplaceAll(suffix, "*const", "* const")
+
+	return strings.Join(append(qualifiers, words...), " ") + suffix
+}
+
+// normalizeIntegerWords rewrites integer type spellings to their shortest form,
+// e.g. "long int" -> "long", "signed int" -> "int", "unsigned" -> "unsigned int"
+func normalizeIntegerWords(words []string) []string {
+	if len(words) > 1 && words[len(words)-1] == "int" && (slices.Contains(words, "short") || slices.Contains(words, "long")) {
+		words = words[:len(words)-1]
+	}
+	if len(words) > 1 && words[0] == "signed" && words[1] != "char" {
+		words = words[1:]
+	}
+	switch strings.Join(words, " ") {
+	case "signed":
+		return []string{"int"}
+	case "unsigned":
+		return []string{"unsigned", "int"}
+	}
+	return words
+}
+
+// normalizeFunction returns a copy of fn with its types normalized
+func normalizeFunction(fn config.FunctionConfig) config.FunctionConfig {
+	fn.ReturnType = NormalizeType(fn.ReturnType)
+	params := make([]config.Param, len(fn.Parameters))
+	for i, p := range fn.Parameters {
+		p.Type = NormalizeType(p.Type)
+		params[i] = p
+	}
+	fn.Parameters = params
+	return fn
+}
+
+// normalizeTypeConfig returns a copy of typ with its field and base types normalized
+func normalizeTypeConfig(typ config.TypeConfig) config.TypeConfig {
+	if typ.BaseType != "" {
+		typ.BaseType = NormalizeType(typ.BaseType)
+	}
+	fields := make([]config.Field, len(typ.Fields))
+	for i, f := range typ.Fields {
+		f.Type = NormalizeType(f.Type)
+		fields[i] = f
+	}
+	typ.Fields = fields
+	return typ
+}
+
+// isFieldKind reports whether types of kind can be used as struct/union fields
+func isFieldKind(kind string) bool {
+	return kind == "struct" || kind == "union" || kind == "enum"
+}
+
+// validateTypes checks that every enum base type and every struct/union field
+// type resolves to either a mapped C type or a declared type
+func (g *PythonGenerator) validateTypes() error {
+	declared := declaredTypes(g.types)
+
+	for _, typ := range g.types {
+		if typ.Kind == "enum" && typ.BaseType != "" {
+			if !g.registry.Has(typ.BaseType) {
+				return fmt.Errorf("enum %s has unmapped base type %s", typ.Name, typ.BaseType)
+			}
+		}
+		if typ.Kind == "enum" {
+			if _, err := enumMembers(typ.Values); err != nil {
+				return fmt.Errorf("enum %s: %w", typ.Name, err)
+			}
+		}
+
+		for _, field := range typ.Fields {
+			if g.registry.Has(field.Type) || declared[field.Type] {
+				continue
+			}
+			return fmt.Errorf("field %s of %s %s has unknown type %s", field.Name, typ.Kind, typ.Name, field.Type)
+		}
+	}
+
+	return nil
+}
+
+// enumMember is an enum value with the Python expression it is assigned
+type enumMember struct {
+	Name  string
+	Value string
+}
+
+// enumMembers parses enum values, each a name or "NAME = value" as in C. A
+// value without an initializer is one more than the one before it, the first
+// being 0. Integer initializers are written in decimal, since Python rejects
+// C's octal literals; others, such as "1 << 3" or "READ | WRITE", are kept.
+func enumMembers(values []string) ([]enumMember, error) {
+	members := make([]enumMember, 0, len(values))
+	expr, offset := "", int64(-1) // The last initializer that is not an integer, plus offset
+	for _, v := range values {
+		name, init, explicit := strings.Cut(v, "=")
+		name, init = strings.TrimSpace(name), strings.TrimSpace(init)
+		if !identifierRe.MatchString(name) || isPythonKeyword(name) || (explicit && init == "") {
+			return nil, fmt.Errorf("invalid value %q", v)
+		}
+
+		switch n, err := strconv.ParseInt(init, 0, 64); {
+		case !explicit:
+			offset++
+		case err == nil:
+			expr, offset = "", n
+		default:
+			expr, offset = init, 0
+		}
+
+		value := strconv.FormatInt(offset, 10)
+		if expr != "" {
+			value = expr
+			if offset != 0 {
+				value = fmt.Sprintf("(%s) + %d", expr, offset)
+			}
+		}
+		members = append(members, enumMember{Name: name, Value: value})
+	}
+	return members, nil
+}
+
+// KnowsType reports whether the generated bindings can use C type t: it has a
+// mapping or names a declared struct, class or union
+func (g *PythonGenerator) KnowsType(t string) bool {
+	t = NormalizeType(t)
+	return g.registry.Has(t) || declaredTypes(g.types)[t]
+}
+
+// declaredTypes returns the names of the declared types usable as fields
+func declaredTypes(types []config.TypeConfig) map[string]bool {
+	declared := make(map[string]bool)
+	for _, typ := range types {
+		if isFieldKind(typ.Kind) {
+			declared[typ.Name] = true
+		}
+	}
+	return declared
+}
+
+// returnClasses returns the names of the declared structs and unions, which
+// functions may return by value as instances of the generated class
+func returnClasses(types []config.TypeConfig) map[string]bool {
+	classes := make(map[string]bool)
+	for _, typ := range types {
+		if typ.Kind == "struct" || typ.Kind == "union" {
+			classes[typ.Name] = true
+		}
+	}
+	return classes
+}
+
+// orderTypes returns types sorted so that every type comes after the declared
+// types its fields reference, keeping declaration order otherwise. Fields hold
+// their types by value, so a type that contains itself, directly or through
+// other types, has no layout and is reported as an error.
+func orderTypes(types []config.TypeConfig) ([]config.TypeConfig, error) {
+	byName := make(map[string]config.TypeConfig)
+	for _, typ := range types {
+		byName[typ.Name] = typ
+	}
+
+	ordered := make([]config.TypeConfig, 0, len(types))
+	visited := make(map[string]bool)
+	var path []string // Types being visited, outermost first
+
+	var visit func(typ config.TypeConfig) error
+	visit = func(typ config.TypeConfig) error {
+		if i := slices.Index(path, typ.Name); i >= 0 {
+			cycle := append(slices.Clone(path[i:]), typ.Name)
+			return fmt.Errorf("types contain each other by value: %s", strings.Join(cycle, " -> "))
+		}
+		if visited[typ.Name] {
+			return nil
+		}
+		visited[typ.Name] = true
+
+		path = append(path, typ.Name)
+		for _, field := range typ.Fields {
+			if dep, ok := byName[field.Type]; ok && isFieldKind(dep.Kind) {
+				if err := visit(dep); err != nil {
+					return err
+				}
+			}
+		}
+		path = path[:len(path)-1]
+
+		ordered = append(ordered, typ)
+		return nil
+	}
+
+	for _, typ := range types {
+		if err := visit(typ); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// registerStructPointers maps pointers to declared structs and unions to
+// ctypes pointers to their classes, so functions can take them, e.g.
+// void init(Config* cfg). Wrappers accept a class instance and pass it by
+// reference, letting the function modify it. cffi modules declare structs in
+// their cdef instead, so wrappers pass the cdata pointer, e.g. from
+// ffi.new("Config *"), to the library as is.
+func (g *PythonGenerator) registerStructPointers() {
+	for name := range returnClasses(g.config.Types) {
+		for _, cType := range []string{name + "*", "const " + name + "*"} {
+			if g.config.OutputBackend == BackendCFFI {
+				g.registry.Register(cType, "ctypes.POINTER("+name+")", "")
+				continue
+			}
+			g.registry.Register(cType, "ctypes.POINTER("+name+")", name)
+			g.structPtrs[cType] = name
+		}
+	}
+}
+
+// typeMappings returns the ctypes mappings emitted in the module's
+// TYPE_MAPPING literal. Struct pointers are added after their classes.
+func (g *PythonGenerator) typeMappings() map[string]string {
+	mappings := make(map[string]string, len(g.registry.ctypes))
+	for cType, expr := range g.registry.ctypes {
+		if g.structPtrs[cType] == "" {
+			mappings[cType] = expr
+		}
+	}
+	return mappings
+}
@@ -0,0 +1,194 @@
+package binding
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestNormalizeType(t *testing.T) {
+	tests := []struct {
+		inputs []string
+		want   string
+	}{
+		{inputs: []string{"const char*", "const char *", "char const*", "char  const *", " const\tchar* "}, want: "const char*"},
+		{inputs: []string{"int", " int "}, want: "int"},
+		{inputs: []string{"int*", "int *", "int  *"}, want: "int*"},
+		{inputs: []string{"int**", "int * *"}, want: "int**"},
+		{inputs: []string{"unsigned long", "unsigned long int", "unsigned  long"}, want: "unsigned long"},
+		{inputs: []string{"long long", "long long int", "signed long long"}, want: "long long"},
+		{inputs: []string{"unsigned", "unsigned int"}, want: "unsigned int"},
+		{inputs: []string{"signed", "signed int", "int"}, want: "int"},
+		{inputs: []string{"short", "short int", "signed short"}, want: "short"},
+		{inputs: []string{"signed char"}, want: "signed char"},
+		{inputs: []string{"volatile const int", "const volatile int", "int const volatile"}, want: "const volatile int"},
+		{inputs: []string{"char * const", "char *const"}, want: "char* const"},
+	}
+
+	for _, tt := range tests {
+		for _, input := range tt.inputs {
+			if got := NormalizeType(input); got != tt.want {
+				t.Errorf("NormalizeType(%q) = %q, want %q", input, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestGenerateNormalizesTypeSpellings(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name:       "greet",
+				Parameters: []config.Param{{Name: "name", Type: "char const *"}, {Name: "times", Type: "unsigned long int"}},
+				ReturnType: "const  char *",
+			},
+		},
+	}
+
+	result, err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
+	if err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if result.FunctionsBound != 1 {
+		t.Fatalf("FunctionsBound = %d, want 1 (skipped %v)", result.FunctionsBound, result.SkippedFunctions)
+	}
+
+	content, err := os.ReadFile(filepath.Join(tmpDir, "test.py"))
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	expected := `_lib.greet.argtypes = [TYPE_MAPPING["const char*"], TYPE_MAPPING["unsigned long"]]`
+	if !strings.Contains(string(content), expected) {
+		t.Errorf("Generated file missing expected content: %s", expected)
+	}
+	if !strings.Contains(string(content), "def greet(name: str, times: int) -> str:") {
+		t.Error("Generated file missing normalized signature")
+	}
+}
+
+func TestOrderTypes(t *testing.T) {
+	field := func(types ...string) []config.Field {
+		var fields []config.Field
+		for i, typ := range types {
+			fields = append(fields, config.Field{Name: "f" + string(rune('a'+i)), Type: typ})
+		}
+		return fields
+	}
+
+	// Each type is declared before the types it contains
+	types := []config.TypeConfig{
+		{Name: "Scene", Kind: "struct", Fields: field("Shape", "Color")},
+		{Name: "Shape", Kind: "union", Fields: field("Circle", "Rect")},
+		{Name: "Rect", Kind: "struct", Fields: field("Point", "Point")},
+		{Name: "Circle", Kind: "struct", Fields: field("Point", "double")},
+		{Name: "Point", Kind: "struct", Fields: field("double", "double")},
+		{Name: "Color", Kind: "enum", Values: []string{"RED"}},
+	}
+	ordered, err := orderTypes(types)
+	if err != nil {
+		t.Fatalf("orderTypes() error = %v", err)
+	}
+	var names []string
+	for _, typ := range ordered {
+		names = append(names, typ.Name)
+	}
+	if want := "Point Circle Rect Shape Color Scene"; strings.Join(names, " ") != want {
+		t.Errorf("orderTypes() = %v, want %s", names, want)
+	}
+
+	cycles := map[string][]config.TypeConfig{
+		"A -> B -> C -> A": {
+			{Name: "A", Kind: "struct", Fields: field("B")},
+			{Name: "B", Kind: "struct", Fields: field("int", "C")},
+			{Name: "C", Kind: "union", Fields: field("A")},
+		},
+		"Node -> Node": {
+			{Name: "Node", Kind: "struct", Fields: field("int", "Node")},
+		},
+	}
+	for want, types := range cycles {
+		_, err := orderTypes(types)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("orderTypes() error = %v, want cycle %s", err, want)
+		}
+	}
+}
+
+func TestGenerateTypeCycle(t *testing.T) {
+	testConfig := &config.Config{
+		Types: []config.TypeConfig{
+			{Name: "Parent", Kind: "struct", Fields: []config.Field{{Name: "child", Type: "Child"}}},
+			{Name: "Child", Kind: "struct", Fields: []config.Field{{Name: "parent", Type: "Parent"}}},
+		},
+	}
+	tmpDir := t.TempDir()
+	_, err := GenerateBindings("test", "test.dll", tmpDir, testConfig)
+	if err == nil || !strings.Contains(err.Error(), "Parent -> Child -> Parent") {
+		t.Errorf("GenerateBindings() error = %v, want the Parent/Child cycle", err)
+	}
+	if _, statErr := os.Stat(filepath.Join(tmpDir, "test.py")); statErr == nil {
+		t.Error("Expected no module to be written for a type cycle")
+	}
+}
+
+func TestGenerateStructPointer(t *testing.T) {
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{Name: "init", Parameters: []config.Param{{Name: "cfg", Type: "Config *"}}, ReturnType: "void"},
+			{Name: "area", Parameters: []config.Param{{Name: "cfg", Type: "const Config*"}}, ReturnType: "double"},
+		},
+		Types: []config.TypeConfig{
+			{Name: "Config", Kind: "struct", Fields: []config.Field{{Name: "width", Type: "int"}, {Name: "scale", Type: "double"}}},
+		},
+	}
+
+	var module strings.Builder
+	gen := NewGenerator("settings", "libsettings.so", "", testConfig)
+	result := &GenerationResult{}
+	if err := gen.writeModule(&module, result); err != nil {
+		t.Fatalf("writeModule() error = %v", err)
+	}
+	if result.FunctionsBound != 2 {
+		t.Errorf("FunctionsBound = %d, want 2 (skipped %v)", result.FunctionsBound, result.SkippedFunctions)
+	}
+	for _, expected := range []string{
+		`TYPE_MAPPING["Config*"] = ctypes.POINTER(Config)`,
+		"def init(cfg: Config) -> None:",
+		"None if cfg is None else ctypes.byref(cfg)",
+	} {
+		if !strings.Contains(module.String(), expected) {
+			t.Errorf("Generated module missing %q", expected)
+		}
+	}
+
+	python, err := FindPython()
+	if err != nil {
+		return
+	}
+	tmpDir := t.TempDir()
+	libPath := buildLibrary(t, tmpDir, "settings.cpp", `struct Config { int width; double scale; };
+extern "C" void init(Config* cfg) { cfg->width = 640; cfg->scale = 1.5; }
+extern "C" double area(const Config* cfg) { return cfg ? cfg->width * cfg->scale : -1; }
+`)
+	if _, err := GenerateBindings("settings", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	script := "import sys; sys.path.insert(0, sys.argv[1]); import settings\n" +
+		"cfg = settings.Config()\n" +
+		"settings.init(cfg)\n" +
+		"assert (cfg.width, cfg.scale) == (640, 1.5), cfg\n" +
+		"assert settings.area(cfg) == 960.0\n" +
+		"assert settings.area(None) == -1\n"
+	cmd := exec.CommandContext(context.Background(), python, "-c", script, tmpDir)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("Struct pointer script failed: %v\n%s", err, output)
+	}
+}
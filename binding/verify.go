@@ -0,0 +1,90 @@
+package binding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// ErrPythonNotFound is returned by Verify when no Python interpreter is on PATH
+var ErrPythonNotFound = errors.New("no Python interpreter found")
+
+// verifyScript imports the module named by argv[2] with argv[1] on sys.path.
+// Importing runs every argtypes/restype assignment, so a missing symbol fails here.
+const verifyScript = "import sys, importlib; sys.path.insert(0, sys.argv[1]); importlib.import_module(sys.argv[2])"
+
+// FindPython returns the path of the first Python interpreter found on PATH
+func FindPython() (string, error) {
+	for _, name := range []string{"python3", "python", "py"} {
+		if path, err := exec.LookPath(name); err == nil {
+			return path, nil
+		}
+	}
+	return "", ErrPythonNotFound
+}
+
+// Verify imports the generated module in a Python subprocess to confirm that it
+// loads its library and that every bound function resolves
+func Verify(outputDir, moduleName string) error {
+	python, err := FindPython()
+	if err != nil {
+		return err
+	}
+
+	absDir, err := filepath.Abs(outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve output directory: %v", err)
+	}
+
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, python, "-c", verifyScript, absDir, moduleName)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("module %s failed to load: %v\n%s", moduleName, err, strings.TrimSpace(string(output)))
+	}
+
+	return nil
+}
+
+// GeneratedModules returns the names of the Python modules generated in
+// outputDir. They are taken from its ManifestFile, leaving out the user's own
+// scripts there and the test_<module> tests generated with a module; without
+// one, every module in outputDir is returned except __init__ and test_*.
+func GeneratedModules(outputDir string) ([]string, error) {
+	var names []string
+	m, fromManifest := readManifest(outputDir)
+	fromManifest = fromManifest && m.Result != nil
+	if fromManifest {
+		names = m.Result.FilesWritten
+	} else {
+		// Read the directory rather than globbing, since outputDir may contain
+		// glob metacharacters such as [ or *
+		entries, err := os.ReadDir(outputDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+
+	var modules []string
+	for _, name := range names {
+		module, ok := strings.CutSuffix(name, ".py")
+		if !ok || module == "__init__" {
+			continue
+		}
+		if tested, isTest := strings.CutPrefix(module, "test_"); isTest && (!fromManifest || slices.Contains(names, tested+".py")) {
+			continue
+		}
+		modules = append(modules, module)
+	}
+	return modules, nil
+}
@@ -0,0 +1,187 @@
+package binding
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"cp2p/compiler"
+	"cp2p/config"
+)
+
+// buildTestLibrary compiles a trivial library exporting add into dir
+func buildTestLibrary(t *testing.T, dir string) string {
+	return buildLibrary(t, dir, "verify.cpp", `extern "C" int add(int a, int b) { return a + b; }`+"\n")
+}
+
+// buildLibrary compiles content, saved as name, into a shared library in dir
+func buildLibrary(t *testing.T, dir, name, content string) string {
+	cc, err := compiler.DetectCompiler(compiler.CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+
+	src := filepath.Join(dir, name)
+	if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	libPath, err := compiler.Compile(src, dir, cc)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	return libPath
+}
+
+func TestVerify(t *testing.T) {
+	if _, err := FindPython(); err != nil {
+		t.Skipf("Skipping verification test: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	libPath := buildTestLibrary(t, tmpDir)
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name:       "add",
+				Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+				ReturnType: "int",
+			},
+		},
+	}
+	if _, err := GenerateBindings("verified", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if err := Verify(tmpDir, "verified"); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// A function the library does not export must fail verification
+	testConfig.Functions = append(testConfig.Functions, config.FunctionConfig{
+		Name:       "missing",
+		Parameters: []config.Param{},
+		ReturnType: "int",
+	})
+	if _, err := GenerateBindings("broken", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if err := Verify(tmpDir, "broken"); err == nil {
+		t.Error("Expected Verify() to fail for an unresolved symbol")
+	}
+}
+
+func TestVerifyWithoutPython(t *testing.T) {
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", t.TempDir())
+
+	if err := Verify(t.TempDir(), "anything"); !errors.Is(err, ErrPythonNotFound) {
+		t.Errorf("Verify() error = %v, want ErrPythonNotFound", err)
+	}
+}
+
+func TestVerifyPathWithSpaces(t *testing.T) {
+	if _, err := FindPython(); err != nil {
+		t.Skipf("Skipping verification test: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "my project [ünï]")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	libPath := buildTestLibrary(t, dir)
+
+	testConfig := &config.Config{
+		Functions: []config.FunctionConfig{
+			{
+				Name:       "add",
+				Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+				ReturnType: "int",
+			},
+		},
+	}
+	if _, err := GenerateBindings("spaced", libPath, dir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+
+	modules, err := GeneratedModules(dir)
+	if err != nil || !slices.Contains(modules, "spaced") {
+		t.Fatalf("GeneratedModules() = %v, %v; want spaced", modules, err)
+	}
+	if err := Verify(dir, "spaced"); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestGeneratedModules(t *testing.T) {
+	dir := t.TempDir()
+	testConfig := &config.Config{
+		GenerateTests: true,
+		Functions:     []config.FunctionConfig{{Name: "add", Parameters: []config.Param{{Name: "a", Type: "int"}}, ReturnType: "int"}},
+	}
+	if _, err := GenerateBindings("calc", "calc.dll", dir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	for _, name := range []string{"helper.py", "test_helper.py"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("import sys\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	// The generated tests and the user's scripts are not generated modules
+	modules, err := GeneratedModules(dir)
+	if err != nil || !slices.Equal(modules, []string{"calc"}) {
+		t.Errorf("GeneratedModules() = %v, %v; want [calc]", modules, err)
+	}
+
+	// Without a manifest, only test modules are told apart
+	if err := os.Remove(filepath.Join(dir, ManifestFile)); err != nil {
+		t.Fatalf("Failed to remove manifest: %v", err)
+	}
+	modules, err = GeneratedModules(dir)
+	if err != nil || !slices.Equal(modules, []string{"calc", "helper"}) {
+		t.Errorf("GeneratedModules() without a manifest = %v, %v; want [calc helper]", modules, err)
+	}
+}
+
+func TestVerifyChecksumMismatch(t *testing.T) {
+	if _, err := FindPython(); err != nil {
+		t.Skipf("Skipping verification test: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	libPath := buildTestLibrary(t, tmpDir)
+
+	testConfig := &config.Config{
+		VerifyChecksum: true,
+		Functions: []config.FunctionConfig{
+			{
+				Name:       "add",
+				Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+				ReturnType: "int",
+			},
+		},
+	}
+	if _, err := GenerateBindings("checked", libPath, tmpDir, testConfig); err != nil {
+		t.Fatalf("GenerateBindings() error = %v", err)
+	}
+	if err := Verify(tmpDir, "checked"); err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	// Appending to the library changes its hash without breaking it
+	file, err := os.OpenFile(libPath, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("Failed to open library: %v", err)
+	}
+	file.Write([]byte{0})
+	file.Close()
+
+	if err := Verify(tmpDir, "checked"); err == nil || !strings.Contains(err.Error(), "Checksum mismatch") {
+		t.Errorf("Verify() error = %v, want a checksum mismatch", err)
+	}
+}
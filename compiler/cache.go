@@ -0,0 +1,87 @@
+package compiler
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"cp2p/util"
+)
+
+// detectionCacheFile is the file in util.GetCacheDir holding cached detection
+// results
+const detectionCacheFile = "compilers.json"
+
+// cachedCompiler is what the detection cache records for a compiler binary.
+// An entry is only used while the binary's size and modification time match,
+// so upgrading the compiler invalidates it.
+type cachedCompiler struct {
+	Size         int64     `json:"size"`
+	ModTime      time.Time `json:"mod_time"`
+	IncludePaths []string  `json:"include_paths"`
+}
+
+// detectionCacheMu serializes updates from goroutines of this process. Other
+// processes may update the file at the same time; writes replace it with an
+// atomic rename, so readers see either version whole and at worst one
+// update is lost.
+var detectionCacheMu sync.Mutex
+
+// detectionCachePath returns the path of the detection cache file
+func detectionCachePath() string {
+	return filepath.Join(util.GetCacheDir(), detectionCacheFile)
+}
+
+// readDetectionCache returns the cached entries by compiler path. A missing,
+// unreadable or corrupt cache is treated as empty.
+func readDetectionCache() map[string]cachedCompiler {
+	entries := make(map[string]cachedCompiler)
+	data, err := os.ReadFile(detectionCachePath())
+	if err != nil {
+		return entries
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return make(map[string]cachedCompiler)
+	}
+	return entries
+}
+
+// cachedIncludePaths returns the include paths cached for the compiler at
+// path, if they were recorded for the binary as it is now
+func cachedIncludePaths(path string) ([]string, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false
+	}
+	entry, ok := readDetectionCache()[path]
+	if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) {
+		return nil, false
+	}
+	return entry.IncludePaths, true
+}
+
+// cacheIncludePaths records the include paths of the compiler at path. The
+// cache only saves work, so failing to write it is not an error.
+func cacheIncludePaths(path string, includePaths []string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	detectionCacheMu.Lock()
+	defer detectionCacheMu.Unlock()
+
+	entries := readDetectionCache()
+	entries[path] = cachedCompiler{Size: info.Size(), ModTime: info.ModTime(), IncludePaths: includePaths}
+
+	cachePath := detectionCachePath()
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return
+	}
+	util.WriteFileAtomic(cachePath, 0644, func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(entries)
+	})
+}
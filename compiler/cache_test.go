@@ -0,0 +1,109 @@
+package compiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+
+	"cp2p/util"
+)
+
+func TestDetectionCacheConcurrentAccess(t *testing.T) {
+	origCache := os.Getenv(util.CacheDirEnv)
+	defer os.Setenv(util.CacheDirEnv, origCache)
+	os.Setenv(util.CacheDirEnv, t.TempDir())
+
+	binDir := t.TempDir()
+	const writers = 16
+	paths := make([]string, writers)
+	for i := range paths {
+		paths[i] = filepath.Join(binDir, fmt.Sprintf("g++-%d", i))
+		if err := os.WriteFile(paths[i], []byte(paths[i]), 0755); err != nil {
+			t.Fatalf("Failed to create compiler binary: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*2)
+	for i, path := range paths {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cacheIncludePaths(path, []string{fmt.Sprintf("/include/%d", i)})
+		}()
+		// Readers run alongside the writers and must never see a partial file
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				data, err := os.ReadFile(detectionCachePath())
+				if err != nil {
+					continue
+				}
+				var entries map[string]cachedCompiler
+				if err := json.Unmarshal(data, &entries); err != nil {
+					errs <- fmt.Errorf("corrupt cache read: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	for i, path := range paths {
+		got, ok := cachedIncludePaths(path)
+		if want := []string{fmt.Sprintf("/include/%d", i)}; !ok || !slices.Equal(got, want) {
+			t.Errorf("cachedIncludePaths(%s) = %v, %v, want %v", path, got, ok, want)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(detectionCachePath()))
+	if err != nil {
+		t.Fatalf("Failed to read cache directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the cache file, found %d entries", len(entries))
+	}
+}
+
+func TestDetectionCacheInvalidation(t *testing.T) {
+	origCache := os.Getenv(util.CacheDirEnv)
+	defer os.Setenv(util.CacheDirEnv, origCache)
+	os.Setenv(util.CacheDirEnv, t.TempDir())
+
+	path := filepath.Join(t.TempDir(), "g++")
+	if err := os.WriteFile(path, []byte("v1"), 0755); err != nil {
+		t.Fatalf("Failed to create compiler binary: %v", err)
+	}
+	cacheIncludePaths(path, []string{"/include/v1"})
+	if _, ok := cachedIncludePaths(path); !ok {
+		t.Fatal("cachedIncludePaths() missed a freshly cached compiler")
+	}
+
+	// An upgraded binary no longer matches its entry
+	if err := os.WriteFile(path, []byte("version 2"), 0755); err != nil {
+		t.Fatalf("Failed to update compiler binary: %v", err)
+	}
+	if paths, ok := cachedIncludePaths(path); ok {
+		t.Errorf("cachedIncludePaths() = %v for a changed binary, want a miss", paths)
+	}
+
+	// A corrupt cache is treated as empty and replaced on the next write
+	if err := os.WriteFile(detectionCachePath(), []byte("{"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt cache: %v", err)
+	}
+	if _, ok := cachedIncludePaths(path); ok {
+		t.Error("cachedIncludePaths() hit in a corrupt cache")
+	}
+	cacheIncludePaths(path, []string{"/include/v2"})
+	if paths, ok := cachedIncludePaths(path); !ok || !slices.Equal(paths, []string{"/include/v2"}) {
+		t.Errorf("cachedIncludePaths() = %v, %v after rewriting a corrupt cache", paths, ok)
+	}
+}
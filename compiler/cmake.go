@@ -0,0 +1,136 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"cp2p/util"
+)
+
+// cmakeBuildDir is the directory under the output directory CMake builds in
+const cmakeBuildDir = "cmake-build"
+
+// IsCMakeProject reports whether path is a CMakeLists.txt or a directory
+// containing one, and returns the project directory
+func IsCMakeProject(path string) (string, bool) {
+	if filepath.Base(path) == "CMakeLists.txt" && util.FileExists(path) {
+		return filepath.Dir(path), true
+	}
+	if util.IsDir(path) && util.FileExists(filepath.Join(path, "CMakeLists.txt")) {
+		return path, true
+	}
+	return "", false
+}
+
+// BuildCMake configures and builds the CMake project in projectDir, then
+// copies the shared library it produces into outputDir and returns its path.
+// CMake picks the compiler unless compiler is non-nil. The build directory is
+// removed afterwards unless opts.KeepIntermediates is set.
+func BuildCMake(projectDir, outputDir string, compiler *CompilerInfo, opts *CompileOptions) (string, error) {
+	cmake, err := lookPathAbs("cmake")
+	if err != nil {
+		return "", fmt.Errorf("%w: cmake", ErrCMakeNotFound)
+	}
+	if err := util.EnsureWritableDir(outputDir); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	buildDir := filepath.Join(outputDir, cmakeBuildDir)
+	if !opts.KeepIntermediates {
+		defer os.RemoveAll(buildDir)
+	}
+	for _, args := range cmakeCommands(projectDir, buildDir, compiler, opts) {
+		ctx := context.Background()
+		cmd := exec.CommandContext(ctx, cmake, args...)
+		var stderr bytes.Buffer
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
+		if err := cmd.Run(); err != nil {
+			return "", &CompileError{Err: err, Stderr: stderr.String()}
+		}
+	}
+
+	built, err := findSharedLibrary(buildDir)
+	if err != nil {
+		return "", err
+	}
+	libPath := filepath.Join(outputDir, filepath.Base(built))
+	err = util.WriteFileAtomic(libPath, 0755, func(w io.Writer) error {
+		f, err := os.Open(built)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to copy %s: %v", built, err)
+	}
+	return libPath, nil
+}
+
+// CMakeCommandString returns the cmake command lines BuildCMake would run,
+// one per line, without running them
+func CMakeCommandString(projectDir, outputDir string, compiler *CompilerInfo, opts *CompileOptions) string {
+	var lines []string
+	for _, args := range cmakeCommands(projectDir, filepath.Join(outputDir, cmakeBuildDir), compiler, opts) {
+		lines = append(lines, "cmake "+strings.Join(args, " "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cmakeCommands returns the arguments of the configure and build steps
+func cmakeCommands(projectDir, buildDir string, compiler *CompilerInfo, opts *CompileOptions) [][]string {
+	buildType := "Release"
+	if opts.Debug {
+		buildType = "Debug"
+	}
+
+	configure := []string{"-S", projectDir, "-B", buildDir, "-DCMAKE_BUILD_TYPE=" + buildType}
+	if compiler != nil && compiler.EnvSetup == nil {
+		configure = append(configure, "-DCMAKE_CXX_COMPILER="+compiler.Path)
+	}
+	build := []string{"--build", buildDir, "--config", buildType}
+	if opts.Jobs > 0 {
+		build = append(build, "--parallel", fmt.Sprint(opts.Jobs))
+	}
+	return [][]string{configure, build}
+}
+
+// findSharedLibrary returns the one shared library under buildDir, skipping
+// CMake's own files
+func findSharedLibrary(buildDir string) (string, error) {
+	var found []string
+	err := filepath.WalkDir(buildDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "CMakeFiles" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && filepath.Ext(path) == util.GetLibraryExtension() {
+			found = append(found, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search CMake build directory: %v", err)
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("CMake build produced no %s library; add a SHARED library target", util.GetLibraryExtension())
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("CMake build produced several libraries, expected one: %s", strings.Join(found, ", "))
+	}
+}
@@ -0,0 +1,127 @@
+package compiler
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cp2p/util"
+)
+
+// writeCMakeProject writes a CMake project building one shared library
+func writeCMakeProject(t *testing.T, dir string) {
+	files := map[string]string{
+		"CMakeLists.txt": "cmake_minimum_required(VERSION 3.10)\n" +
+			"project(mathlib CXX)\n" +
+			"add_library(mathlib SHARED math.cpp)\n",
+		"math.cpp": `extern "C" int add(int a, int b) { return a + b; }` + "\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestBuildCMake(t *testing.T) {
+	if _, err := exec.LookPath("cmake"); err != nil {
+		t.Skip("cmake not available")
+	}
+
+	projectDir := t.TempDir()
+	writeCMakeProject(t, projectDir)
+	outputDir := t.TempDir()
+
+	libPath, err := BuildCMake(projectDir, outputDir, nil, DefaultCompileOptions())
+	if err != nil {
+		t.Fatalf("BuildCMake() error = %v", err)
+	}
+	if filepath.Dir(libPath) != outputDir || filepath.Ext(libPath) != util.GetLibraryExtension() {
+		t.Errorf("BuildCMake() = %s, want a library in %s", libPath, outputDir)
+	}
+	if _, err := os.Stat(libPath); err != nil {
+		t.Errorf("Expected library at %s: %v", libPath, err)
+	}
+	if util.FileExists(filepath.Join(outputDir, cmakeBuildDir)) {
+		t.Error("BuildCMake() left its build directory behind")
+	}
+
+	// A broken project reports CMake's diagnostics
+	if err := os.WriteFile(filepath.Join(projectDir, "CMakeLists.txt"), []byte("not_a_command()\n"), 0644); err != nil {
+		t.Fatalf("Failed to write CMakeLists.txt: %v", err)
+	}
+	_, err = BuildCMake(projectDir, outputDir, nil, DefaultCompileOptions())
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) || !strings.Contains(compileErr.Stderr, "not_a_command") {
+		t.Errorf("BuildCMake() error = %v, want a CompileError naming the unknown command", err)
+	}
+}
+
+func TestBuildCMakeNotFound(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCMakeProject(t, projectDir)
+
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", t.TempDir())
+
+	if _, err := BuildCMake(projectDir, t.TempDir(), nil, DefaultCompileOptions()); !errors.Is(err, ErrCMakeNotFound) {
+		t.Errorf("BuildCMake() error = %v, want ErrCMakeNotFound", err)
+	}
+}
+
+func TestIsCMakeProject(t *testing.T) {
+	projectDir := t.TempDir()
+	writeCMakeProject(t, projectDir)
+
+	tests := []struct {
+		path    string
+		wantDir string
+		want    bool
+	}{
+		{projectDir, projectDir, true},
+		{filepath.Join(projectDir, "CMakeLists.txt"), projectDir, true},
+		{filepath.Join(projectDir, "math.cpp"), "", false},
+		{t.TempDir(), "", false},
+	}
+	for _, tt := range tests {
+		dir, ok := IsCMakeProject(tt.path)
+		if dir != tt.wantDir || ok != tt.want {
+			t.Errorf("IsCMakeProject(%s) = %s, %v, want %s, %v", tt.path, dir, ok, tt.wantDir, tt.want)
+		}
+	}
+}
+
+func TestFindSharedLibrary(t *testing.T) {
+	buildDir := t.TempDir()
+	ext := util.GetLibraryExtension()
+	write := func(rel string) {
+		path := filepath.Join(buildDir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", rel, err)
+		}
+	}
+
+	if _, err := findSharedLibrary(buildDir); err == nil {
+		t.Error("findSharedLibrary() with no library should fail")
+	}
+
+	// Libraries under CMakeFiles belong to CMake's own checks
+	write(filepath.Join("CMakeFiles", "probe"+ext))
+	write(filepath.Join("lib", "libmath"+ext))
+	got, err := findSharedLibrary(buildDir)
+	if err != nil || got != filepath.Join(buildDir, "lib", "libmath"+ext) {
+		t.Errorf("findSharedLibrary() = %s, %v, want lib/libmath%s", got, err, ext)
+	}
+
+	write("libother" + ext)
+	if _, err := findSharedLibrary(buildDir); err == nil {
+		t.Error("findSharedLibrary() with two libraries should fail")
+	}
+}
@@ -1,105 +1,513 @@
 package compiler
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
+
+	"cp2p/util"
+)
+
+// Warning levels accepted by CompileOptions.Warnings
+const (
+	WarningsDefault = ""      // Compiler's own default
+	WarningsAll     = "all"   // -Wall, /W4
+	WarningsExtra   = "extra" // -Wall -Wextra, /W4
+	WarningsNone    = "none"  // -w, /w
 )
 
+// MSVC C runtime libraries, for CompileOptions.RuntimeLibrary
+const (
+	RuntimeDefault     = ""    // Same as RuntimeDLL
+	RuntimeDLL         = "md"  // /MD: multithreaded DLL runtime
+	RuntimeStatic      = "mt"  // /MT: multithreaded static runtime
+	RuntimeDLLDebug    = "mdd" // /MDd: debug DLL runtime, requires Debug
+	RuntimeStaticDebug = "mtd" // /MTd: debug static runtime, requires Debug
+)
+
+// optimizationLevels maps each supported OptimizationLevel, spelled as for
+// GCC and Clang, to its closest MSVC equivalent
+var optimizationLevels = map[string]string{
+	"-O0": "/Od", // No optimization
+	"-O1": "/O1", // Minimize size
+	"-O2": "/O2", // Maximize speed
+	"-O3": "/O2", // MSVC doesn't have O3, use O2
+	"-Os": "/O1", // Minimize size
+	"-Og": "/Od", // MSVC has no debug-friendly optimization level
+}
+
+// runtimeFlags maps each runtime library to its MSVC flag
+var runtimeFlags = map[string]string{
+	RuntimeDefault:     "/MD",
+	RuntimeDLL:         "/MD",
+	RuntimeStatic:      "/MT",
+	RuntimeDLLDebug:    "/MDd",
+	RuntimeStaticDebug: "/MTd",
+}
+
 // CompileOptions contains options for the compilation process
 type CompileOptions struct {
-	OptimizationLevel string
+	OptimizationLevel string // -O0, -O1, -O2, -O3, -Os or -Og; empty uses the compiler's default
 	Debug             bool
 	IncludePaths      []string
 	LibraryPaths      []string
+	Libraries         []string // Libraries to link against, by name (e.g. "m" for libm)
+	Warnings          string   // One of the Warnings* levels
+	WarningsAsErrors  bool     // Treat warnings as errors (-Werror, /WX)
+	FailOnWarnings    bool     // Fail with ErrCompilerWarnings if the compiler reports warnings, even though it succeeded
+	HiddenVisibility  bool     // Hide all symbols not marked with ExportMacro (GCC/Clang only)
+	Fallback          bool     // Retry with other auto-detected compilers if compilation fails
+	KeepIntermediates bool     // Keep batch scripts and object files instead of removing them
+	ExportedFunctions []string // C symbols to keep in Emscripten builds; other builds export all extern "C" functions
+	ExtraFlags        []string // Passed verbatim after the structured flags, for anything not modelled above
+	LinkerFlags       []string // Passed verbatim to the link step: as given to GCC and Clang (e.g. -Wl,-soname,libfoo.so.1), after /link to MSVC (e.g. /DEF:exports.def)
+	RuntimeLibrary    string   // One of the Runtime* libraries (MSVC only)
+	Jobs              int      // Compilations CompileAll runs at once; 0 means runtime.NumCPU()
+	PrecompiledHeader string   // Header built once by PrecompileHeader and force-included in each library compiled; ignored by CompileObject, unsupported with Emscripten
 }
 
-// DefaultCompileOptions returns default compilation options
+// ExportMacro is defined by GCC/Clang builds with HiddenVisibility so sources can
+// mark the functions that should stay visible. MSVC exports nothing from a DLL by
+// default, so sources targeting it use __declspec(dllexport) instead.
+const ExportMacro = "CP2P_EXPORT"
+
+// DefaultCompileOptions returns the default compilation options: an -O2
+// release build without debug information
 func DefaultCompileOptions() *CompileOptions {
 	return &CompileOptions{
 		OptimizationLevel: "-O2",
 		Debug:             false,
 		IncludePaths:      []string{},
 		LibraryPaths:      []string{},
+		Libraries:         []string{},
 	}
 }
 
-// Compile compiles the C++ source file into a shared library
+// Validate checks the options for unknown or contradictory settings
+func (o *CompileOptions) Validate() error {
+	if _, ok := optimizationLevels[o.OptimizationLevel]; !ok && o.OptimizationLevel != "" {
+		return fmt.Errorf("unknown optimization level: %s (want one of -O0, -O1, -O2, -O3, -Os, -Og)", o.OptimizationLevel)
+	}
+
+	switch o.Warnings {
+	case WarningsDefault, WarningsAll, WarningsExtra, WarningsNone:
+	default:
+		return fmt.Errorf("unknown warning level: %s", o.Warnings)
+	}
+
+	if o.WarningsAsErrors && o.Warnings == WarningsNone {
+		return fmt.Errorf("warnings as errors cannot be combined with warning level %q", WarningsNone)
+	}
+
+	if o.Jobs < 0 {
+		return fmt.Errorf("jobs must not be negative, got %d", o.Jobs)
+	}
+
+	if _, ok := runtimeFlags[o.RuntimeLibrary]; !ok {
+		return fmt.Errorf("unknown runtime library: %s", o.RuntimeLibrary)
+	}
+	if (o.RuntimeLibrary == RuntimeDLLDebug || o.RuntimeLibrary == RuntimeStaticDebug) && !o.Debug {
+		return fmt.Errorf("debug runtime library %q requires a debug build", o.RuntimeLibrary)
+	}
+
+	// Arguments are passed to the compiler directly rather than through a
+	// shell, so only empty flags, which compilers misread as file names, are rejected
+	for i, flag := range o.ExtraFlags {
+		if strings.TrimSpace(flag) == "" {
+			return fmt.Errorf("extra flag at index %d is empty", i)
+		}
+	}
+	for i, flag := range o.LinkerFlags {
+		if strings.TrimSpace(flag) == "" {
+			return fmt.Errorf("linker flag at index %d is empty", i)
+		}
+	}
+
+	return nil
+}
+
+// Compile compiles the C++ source file into a shared library, as a
+// CompileObject step followed by a Link step. The object file is built in a
+// private temporary directory, so an object already in outputDir is neither
+// reused nor removed, and the directory is removed afterwards.
 func Compile(sourceFile, outputDir string, compiler *CompilerInfo) (string, error) {
 	opts := DefaultCompileOptions()
-	opts.IncludePaths = compiler.IncludePaths
-	return CompileWithOptions(sourceFile, outputDir, compiler, opts)
+	opts.IncludePaths = compiler.CommandIncludePaths()
+
+	objectDir, err := os.MkdirTemp("", "cp2p-obj")
+	if err != nil {
+		return "", fmt.Errorf("failed to create object directory: %v", err)
+	}
+	defer os.RemoveAll(objectDir)
+
+	objectPath, err := CompileObject(sourceFile, objectDir, compiler, opts)
+	if err != nil {
+		return "", err
+	}
+
+	return Link([]string{objectPath}, outputDir, sourceFile, compiler, opts)
+}
+
+// checkSourceFile reports a clear error if sourceFile is missing or unreadable,
+// rather than leaving it to the compiler
+func checkSourceFile(sourceFile string) error {
+	file, err := os.Open(sourceFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrSourceNotFound, sourceFile)
+	}
+	if err != nil {
+		return fmt.Errorf("source file not readable: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("source file not readable: %v", err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("source file is a directory: %s", sourceFile)
+	}
+	return nil
 }
 
 // CompileWithOptions compiles the C++ source file with custom options
 func CompileWithOptions(sourceFile, outputDir string, compiler *CompilerInfo, opts *CompileOptions) (string, error) {
-	// Ensure output directory exists
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create output directory: %v", err)
+	libPath, _, err := CompileWithFallback(sourceFile, outputDir, compiler, opts)
+	return libPath, err
+}
+
+// CompileWithFallback compiles the C++ source file and returns the compiler that
+// produced the library. If compilation fails and opts.Fallback is set, the other
+// auto-detected compilers are tried in preference order.
+func CompileWithFallback(sourceFile, outputDir string, compiler *CompilerInfo, opts *CompileOptions) (string, *CompilerInfo, error) {
+	if err := checkSourceFile(sourceFile); err != nil {
+		return "", nil, err
+	}
+
+	if err := opts.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	libPath, err := compileWith(sourceFile, outputDir, compiler, opts)
+	if err == nil || !opts.Fallback {
+		return libPath, compiler, err
+	}
+
+	for _, candidate := range DetectCompilers() {
+		if candidate.Path == compiler.Path {
+			continue
+		}
+		// Swap the failed compiler's own include paths for the candidate's
+		candidateOpts := *opts
+		candidateOpts.IncludePaths = slices.DeleteFunc(slices.Clone(opts.IncludePaths), func(path string) bool {
+			return slices.Contains(compiler.CommandIncludePaths(), path)
+		})
+		candidateOpts.IncludePaths = append(candidateOpts.IncludePaths, candidate.CommandIncludePaths()...)
+		if libPath, fallbackErr := compileWith(sourceFile, outputDir, candidate, &candidateOpts); fallbackErr == nil {
+			return libPath, candidate, nil
+		}
+	}
+
+	return "", nil, err
+}
+
+func compileWith(sourceFile, outputDir string, compiler *CompilerInfo, opts *CompileOptions) (string, error) {
+	// Ensure output directory exists and is writable
+	if err := util.EnsureWritableDir(outputDir); err != nil {
+		return "", fmt.Errorf("failed to prepare output directory: %w", err)
 	}
 
 	// Generate output library name based on OS
-	libName := generateLibraryName(sourceFile)
+	libName := generateLibraryName(sourceFile, compiler)
 	outputPath := filepath.Join(outputDir, libName)
 
 	// Build compilation command based on compiler type
-	args := buildCompileCommand(sourceFile, outputPath, compiler, opts)
+	pchArgs, err := precompiledHeaderArgs(outputDir, compiler, opts, true)
+	if err != nil {
+		return "", err
+	}
+	args := append(pchArgs, buildCompileCommand(sourceFile, outputPath, compiler, opts)...)
+
+	// Artifacts removed once compilation finishes, unless asked to keep them
+	var intermediates []string
+	if compiler.Type == CompilerMSVC {
+		intermediates = msvcIntermediates(outputPath)
+	}
+
+	err = runCompiler(compiler, outputPath, args, opts)
+	removeIntermediates(intermediates, opts)
+	if err != nil {
+		return "", err
+	}
+	return outputPath, nil
+}
+
+// runCompiler runs compiler with args for a build producing outputPath,
+// passing them through a response file if the command line is too long and
+// through a batch file if the compiler needs its environment set up first
+func runCompiler(compiler *CompilerInfo, outputPath string, args []string, opts *CompileOptions) error {
+	outputDir := filepath.Dir(outputPath)
+	outputBase := strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath))
+
+	// Move the arguments into a response file if the command line is too long.
+	// Intermediate files are removed on every return, failed runs included.
+	var intermediates []string
+	defer func() { removeIntermediates(intermediates, opts) }()
+	args, rspFile, err := useResponseFile(compiler, outputPath, args)
+	if err != nil {
+		return err
+	}
+	if rspFile != "" {
+		intermediates = append(intermediates, rspFile)
+	}
 
 	// If compiler requires environment setup, create and run a setup script
 	if compiler.EnvSetup != nil {
-		// Create a batch file to set up the environment and run the compilation
-		batchFile := filepath.Join(outputDir, "compile.bat")
-		batchContent := fmt.Sprintf(`@echo off
-call "%s" %s
-"%s" %s
-`, compiler.EnvSetup.SetupScript, strings.Join(compiler.EnvSetup.SetupArgs, " "),
-			compiler.Path, strings.Join(args, " "))
+		// Create a batch file to set up the environment and run the compilation,
+		// named after the output so concurrent builds don't share one
+		batchFile, err := filepath.Abs(filepath.Join(outputDir, "compile-"+outputBase+".bat"))
+		if err != nil {
+			return fmt.Errorf("failed to resolve batch file path: %v", err)
+		}
+		batchContent := batchScript(compiler, args)
 		if err := os.WriteFile(batchFile, []byte(batchContent), 0644); err != nil {
-			return "", fmt.Errorf("failed to create batch file: %v", err)
+			return fmt.Errorf("failed to create batch file: %v", err)
 		}
+		intermediates = append(intermediates, batchFile)
 
 		// Run the batch file
 		// Validate paths are safe
 		if !filepath.IsAbs(compiler.EnvSetup.SetupCmd) || !filepath.IsAbs(batchFile) {
-			return "", fmt.Errorf("invalid command or batch file path")
+			return fmt.Errorf("invalid command or batch file path")
 		}
 
 		ctx := context.Background()
 		cmd := exec.CommandContext(ctx, compiler.EnvSetup.SetupCmd, batchFile)
+		var stderr bytes.Buffer
 		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
 		if err := cmd.Run(); err != nil {
-			return "", fmt.Errorf("compilation failed: %v", err)
+			return &CompileError{Err: err, Stderr: stderr.String()}
 		}
-		return outputPath, nil
+		return checkWarnings(stderr.String(), opts)
 	}
 
 	// For compilers that don't need environment setup, run directly
 	// Validate compiler path is safe
 	if !filepath.IsAbs(compiler.Path) {
-		return "", fmt.Errorf("invalid compiler path: %s", compiler.Path)
+		return fmt.Errorf("invalid compiler path: %s", compiler.Path)
 	}
 
 	ctx := context.Background()
 	cmd := exec.CommandContext(ctx, compiler.Path, args...)
+	var stderr bytes.Buffer
 	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
+	cmd.Stderr = io.MultiWriter(os.Stderr, &stderr)
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("compilation failed: %v", err)
+		return &CompileError{Err: err, Stderr: stderr.String()}
 	}
+	return checkWarnings(stderr.String(), opts)
+}
 
-	return outputPath, nil
+// checkWarnings fails a successful compiler run that reported warnings in
+// stderr if opts.FailOnWarnings is set. Unlike WarningsAsErrors, this also
+// catches warnings compilers emit regardless of -Werror, such as linker ones.
+func checkWarnings(stderr string, opts *CompileOptions) error {
+	if !opts.FailOnWarnings || len(Warnings(ParseDiagnostics(stderr))) == 0 {
+		return nil
+	}
+	return &CompileError{Err: ErrCompilerWarnings, Stderr: stderr}
+}
+
+// CompileCommandString returns the command line compiling sourceFile into
+// outputDir would run, without running it. Arguments keep the order given in
+// opts, since include paths and libraries are searched in that order, and the
+// result depends only on the inputs, so equal inputs give byte-identical
+// strings usable as cache keys.
+func CompileCommandString(sourceFile, outputDir string, compiler *CompilerInfo, opts *CompileOptions) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+
+	outputPath := filepath.Join(outputDir, generateLibraryName(sourceFile, compiler))
+	args, err := precompiledHeaderArgs(outputDir, compiler, opts, false)
+	if err != nil {
+		return "", err
+	}
+	args = append(args, buildCompileCommand(sourceFile, outputPath, compiler, opts)...)
+
+	quote := quoteCommandArg
+	if compiler.Type == CompilerMSVC {
+		quote = quoteWindowsArg
+	}
+	parts := []string{quote(compiler.Path)}
+	for _, arg := range args {
+		parts = append(parts, quote(arg))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// quoteCommandArg quotes arg for display on a command line if it contains
+// whitespace or quote characters
+func quoteCommandArg(arg string) string {
+	if arg != "" && !strings.ContainsAny(arg, " \t\n\"'") {
+		return arg
+	}
+	return quoteGCCResponseArg(arg)
+}
+
+// responseFileThreshold is the command-line length above which arguments are
+// passed through a response file. cmd.exe limits lines to 8191 characters and
+// CreateProcess to 32767.
+const responseFileThreshold = 8000
+
+// useResponseFile writes args to a response file next to outputPath when the
+// command line would exceed responseFileThreshold. It returns the arguments to
+// run with and the response file path, or args unchanged and "" if none was needed.
+func useResponseFile(compiler *CompilerInfo, outputPath string, args []string) ([]string, string, error) {
+	length := len(compiler.Path)
+	for _, arg := range args {
+		length += len(arg) + 1
+	}
+	if length <= responseFileThreshold {
+		return args, "", nil
+	}
+
+	// MSVC reads response files with the Windows command-line quoting rules
+	lines := make([]string, len(args))
+	for i, arg := range args {
+		if compiler.Type == CompilerMSVC {
+			lines[i] = quoteWindowsArg(arg)
+		} else {
+			lines[i] = quoteGCCResponseArg(arg)
+		}
+	}
+
+	rspFile := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".rsp"
+	if err := os.WriteFile(rspFile, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return nil, "", fmt.Errorf("failed to create response file: %v", err)
+	}
+
+	return []string{"@" + rspFile}, rspFile, nil
+}
+
+// quoteGCCResponseArg quotes arg for a GCC/Clang response file, where
+// backslashes and double quotes inside quotes must be escaped
+func quoteGCCResponseArg(arg string) string {
+	arg = strings.ReplaceAll(arg, `\`, `\\`)
+	arg = strings.ReplaceAll(arg, `"`, `\"`)
+	return `"` + arg + `"`
 }
 
-func generateLibraryName(sourceFile string) string {
+// quoteWindowsArg quotes arg following the rules the Microsoft C runtime uses
+// to split a command line: backslashes are literal unless they precede a
+// double quote, in which case they and the quote are escaped
+func quoteWindowsArg(arg string) string {
+	return quoteWindowsArgIf(arg, " \t\"")
+}
+
+// quoteBatchArg quotes arg for a line of a batch file. On top of the usual
+// command-line quoting, cmd.exe metacharacters must be quoted and % is
+// expanded even inside quotes.
+func quoteBatchArg(arg string) string {
+	return strings.ReplaceAll(quoteWindowsArgIf(arg, " \t\"&|<>^()"), "%", "%%")
+}
+
+// quoteWindowsArgIf quotes arg if it is empty or contains any of special
+func quoteWindowsArgIf(arg, special string) string {
+	if arg != "" && !strings.ContainsAny(arg, special) {
+		return arg
+	}
+
+	var b strings.Builder
+	b.WriteByte('"')
+	backslashes := 0
+	for _, c := range arg {
+		switch c {
+		case '\\':
+			backslashes++
+			continue
+		case '"':
+			// Escape the preceding backslashes and the quote itself
+			b.WriteString(strings.Repeat(`\`, 2*backslashes+1))
+		default:
+			b.WriteString(strings.Repeat(`\`, backslashes))
+		}
+		backslashes = 0
+		b.WriteRune(c)
+	}
+	// Backslashes before the closing quote must be doubled
+	b.WriteString(strings.Repeat(`\`, 2*backslashes))
+	b.WriteByte('"')
+	return b.String()
+}
+
+// batchScript returns a batch file that sets up the compiler's environment
+// and runs it with args. The code page is switched to UTF-8 first so that
+// non-ASCII paths survive.
+func batchScript(compiler *CompilerInfo, args []string) string {
+	setup := []string{"call", quoteBatchArg(compiler.EnvSetup.SetupScript)}
+	for _, arg := range compiler.EnvSetup.SetupArgs {
+		setup = append(setup, quoteBatchArg(arg))
+	}
+
+	run := []string{quoteBatchArg(compiler.Path)}
+	for _, arg := range args {
+		run = append(run, quoteBatchArg(arg))
+	}
+
+	return "@echo off\r\nchcp 65001 >nul\r\n" + strings.Join(setup, " ") + "\r\n" + strings.Join(run, " ") + "\r\n"
+}
+
+// msvcIntermediates returns the object and export files MSVC leaves next to a DLL
+func msvcIntermediates(outputPath string) []string {
+	base := strings.TrimSuffix(outputPath, filepath.Ext(outputPath))
+	return []string{base + ".obj", base + ".exp"}
+}
+
+// removeIntermediates deletes temporary build artifacts unless opts.KeepIntermediates is set
+func removeIntermediates(paths []string, opts *CompileOptions) {
+	if opts.KeepIntermediates {
+		return
+	}
+	for _, path := range paths {
+		os.Remove(path)
+	}
+}
+
+// OutputFiles returns the files building sourceFile writes to outputDir: the
+// library, the JavaScript loader of WebAssembly builds and the batch file of
+// compilers that need their environment set up
+func OutputFiles(sourceFile, outputDir string, compiler *CompilerInfo) []string {
+	libPath := filepath.Join(outputDir, generateLibraryName(sourceFile, compiler))
+	files := []string{libPath}
+	base := strings.TrimSuffix(libPath, filepath.Ext(libPath))
+	if compiler.Type == CompilerEmscripten {
+		files = append(files, base+".js")
+	}
+	if compiler.EnvSetup != nil {
+		files = append(files, filepath.Join(outputDir, "compile-"+filepath.Base(base)+".bat"))
+	}
+	return files
+}
+
+func generateLibraryName(sourceFile string, compiler *CompilerInfo) string {
 	baseName := filepath.Base(sourceFile)
 	baseName = baseName[:len(baseName)-len(filepath.Ext(baseName))]
 
+	if compiler.Type == CompilerEmscripten {
+		return baseName + ".wasm"
+	}
+
 	switch runtime.GOOS {
 	case "windows":
 		return baseName + ".dll"
@@ -115,11 +523,14 @@ func buildCompileCommand(sourceFile, outputPath string, compiler *CompilerInfo,
 
 	switch compiler.Type {
 	case CompilerGCC:
-		args = buildGCCCommand(sourceFile, outputPath, opts)
-	case CompilerClang:
+		args = append(staticRuntimeFlags(compiler), buildGCCCommand(sourceFile, outputPath, opts)...)
+	case CompilerClang, CompilerIntel:
+		// Intel's oneAPI compilers are Clang-based and accept the same flags
 		args = buildClangCommand(sourceFile, outputPath, opts)
 	case CompilerMSVC:
 		args = buildMSVCCommand(sourceFile, outputPath, opts)
+	case CompilerEmscripten:
+		args = buildEmscriptenCommand(sourceFile, outputPath, opts)
 	default:
 		panic(fmt.Sprintf("unsupported compiler type: %s", compiler.Type))
 	}
@@ -131,64 +542,165 @@ func buildGCCCommand(sourceFile, outputPath string, opts *CompileOptions) []stri
 	args := []string{
 		"-shared",
 		"-fPIC",
-		opts.OptimizationLevel,
 		"-o", outputPath,
 	}
+	args = append(args, gccCompileFlags(opts)...)
+
+	for _, lib := range opts.LibraryPaths {
+		args = append(args, "-L"+lib)
+	}
+
+	args = append(args, opts.ExtraFlags...)
+	args = append(args, opts.LinkerFlags...)
+	args = append(args, sourceFile)
+
+	// Libraries must follow the sources that reference them
+	for _, lib := range opts.Libraries {
+		args = append(args, "-l"+lib)
+	}
+	return args
+}
+
+// gccCompileFlags returns the GCC/Clang flags that affect compiling a source,
+// as opposed to linking it
+func gccCompileFlags(opts *CompileOptions) []string {
+	var args []string
+
+	if opts.OptimizationLevel != "" {
+		args = append(args, opts.OptimizationLevel)
+	}
 
 	if opts.Debug {
 		args = append(args, "-g")
 	}
 
-	for _, include := range opts.IncludePaths {
-		args = append(args, "-I"+include)
+	switch opts.Warnings {
+	case WarningsAll:
+		args = append(args, "-Wall")
+	case WarningsExtra:
+		args = append(args, "-Wall", "-Wextra")
+	case WarningsNone:
+		args = append(args, "-w")
 	}
 
-	for _, lib := range opts.LibraryPaths {
-		args = append(args, "-L"+lib)
+	if opts.WarningsAsErrors {
+		args = append(args, "-Werror")
 	}
 
-	args = append(args, sourceFile)
+	if opts.HiddenVisibility {
+		args = append(args,
+			"-fvisibility=hidden",
+			"-D"+ExportMacro+`=__attribute__((visibility("default")))`,
+		)
+	}
+
+	for _, include := range opts.IncludePaths {
+		args = append(args, "-I"+include)
+	}
 	return args
 }
 
+// staticRuntimeFlags returns the flags linking compiler's C++ runtime into
+// the library. MinGW's libgcc and libstdc++ DLLs are rarely on the PATH of
+// the Python process loading the library, so they are linked statically.
+func staticRuntimeFlags(compiler *CompilerInfo) []string {
+	if compiler.MinGW {
+		return []string{"-static-libgcc", "-static-libstdc++"}
+	}
+	return nil
+}
+
 func buildClangCommand(sourceFile, outputPath string, opts *CompileOptions) []string {
 	// Clang uses the same flags as GCC
 	return buildGCCCommand(sourceFile, outputPath, opts)
 }
 
+// buildEmscriptenCommand builds an em++ command producing outputPath (a .wasm
+// module) together with a JavaScript loader of the same name ending in .js
+func buildEmscriptenCommand(sourceFile, outputPath string, opts *CompileOptions) []string {
+	// Emscripten takes GCC-style flags, but links a WebAssembly module rather
+	// than a shared library, so -shared and -fPIC are dropped. Naming a .js
+	// output makes em++ emit the .wasm file next to its loader.
+	jsPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".js"
+	args := buildGCCCommand(sourceFile, jsPath, opts)[2:]
+	return append(args, emscriptenSettings(opts)...)
+}
+
+// emscriptenSettings returns the -s settings for linking a WebAssembly module
+// with a JavaScript loader that exports opts.ExportedFunctions
+func emscriptenSettings(opts *CompileOptions) []string {
+	args := []string{
+		"-sMODULARIZE=1",
+		"-sEXPORTED_RUNTIME_METHODS=ccall,cwrap",
+	}
+	if len(opts.ExportedFunctions) > 0 {
+		exported := make([]string, len(opts.ExportedFunctions))
+		for i, name := range opts.ExportedFunctions {
+			exported[i] = "_" + name
+		}
+		args = append(args, "-sEXPORTED_FUNCTIONS="+strings.Join(exported, ","))
+	}
+	return args
+}
+
 func buildMSVCCommand(sourceFile, outputPath string, opts *CompileOptions) []string {
 	args := []string{
 		"/LD", // Create DLL
-		"/MD", // Use multithreaded DLL runtime
+		runtimeFlags[opts.RuntimeLibrary],
 		"/Fe:" + outputPath,
+		"/Fo:" + msvcIntermediates(outputPath)[0], // Keep the object file out of the working directory
+	}
+	args = append(args, msvcCompileFlags(opts)...)
+	args = append(args, opts.ExtraFlags...)
+	args = append(args, sourceFile)
+
+	for _, lib := range opts.Libraries {
+		args = append(args, strings.TrimSuffix(lib, ".lib")+".lib")
+	}
+	return append(args, msvcLinkerArgs(opts)...)
+}
+
+// msvcLinkerArgs returns the /link section of a cl command, passing library
+// paths and opts.LinkerFlags on to the linker. It must come last, since cl
+// hands everything after /link to the linker.
+func msvcLinkerArgs(opts *CompileOptions) []string {
+	if len(opts.LibraryPaths) == 0 && len(opts.LinkerFlags) == 0 {
+		return nil
+	}
+	args := []string{"/link"}
+	for _, lib := range opts.LibraryPaths {
+		args = append(args, "/LIBPATH:"+lib)
 	}
+	return append(args, opts.LinkerFlags...)
+}
+
+// msvcCompileFlags returns the MSVC flags that affect compiling a source, as
+// opposed to linking it
+func msvcCompileFlags(opts *CompileOptions) []string {
+	var args []string
 
-	// Map optimization levels
-	switch opts.OptimizationLevel {
-	case "-O0":
-		args = append(args, "/Od") // No optimization
-	case "-O1":
-		args = append(args, "/O1") // Minimize size
-	case "-O2":
-		args = append(args, "/O2") // Maximize speed
-	case "-O3":
-		args = append(args, "/O2") // MSVC doesn't have O3, use O2
+	if flag, ok := optimizationLevels[opts.OptimizationLevel]; ok {
+		args = append(args, flag)
 	}
 
 	if opts.Debug {
 		args = append(args, "/Zi")
 	}
 
-	// Add include paths
-	for _, include := range opts.IncludePaths {
-		args = append(args, "/I\""+include+"\"")
+	switch opts.Warnings {
+	case WarningsAll, WarningsExtra:
+		args = append(args, "/W4") // MSVC's /Wall is far noisier than GCC's -Wall
+	case WarningsNone:
+		args = append(args, "/w")
 	}
 
-	// Add library paths
-	for _, lib := range opts.LibraryPaths {
-		args = append(args, "/LIBPATH:\""+lib+"\"")
+	if opts.WarningsAsErrors {
+		args = append(args, "/WX")
 	}
 
-	args = append(args, sourceFile)
+	// Add include paths
+	for _, include := range opts.IncludePaths {
+		args = append(args, "/I"+include)
+	}
 	return args
 }
@@ -0,0 +1,81 @@
+package compiler
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"cp2p/util"
+)
+
+// compileSource compiles one source for CompileAll; tests replace it
+var compileSource = CompileWithOptions
+
+// CompileAll compiles each source into its own shared library in outputDir,
+// running up to opts.Jobs (by default runtime.NumCPU()) compilations
+// concurrently. It returns the library path of every source that compiled,
+// along with the first error hit.
+func CompileAll(sources []string, outputDir string, compiler *CompilerInfo, opts *CompileOptions) (map[string]string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Create the output directory once up front instead of in every worker
+	if err := util.EnsureWritableDir(outputDir); err != nil {
+		return nil, fmt.Errorf("failed to prepare output directory: %w", err)
+	}
+
+	// Sources sharing a base name would overwrite each other's library
+	libSources := make(map[string]string)
+	for _, src := range sources {
+		libName := generateLibraryName(src, compiler)
+		if other, ok := libSources[libName]; ok {
+			return nil, fmt.Errorf("sources %s and %s both produce %s", other, src, libName)
+		}
+		libSources[libName] = src
+	}
+
+	// Build the precompiled header once, for every worker to reuse
+	if opts.PrecompiledHeader != "" {
+		if _, err := PrecompileHeader(outputDir, compiler, opts); err != nil {
+			return nil, fmt.Errorf("failed to precompile %s: %w", opts.PrecompiledHeader, err)
+		}
+	}
+
+	jobs := opts.Jobs
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		results  = make(map[string]string, len(sources))
+		workers  = make(chan struct{}, jobs)
+	)
+
+	for _, src := range sources {
+		wg.Add(1)
+		workers <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-workers }()
+
+			libPath, err := compileSource(src, outputDir, compiler, opts)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("%s: %w", src, err)
+				}
+				return
+			}
+			results[src] = libPath
+		}()
+	}
+
+	wg.Wait()
+	return results, firstErr
+}
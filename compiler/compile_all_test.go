@@ -0,0 +1,95 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCompileAll(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	outputDir := filepath.Join(tmpDir, "out")
+
+	var sources []string
+	for i := range 4 {
+		src := filepath.Join(tmpDir, fmt.Sprintf("mod%d.cpp", i))
+		content := fmt.Sprintf("extern \"C\" int value%d() { return %d; }\n", i, i)
+		if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		sources = append(sources, src)
+	}
+
+	results, err := CompileAll(sources, outputDir, compiler, DefaultCompileOptions())
+	if err != nil {
+		t.Fatalf("CompileAll() error = %v", err)
+	}
+
+	if len(results) != len(sources) {
+		t.Fatalf("Expected %d libraries, got %d", len(sources), len(results))
+	}
+	for _, src := range sources {
+		libPath, ok := results[src]
+		if !ok {
+			t.Errorf("No library reported for %s", src)
+			continue
+		}
+		if _, err := os.Stat(libPath); err != nil {
+			t.Errorf("Library file not created for %s: %v", src, err)
+		}
+	}
+}
+
+func TestCompileAllNameCollision(t *testing.T) {
+	compiler := &CompilerInfo{Type: CompilerGCC, Path: "/usr/bin/g++"}
+	sources := []string{filepath.Join("a", fileName), filepath.Join("b", fileName)}
+
+	if _, err := CompileAll(sources, t.TempDir(), compiler, DefaultCompileOptions()); err == nil {
+		t.Error("Expected an error for sources producing the same library")
+	}
+}
+
+func TestCompileAllJobs(t *testing.T) {
+	origCompile := compileSource
+	defer func() { compileSource = origCompile }()
+
+	for _, jobs := range []int{1, 3} {
+		var mu sync.Mutex
+		running, peak := 0, 0
+		compileSource = func(src, outputDir string, compiler *CompilerInfo, opts *CompileOptions) (string, error) {
+			mu.Lock()
+			running++
+			peak = max(peak, running)
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return filepath.Join(outputDir, generateLibraryName(src, compiler)), nil
+		}
+
+		sources := []string{"a.cpp", "b.cpp", "c.cpp", "d.cpp", "e.cpp", "f.cpp"}
+		opts := DefaultCompileOptions()
+		opts.Jobs = jobs
+		results, err := CompileAll(sources, t.TempDir(), &CompilerInfo{Type: CompilerGCC, Path: "g++"}, opts)
+		if err != nil {
+			t.Fatalf("CompileAll() error = %v", err)
+		}
+		if len(results) != len(sources) {
+			t.Errorf("Jobs %d: got %d results, want %d", jobs, len(results), len(sources))
+		}
+		if peak > jobs {
+			t.Errorf("Jobs %d: %d compilations ran at once", jobs, peak)
+		}
+	}
+}
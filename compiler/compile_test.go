@@ -1,10 +1,18 @@
 package compiler
 
 import (
+	"errors"
+	"fmt"
+	"maps"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
 	"slices"
+	"strings"
 	"testing"
+
+	"cp2p/config"
 )
 
 const fileName = "test.cpp"
@@ -57,6 +65,37 @@ extern "C" {
 	}
 }
 
+func TestCompileKeepsExistingObject(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerGCC)
+	if err != nil {
+		t.Skipf("GCC not available: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, fileName)
+	if err := os.WriteFile(testFile, []byte("extern \"C\" int add(int a, int b) { return a + b; }\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// An object of the user's, newer than the source, where CompileObject
+	// would put its own
+	userObject := filepath.Join(tmpDir, objectFileName(testFile, compiler))
+	if err := os.WriteFile(userObject, []byte("not an object file"), 0644); err != nil {
+		t.Fatalf("Failed to create object file: %v", err)
+	}
+
+	if _, err := Compile(testFile, tmpDir, compiler); err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	content, err := os.ReadFile(userObject)
+	if err != nil {
+		t.Fatalf("Compile() removed the existing object file: %v", err)
+	}
+	if string(content) != "not an object file" {
+		t.Errorf("Compile() overwrote the existing object file")
+	}
+}
+
 func TestCompileWithOptions(t *testing.T) {
 	// Create a temporary directory for test files
 	tmpDir := t.TempDir()
@@ -228,3 +267,778 @@ func testBuildCompileCommandCase(tt struct {
 		}
 	}
 }
+
+func TestWarningFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		build   func(string, string, *CompileOptions) []string
+		opts    *CompileOptions
+		want    []string
+		notWant []string
+	}{
+		{
+			name:    "GCC all",
+			build:   buildGCCCommand,
+			opts:    &CompileOptions{OptimizationLevel: "-O2", Warnings: WarningsAll},
+			want:    []string{"-Wall"},
+			notWant: []string{"-Wextra", "-Werror"},
+		},
+		{
+			name:  "GCC extra as errors",
+			build: buildGCCCommand,
+			opts:  &CompileOptions{OptimizationLevel: "-O2", Warnings: WarningsExtra, WarningsAsErrors: true},
+			want:  []string{"-Wall", "-Wextra", "-Werror"},
+		},
+		{
+			name:    "GCC none",
+			build:   buildGCCCommand,
+			opts:    &CompileOptions{OptimizationLevel: "-O2", Warnings: WarningsNone},
+			want:    []string{"-w"},
+			notWant: []string{"-Wall"},
+		},
+		{
+			name:  "Clang all",
+			build: buildClangCommand,
+			opts:  &CompileOptions{OptimizationLevel: "-O2", Warnings: WarningsAll, WarningsAsErrors: true},
+			want:  []string{"-Wall", "-Werror"},
+		},
+		{
+			name:  "MSVC extra as errors",
+			build: buildMSVCCommand,
+			opts:  &CompileOptions{OptimizationLevel: "-O2", Warnings: WarningsExtra, WarningsAsErrors: true},
+			want:  []string{"/W4", "/WX"},
+		},
+		{
+			name:    "MSVC none",
+			build:   buildMSVCCommand,
+			opts:    &CompileOptions{OptimizationLevel: "-O2", Warnings: WarningsNone},
+			want:    []string{"/w"},
+			notWant: []string{"/W4", "/WX"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.build(fileName, "out", tt.opts)
+			for _, flag := range tt.want {
+				if !slices.Contains(args, flag) {
+					t.Errorf("Expected flag %s in %v", flag, args)
+				}
+			}
+			for _, flag := range tt.notWant {
+				if slices.Contains(args, flag) {
+					t.Errorf("Unexpected flag %s in %v", flag, args)
+				}
+			}
+		})
+	}
+}
+
+func TestExtraFlags(t *testing.T) {
+	opts := DefaultCompileOptions()
+	opts.ExtraFlags = []string{"-std=c++17", "-DNDEBUG"}
+
+	builds := map[string]func(string, string, *CompileOptions) []string{
+		"GCC":  buildGCCCommand,
+		"MSVC": buildMSVCCommand,
+	}
+	for name, build := range builds {
+		t.Run(name, func(t *testing.T) {
+			args := build(fileName, "out", opts)
+			std := slices.Index(args, "-std=c++17")
+			src := slices.Index(args, fileName)
+			if std < 0 || !slices.Contains(args, "-DNDEBUG") {
+				t.Fatalf("Expected extra flags in %v", args)
+			}
+			if std > src {
+				t.Errorf("Expected extra flags before the source file in %v", args)
+			}
+		})
+	}
+}
+
+func TestLinkerFlags(t *testing.T) {
+	opts := DefaultCompileOptions()
+	opts.LinkerFlags = []string{"-Wl,-soname,libtest.so.1"}
+	opts.Libraries = []string{"m"}
+
+	args := buildGCCCommand(fileName, "out", opts)
+	soname := slices.Index(args, "-Wl,-soname,libtest.so.1")
+	if soname < 0 {
+		t.Fatalf("Expected linker flags in %v", args)
+	}
+	if soname > slices.Index(args, "-lm") {
+		t.Errorf("Expected linker flags before the libraries in %v", args)
+	}
+
+	opts.LinkerFlags = []string{"/DEF:exports.def"}
+	opts.LibraryPaths = []string{"deps"}
+	args = buildMSVCCommand(fileName, "out.dll", opts)
+	want := []string{"/link", "/LIBPATH:deps", "/DEF:exports.def"}
+	if !slices.Equal(args[len(args)-len(want):], want) {
+		t.Errorf("Expected %v at the end of %v", want, args)
+	}
+
+	// Without library paths or linker flags there is no /link section
+	args = buildMSVCCommand(fileName, "out.dll", DefaultCompileOptions())
+	if slices.Contains(args, "/link") {
+		t.Errorf("Unexpected /link in %v", args)
+	}
+}
+
+func TestRuntimeLibraryFlags(t *testing.T) {
+	tests := []struct {
+		runtime string
+		want    string
+	}{
+		{RuntimeDefault, "/MD"},
+		{RuntimeDLL, "/MD"},
+		{RuntimeStatic, "/MT"},
+		{RuntimeDLLDebug, "/MDd"},
+		{RuntimeStaticDebug, "/MTd"},
+	}
+
+	allFlags := slices.Collect(maps.Values(runtimeFlags))
+	for _, tt := range tests {
+		opts := DefaultCompileOptions()
+		opts.RuntimeLibrary = tt.runtime
+
+		var runtimes []string
+		for _, arg := range buildMSVCCommand(fileName, "test.dll", opts) {
+			if slices.Contains(allFlags, arg) {
+				runtimes = append(runtimes, arg)
+			}
+		}
+		if !slices.Equal(runtimes, []string{tt.want}) {
+			t.Errorf("RuntimeLibrary %q: runtime flags %v, want [%s]", tt.runtime, runtimes, tt.want)
+		}
+	}
+}
+
+func TestOptimizationLevelFlags(t *testing.T) {
+	tests := []struct {
+		level    string
+		wantGCC  string
+		wantMSVC string
+	}{
+		{"-O0", "-O0", "/Od"},
+		{"-O2", "-O2", "/O2"},
+		{"-O3", "-O3", "/O2"},
+		{"-Os", "-Os", "/O1"},
+		{"-Og", "-Og", "/Od"},
+	}
+
+	for _, tt := range tests {
+		opts := DefaultCompileOptions()
+		opts.OptimizationLevel = tt.level
+		if args := buildGCCCommand(fileName, "libtest.so", opts); !slices.Contains(args, tt.wantGCC) {
+			t.Errorf("GCC %s: expected %s in %v", tt.level, tt.wantGCC, args)
+		}
+		if args := buildMSVCCommand(fileName, "test.dll", opts); !slices.Contains(args, tt.wantMSVC) {
+			t.Errorf("MSVC %s: expected %s in %v", tt.level, tt.wantMSVC, args)
+		}
+	}
+
+	// The compiler's default level passes no flag rather than an empty argument
+	args := buildGCCCommand(fileName, "libtest.so", &CompileOptions{})
+	if slices.Contains(args, "") {
+		t.Errorf("Expected no empty argument in %v", args)
+	}
+}
+
+func TestCompileOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    *CompileOptions
+		wantErr bool
+	}{
+		{name: "Defaults", opts: DefaultCompileOptions(), wantErr: false},
+		{name: "Extra as errors", opts: &CompileOptions{Warnings: WarningsExtra, WarningsAsErrors: true}, wantErr: false},
+		{name: "None as errors", opts: &CompileOptions{Warnings: WarningsNone, WarningsAsErrors: true}, wantErr: true},
+		{name: "Unknown level", opts: &CompileOptions{Warnings: "pedantic"}, wantErr: true},
+		{name: "Extra flags", opts: &CompileOptions{ExtraFlags: []string{"-std=c++17", "-DNDEBUG"}}, wantErr: false},
+		{name: "Empty extra flag", opts: &CompileOptions{ExtraFlags: []string{"-std=c++17", " "}}, wantErr: true},
+		{name: "Empty linker flag", opts: &CompileOptions{LinkerFlags: []string{""}}, wantErr: true},
+		{name: "Static runtime", opts: &CompileOptions{RuntimeLibrary: RuntimeStatic}, wantErr: false},
+		{name: "Debug runtime", opts: &CompileOptions{RuntimeLibrary: RuntimeDLLDebug, Debug: true}, wantErr: false},
+		{name: "Debug runtime in release build", opts: &CompileOptions{RuntimeLibrary: RuntimeStaticDebug}, wantErr: true},
+		{name: "Unknown runtime", opts: &CompileOptions{RuntimeLibrary: "ml"}, wantErr: true},
+		{name: "Serial jobs", opts: &CompileOptions{Jobs: 1}, wantErr: false},
+		{name: "Negative jobs", opts: &CompileOptions{Jobs: -1}, wantErr: true},
+		{name: "Size optimization", opts: &CompileOptions{OptimizationLevel: "-Os"}, wantErr: false},
+		{name: "Debug optimization", opts: &CompileOptions{OptimizationLevel: "-Og"}, wantErr: false},
+		{name: "Unknown optimization", opts: &CompileOptions{OptimizationLevel: "-O9"}, wantErr: true},
+		{name: "MSVC optimization spelling", opts: &CompileOptions{OptimizationLevel: "/O2"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHiddenVisibilityFlags(t *testing.T) {
+	opts := DefaultCompileOptions()
+	opts.HiddenVisibility = true
+
+	args := buildGCCCommand(fileName, "out", opts)
+	if !slices.Contains(args, "-fvisibility=hidden") {
+		t.Errorf("Expected -fvisibility=hidden in %v", args)
+	}
+	if !slices.Contains(args, "-D"+ExportMacro+`=__attribute__((visibility("default")))`) {
+		t.Errorf("Expected %s definition in %v", ExportMacro, args)
+	}
+
+	args = buildGCCCommand(fileName, "out", DefaultCompileOptions())
+	if slices.Contains(args, "-fvisibility=hidden") {
+		t.Errorf("Unexpected -fvisibility=hidden in %v", args)
+	}
+}
+
+func TestMinGWStaticRuntimeFlags(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		t.Skip("MinGW builds Windows DLLs")
+	}
+
+	mingw := &CompilerInfo{Type: CompilerGCC, Path: "x86_64-w64-mingw32-g++", MinGW: true}
+	args := buildCompileCommand(fileName, "out.dll", mingw, DefaultCompileOptions())
+	for _, flag := range []string{"-static-libgcc", "-static-libstdc++"} {
+		if !slices.Contains(args, flag) {
+			t.Errorf("Expected %s in %v", flag, args)
+		}
+	}
+
+	gcc := &CompilerInfo{Type: CompilerGCC, Path: "g++"}
+	args = buildCompileCommand(fileName, "out.dll", gcc, DefaultCompileOptions())
+	if slices.Contains(args, "-static-libgcc") {
+		t.Errorf("Unexpected -static-libgcc in %v", args)
+	}
+}
+
+func TestCompileHiddenVisibility(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerGCC)
+	if err != nil {
+		t.Skipf("GCC not available: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, fileName)
+	testContent := `
+extern "C" {
+    CP2P_EXPORT int add(int a, int b) {
+        return a + b;
+    }
+}
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	opts := DefaultCompileOptions()
+	opts.HiddenVisibility = true
+	if _, err := CompileWithOptions(testFile, tmpDir, compiler, opts); err != nil {
+		t.Fatalf("CompileWithOptions() error = %v", err)
+	}
+}
+
+func TestCompileWithFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Mock compiler names are Unix-specific")
+	}
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, fileName)
+	if err := os.WriteFile(testFile, []byte("int add(int a, int b) { return a + b; }\n"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// A Clang that always fails and a GCC that always succeeds
+	mockCompilerWithExit(t, tmpDir, "clang++", "clang version 12.0.0", 1)
+	mockCompiler(t, tmpDir, "g++", "g++ (GCC) 9.4.0")
+
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", tmpDir)
+
+	failing, err := DetectCompiler(CompilerClang)
+	if err != nil {
+		t.Fatalf("Failed to detect mock clang: %v", err)
+	}
+
+	opts := DefaultCompileOptions()
+	if _, _, err := CompileWithFallback(testFile, tmpDir, failing, opts); err == nil {
+		t.Fatal("Expected compilation to fail without fallback")
+	}
+
+	opts.Fallback = true
+	_, used, err := CompileWithFallback(testFile, tmpDir, failing, opts)
+	if err != nil {
+		t.Fatalf("CompileWithFallback() error = %v", err)
+	}
+	if used.Type != CompilerGCC {
+		t.Errorf("Expected fallback to GCC, got %s", used.Type)
+	}
+}
+
+func TestConfigIncludesInCommand(t *testing.T) {
+	cfg := &config.Config{
+		Includes:  []string{"/opt/common/include"},
+		Libraries: []string{"m"},
+		Functions: []config.FunctionConfig{
+			{Name: "draw", ReturnType: "void", Includes: []string{"/opt/widget/include"}, Libraries: []string{"widget"}},
+			{Name: "area", ReturnType: "double", Includes: []string{"/opt/common/include"}},
+		},
+	}
+
+	opts := DefaultCompileOptions()
+	opts.IncludePaths = cfg.AllIncludes()
+	opts.Libraries = cfg.AllLibraries()
+
+	args := buildGCCCommand(fileName, "out", opts)
+	for _, flag := range []string{"-I/opt/common/include", "-I/opt/widget/include", "-lm", "-lwidget"} {
+		if !slices.Contains(args, flag) {
+			t.Errorf("Expected flag %s in %v", flag, args)
+		}
+	}
+	if slices.Index(args, "-lwidget") < slices.Index(args, fileName) {
+		t.Errorf("Expected libraries after the source file in %v", args)
+	}
+
+	args = buildMSVCCommand(fileName, "out", opts)
+	for _, flag := range []string{"/I/opt/widget/include", "widget.lib"} {
+		if !slices.Contains(args, flag) {
+			t.Errorf("Expected flag %s in %v", flag, args)
+		}
+	}
+}
+
+func TestCompilerIncludesInCommand(t *testing.T) {
+	// cl.exe outside a Developer Prompt only finds its headers through /I
+	msvc := &CompilerInfo{Type: CompilerMSVC, Path: `C:\VC\bin\cl.exe`, IncludePaths: []string{`C:\VC\include`}}
+	opts := DefaultCompileOptions()
+	opts.IncludePaths = msvc.CommandIncludePaths()
+	if args := buildMSVCCommand(fileName, "out", opts); !slices.Contains(args, `/IC:\VC\include`) {
+		t.Errorf("Expected flag /IC:\\VC\\include in %v", args)
+	}
+
+	// GCC searches its system directories itself
+	gcc := &CompilerInfo{Type: CompilerGCC, Path: "/usr/bin/g++", IncludePaths: []string{"/usr/include/c++/13"}}
+	opts = DefaultCompileOptions()
+	opts.IncludePaths = gcc.CommandIncludePaths()
+	if args := buildGCCCommand(fileName, "out", opts); slices.Contains(args, "-I/usr/include/c++/13") {
+		t.Errorf("Unexpected system include directory in %v", args)
+	}
+}
+
+func TestIntermediatesCleanup(t *testing.T) {
+	// The batch file switches the code page with chcp, which only cmd runs
+	if runtime.GOOS != "windows" {
+		t.Skip("Runs the batch file through cmd")
+	}
+
+	tests := []struct {
+		name    string
+		keep    bool
+		exit    int    // Exit status of the mock compiler
+		setup   string // Environment setup command; empty for the batch runner
+		wantErr bool
+	}{
+		{name: "success", exit: 0},
+		{name: "success keeping intermediates", keep: true, exit: 0},
+		{name: "compile failure", exit: 1, wantErr: true},
+		{name: "compile failure keeping intermediates", keep: true, exit: 1, wantErr: true},
+		{name: "invalid setup command", setup: "cmd", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			testFile := filepath.Join(tmpDir, fileName)
+			if err := os.WriteFile(testFile, []byte("int x;\n"), 0644); err != nil {
+				t.Fatalf("Failed to create test file: %v", err)
+			}
+			setupScript := filepath.Join(tmpDir, "vcvarsall.bat")
+			if err := os.WriteFile(setupScript, []byte("@echo off\r\n"), 0644); err != nil {
+				t.Fatalf("Failed to create setup script: %v", err)
+			}
+
+			// An MSVC-style compiler whose environment setup runs the batch
+			// file through cmd
+			setup := tt.setup
+			if setup == "" {
+				setup = mockBatchRunner(t, tmpDir)
+			}
+			compiler := &CompilerInfo{
+				Type:     CompilerMSVC,
+				Path:     mockCompilerWithExit(t, tmpDir, "cl.exe", "Microsoft (R) C/C++ Optimizing Compiler", tt.exit),
+				EnvSetup: &CompilerEnvSetup{SetupScript: setupScript, SetupCmd: setup},
+			}
+
+			// Run from an empty working directory, so nothing the batch file
+			// writes can land in the source tree
+			workDir := t.TempDir()
+			t.Chdir(workDir)
+
+			opts := DefaultCompileOptions()
+			opts.KeepIntermediates = tt.keep
+			_, err := CompileWithOptions(testFile, tmpDir, compiler, opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompileWithOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if entries, _ := os.ReadDir(workDir); len(entries) != 0 {
+				t.Errorf("Expected nothing left in the working directory, found %v", entries)
+			}
+			batchFiles, _ := filepath.Glob(filepath.Join(tmpDir, "*.bat"))
+			batchFiles = slices.DeleteFunc(batchFiles, func(path string) bool { return path == setupScript })
+			if tt.keep && len(batchFiles) != 1 {
+				t.Errorf("Expected the batch file to be kept, found %v", batchFiles)
+			}
+			if !tt.keep && len(batchFiles) != 0 {
+				t.Errorf("Expected the batch file to be removed, found %v", batchFiles)
+			}
+		})
+	}
+}
+
+// mockBatchRunner builds a setup command running the batch file it is given
+// with cmd /c, exiting with its status
+func mockBatchRunner(t *testing.T, dir string) string {
+	path := filepath.Join(dir, "runbatch.exe")
+	srcPath := filepath.Join(dir, "runbatch.go")
+	content := []byte(`package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func main() {
+	cmd := exec.Command("cmd", "/c", os.Args[1])
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	var exitErr *exec.ExitError
+	if err := cmd.Run(); errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	} else if err != nil {
+		os.Exit(1)
+	}
+}`)
+	if err := os.WriteFile(srcPath, content, 0644); err != nil {
+		t.Fatalf("Failed to create batch runner source: %v", err)
+	}
+	if err := exec.Command("go", "build", "-o", path, srcPath).Run(); err != nil {
+		t.Fatalf("Failed to build batch runner: %v", err)
+	}
+	os.Remove(srcPath)
+	return path
+}
+
+func TestCompileWithResponseFile(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerGCC)
+	if err != nil {
+		t.Skipf("GCC not available: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, fileName)
+	testContent := `
+#include "marker.h"
+
+extern "C" int add(int a, int b) { return a + b + MARKER; }
+`
+	if err := os.WriteFile(testFile, []byte(testContent), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	// The only include path that matters contains a space and comes last
+	headerDir := filepath.Join(tmpDir, "real headers")
+	if err := os.Mkdir(headerDir, 0755); err != nil {
+		t.Fatalf("Failed to create header directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(headerDir, "marker.h"), []byte("#define MARKER 0\n"), 0644); err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+
+	opts := DefaultCompileOptions()
+	opts.KeepIntermediates = true
+	for i := range 400 {
+		opts.IncludePaths = append(opts.IncludePaths, filepath.Join(tmpDir, fmt.Sprintf("missing-include-dir-%03d", i)))
+	}
+	opts.IncludePaths = append(opts.IncludePaths, headerDir)
+
+	libPath, err := CompileWithOptions(testFile, tmpDir, compiler, opts)
+	if err != nil {
+		t.Fatalf("CompileWithOptions() error = %v", err)
+	}
+	if _, err := os.Stat(libPath); err != nil {
+		t.Fatalf("Library file not created: %v", err)
+	}
+
+	rspFiles, _ := filepath.Glob(filepath.Join(tmpDir, "*.rsp"))
+	if len(rspFiles) != 1 {
+		t.Errorf("Expected a response file to be used, found %v", rspFiles)
+	}
+}
+
+func TestUseResponseFileShortCommand(t *testing.T) {
+	compiler := &CompilerInfo{Type: CompilerGCC, Path: "/usr/bin/g++"}
+	args := []string{"-shared", "-o", "out.so", fileName}
+
+	got, rspFile, err := useResponseFile(compiler, filepath.Join(t.TempDir(), "out.so"), args)
+	if err != nil {
+		t.Fatalf("useResponseFile() error = %v", err)
+	}
+	if rspFile != "" || !slices.Equal(got, args) {
+		t.Errorf("Expected short command to run unchanged, got %v with %q", got, rspFile)
+	}
+}
+
+func TestEmscriptenCommand(t *testing.T) {
+	emcc := &CompilerInfo{Type: CompilerEmscripten, Path: "/usr/bin/em++"}
+	outputPath := generateLibraryName(filepath.Join("src", "math.cpp"), emcc)
+	if outputPath != "math.wasm" {
+		t.Errorf("generateLibraryName() = %s, want math.wasm", outputPath)
+	}
+
+	opts := DefaultCompileOptions()
+	opts.ExportedFunctions = []string{"add", "sub"}
+	args := buildCompileCommand("math.cpp", outputPath, emcc, opts)
+
+	for _, want := range []string{"math.js", "-sMODULARIZE=1", "-sEXPORTED_FUNCTIONS=_add,_sub"} {
+		if !slices.Contains(args, want) {
+			t.Errorf("Expected %s in %v", want, args)
+		}
+	}
+	for _, unwanted := range []string{"-shared", "-fPIC"} {
+		if slices.Contains(args, unwanted) {
+			t.Errorf("Unexpected %s in %v", unwanted, args)
+		}
+	}
+}
+
+func TestCompileEmscripten(t *testing.T) {
+	emcc, err := DetectCompiler(CompilerEmscripten)
+	if err != nil {
+		t.Skipf("Skipping WebAssembly build: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	srcPath := filepath.Join(tmpDir, "wasm_add.cpp")
+	src := `extern "C" int add(int a, int b) { return a + b; }` + "\n"
+	if err := os.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	opts := DefaultCompileOptions()
+	opts.ExportedFunctions = []string{"add"}
+	wasmPath, err := CompileWithOptions(srcPath, tmpDir, emcc, opts)
+	if err != nil {
+		t.Fatalf("CompileWithOptions() error = %v", err)
+	}
+
+	for _, path := range []string{wasmPath, filepath.Join(tmpDir, "wasm_add.js")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected build output %s: %v", path, err)
+		}
+	}
+}
+
+func TestCompileCommandString(t *testing.T) {
+	gcc := &CompilerInfo{Type: CompilerGCC, Path: "/usr/bin/g++"}
+	newOpts := func() *CompileOptions {
+		opts := DefaultCompileOptions()
+		opts.IncludePaths = []string{"/opt/zlib/include", "/opt/my libs/include"}
+		opts.Libraries = []string{"z", "m"}
+		opts.HiddenVisibility = true
+		return opts
+	}
+
+	first, err := CompileCommandString(fileName, "out", gcc, newOpts())
+	if err != nil {
+		t.Fatalf("CompileCommandString() error = %v", err)
+	}
+	second, err := CompileCommandString(fileName, "out", gcc, newOpts())
+	if err != nil {
+		t.Fatalf("CompileCommandString() error = %v", err)
+	}
+	if first != second {
+		t.Errorf("Expected identical command strings, got:\n%s\n%s", first, second)
+	}
+
+	for _, want := range []string{"/usr/bin/g++ -shared", `"-I/opt/my libs/include"`, "-lz -lm"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("Expected %s in %s", want, first)
+		}
+	}
+
+	opts := newOpts()
+	opts.Warnings = "pedantic"
+	if _, err := CompileCommandString(fileName, "out", gcc, opts); err == nil {
+		t.Error("Expected an error for invalid options")
+	}
+}
+
+func TestQuoteWindowsArg(t *testing.T) {
+	tests := []struct {
+		arg   string
+		want  string
+		batch string
+	}{
+		{arg: "/O2", want: "/O2", batch: "/O2"},
+		{arg: "", want: `""`, batch: `""`},
+		{arg: `C:\my project\add.cpp`, want: `"C:\my project\add.cpp"`, batch: `"C:\my project\add.cpp"`},
+		{arg: `/IC:\my dir\`, want: `"/IC:\my dir\\"`, batch: `"/IC:\my dir\\"`},
+		{arg: `say "hi"`, want: `"say \"hi\""`, batch: `"say \"hi\""`},
+		{arg: `C:\R&D\lib`, want: `C:\R&D\lib`, batch: `"C:\R&D\lib"`},
+		{arg: `C:\100%\ünï.cpp`, want: `C:\100%\ünï.cpp`, batch: `C:\100%%\ünï.cpp`},
+	}
+
+	for _, tt := range tests {
+		if got := quoteWindowsArg(tt.arg); got != tt.want {
+			t.Errorf("quoteWindowsArg(%q) = %s, want %s", tt.arg, got, tt.want)
+		}
+		if got := quoteBatchArg(tt.arg); got != tt.batch {
+			t.Errorf("quoteBatchArg(%q) = %s, want %s", tt.arg, got, tt.batch)
+		}
+	}
+}
+
+func TestCompilePathWithSpaces(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "my project", "ünïcode dir")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	srcPath := filepath.Join(dir, "add one.cpp")
+	if err := os.WriteFile(srcPath, []byte(`extern "C" int add(int a, int b) { return a + b; }`+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	opts := DefaultCompileOptions()
+	opts.IncludePaths = append(slices.Clone(compiler.IncludePaths), dir)
+	libPath, err := CompileWithOptions(srcPath, filepath.Join(dir, "out dir"), compiler, opts)
+	if err != nil {
+		t.Fatalf("CompileWithOptions() error = %v", err)
+	}
+	if _, err := os.Stat(libPath); err != nil {
+		t.Errorf("Expected library at %s: %v", libPath, err)
+	}
+}
+
+func TestBatchScript(t *testing.T) {
+	compiler := &CompilerInfo{
+		Type: CompilerMSVC,
+		Path: `C:\Program Files\MSVC\cl.exe`,
+		EnvSetup: &CompilerEnvSetup{
+			SetupScript: `C:\Program Files\MSVC\vcvarsall.bat`,
+			SetupArgs:   []string{"x64"},
+		},
+	}
+	args := []string{`/Fe:C:\my project\add.dll`, `C:\my project\add.cpp`}
+
+	want := "@echo off\r\nchcp 65001 >nul\r\n" +
+		`call "C:\Program Files\MSVC\vcvarsall.bat" x64` + "\r\n" +
+		`"C:\Program Files\MSVC\cl.exe" "/Fe:C:\my project\add.dll" "C:\my project\add.cpp"` + "\r\n"
+	if got := batchScript(compiler, args); got != want {
+		t.Errorf("batchScript() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCompileMissingSource(t *testing.T) {
+	tmpDir := t.TempDir()
+	missing := filepath.Join(tmpDir, "missing.cpp")
+	compiler := &CompilerInfo{Type: CompilerGCC, Path: "/nonexistent/g++"}
+
+	_, err := CompileWithOptions(missing, tmpDir, compiler, DefaultCompileOptions())
+	if err == nil {
+		t.Fatal("Expected an error for a missing source file")
+	}
+	if want := "source file not found: " + missing; err.Error() != want {
+		t.Errorf("CompileWithOptions() error = %q, want %q", err, want)
+	}
+	if !errors.Is(err, ErrSourceNotFound) {
+		t.Errorf("Expected error to wrap ErrSourceNotFound, got %v", err)
+	}
+
+	if _, err := CompileWithOptions(tmpDir, tmpDir, compiler, DefaultCompileOptions()); err == nil {
+		t.Error("Expected an error for a directory source")
+	}
+}
+
+func TestCompileError(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerGCC)
+	if err != nil {
+		t.Skipf("GCC not available: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	srcPath := filepath.Join(tmpDir, "broken.cpp")
+	if err := os.WriteFile(srcPath, []byte("extern \"C\" int broken( { return; }\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	_, err = CompileWithOptions(srcPath, tmpDir, compiler, DefaultCompileOptions())
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) {
+		t.Fatalf("Expected a *CompileError, got %v", err)
+	}
+	if !strings.Contains(compileErr.Stderr, "broken.cpp") {
+		t.Errorf("Expected diagnostics naming the source in Stderr, got %q", compileErr.Stderr)
+	}
+	if !strings.HasPrefix(err.Error(), "compilation failed: ") {
+		t.Errorf("Unexpected error message %q", err)
+	}
+}
+
+func TestFailOnWarnings(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+
+	// Calling a deprecated function compiles, with a warning
+	tmpDir := t.TempDir()
+	srcPath := filepath.Join(tmpDir, "deprecated.cpp")
+	source := `[[deprecated("use add")]] static int old_add(int a, int b) { return a + b; }
+extern "C" int add(int a, int b) { return old_add(a, b); }
+`
+	if err := os.WriteFile(srcPath, []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	opts := DefaultCompileOptions()
+	if _, err := CompileWithOptions(srcPath, tmpDir, compiler, opts); err != nil {
+		t.Fatalf("CompileWithOptions() error = %v", err)
+	}
+
+	opts.FailOnWarnings = true
+	_, err = CompileWithOptions(srcPath, tmpDir, compiler, opts)
+	if !errors.Is(err, ErrCompilerWarnings) {
+		t.Fatalf("CompileWithOptions() with FailOnWarnings error = %v, want ErrCompilerWarnings", err)
+	}
+	var compileErr *CompileError
+	if !errors.As(err, &compileErr) || !strings.Contains(compileErr.Stderr, "deprecated") {
+		t.Errorf("Expected the deprecation warning in a *CompileError, got %v", err)
+	}
+}
+
+func TestUnsupportedCompilerError(t *testing.T) {
+	compiler := &CompilerInfo{Type: "tcc", Path: "/usr/bin/tcc"}
+	_, err := Link([]string{"a.o"}, t.TempDir(), fileName, compiler, DefaultCompileOptions())
+	if !errors.Is(err, ErrUnsupportedCompiler) {
+		t.Errorf("Link() error = %v, want ErrUnsupportedCompiler", err)
+	}
+	if want := "unsupported compiler type: tcc"; err.Error() != want {
+		t.Errorf("Link() error = %q, want %q", err, want)
+	}
+}
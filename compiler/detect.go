@@ -1,33 +1,36 @@
 package compiler
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"strings"
 )
 
 const (
 	ErrInvalidCompilerPath = "invalid compiler path: %s"
-	ErrNoCompilerFound     = "no supported compiler found"
-	ErrNoWindowsCompiler   = "no supported compiler found on Windows"
 	ErrUnsupportedOS       = "unsupported operating system: %s"
-	ErrUnsupportedCompiler = "unsupported compiler type: %s"
-	ErrCompilerNotFound    = "compiler not found: %s"
 	ErrVersionCheckFailed  = "failed to get compiler version: %v"
+	ErrCxxProbeFailed      = "compiler %s cannot compile and link C++: %v"
+	ErrSharedLibFailed     = "compiler %s cannot build a shared library; check that its linker and C library development files are installed: %v"
 )
 
 // CompilerType represents the type of C++ compiler
 type CompilerType string
 
 const (
-	CompilerGCC   CompilerType = "gcc"
-	CompilerClang CompilerType = "clang"
-	CompilerMSVC  CompilerType = "msvc"
-	CompilerAuto  CompilerType = "auto"
+	CompilerGCC        CompilerType = "gcc"
+	CompilerClang      CompilerType = "clang"
+	CompilerMSVC       CompilerType = "msvc"
+	CompilerIntel      CompilerType = "intel"
+	CompilerEmscripten CompilerType = "emscripten" // Targets WebAssembly; never auto-detected
+	CompilerAuto       CompilerType = "auto"
 )
 
 // CompilerInfo contains information about the detected compiler
@@ -35,8 +38,9 @@ type CompilerInfo struct {
 	Type         CompilerType
 	Version      string
 	Path         string
-	IncludePaths []string
+	IncludePaths []string // Standard include directories; only MSVC needs them passed when compiling, see CommandIncludePaths
 	EnvSetup     *CompilerEnvSetup
+	MinGW        bool // GCC from MinGW, whose libraries depend on its runtime DLLs unless linked statically
 }
 
 // CompilerEnvSetup contains information about how to set up the compiler's environment
@@ -52,6 +56,11 @@ func DetectCompiler(preferred CompilerType) (*CompilerInfo, error) {
 		return detectSpecificCompiler(preferred)
 	}
 
+	// A compiler named by $CXX or $CC takes precedence, as in other build tools
+	if info, set, err := detectEnvCompiler(); set {
+		return info, err
+	}
+
 	// Auto-detect based on OS
 	switch runtime.GOOS {
 	case "windows":
@@ -63,19 +72,235 @@ func DetectCompiler(preferred CompilerType) (*CompilerInfo, error) {
 	}
 }
 
+// DetectOptions controls DetectCompilerWithOptions
+type DetectOptions struct {
+	Name     string // Compiler binary to use, such as g++-12, instead of detecting one by type
+	Validate bool   // Build a trivial shared library with the detected compiler, failing detection if it cannot
+}
+
+// DetectCompilerWithOptions detects a compiler like DetectCompiler, then
+// validates it if opts.Validate is set. Detection only checks that the
+// compiler runs and compiles C++, so a toolchain missing its linker or C
+// library development files is otherwise found broken only when building.
+func DetectCompilerWithOptions(preferred CompilerType, opts DetectOptions) (*CompilerInfo, error) {
+	var info *CompilerInfo
+	var err error
+	if opts.Name != "" {
+		info, err = DetectCompilerByName(opts.Name)
+	} else {
+		info, err = DetectCompiler(preferred)
+	}
+	if err != nil || !opts.Validate {
+		return info, err
+	}
+	if err := info.Validate(); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Validate checks that the compiler can compile and link a shared library,
+// reporting the compiler's diagnostics if it cannot
+func (c *CompilerInfo) Validate() error {
+	err := c.buildProbe()
+	if err == nil {
+		return nil
+	}
+	var compileErr *CompileError
+	if errors.As(err, &compileErr) && strings.TrimSpace(compileErr.Stderr) != "" {
+		return fmt.Errorf(ErrSharedLibFailed, c.Path, strings.TrimSpace(compileErr.Stderr))
+	}
+	return fmt.Errorf(ErrSharedLibFailed, c.Path, err)
+}
+
+// CommandIncludePaths returns the include directories of the compiler that
+// must be passed when compiling with it. MSVC only searches the INCLUDE
+// environment variable, so outside a Developer Prompt its headers next to
+// cl.exe are passed with /I; GCC-compatible compilers search their system
+// directories themselves.
+func (c *CompilerInfo) CommandIncludePaths() []string {
+	if c.Type == CompilerMSVC {
+		return c.IncludePaths
+	}
+	return nil
+}
+
+// DetectCompilerByName detects the compiler binary name, looked up on PATH
+// unless it is a path, for picking one of several installed versions such as
+// g++-12 or clang++-15. Its type is inferred from the name, ignoring any
+// version suffix, or else from its version output.
+func DetectCompilerByName(name string) (*CompilerInfo, error) {
+	compilerType, known := compilerTypeFromName(name)
+	if known && compilerType == CompilerMSVC {
+		return detectSpecificCompiler(CompilerMSVC)
+	}
+
+	path, err := lookPathAbs(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCompilerNotFound, name)
+	}
+
+	ctx := context.Background()
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return nil, fmt.Errorf(ErrVersionCheckFailed, err)
+	}
+	version := string(output)
+
+	if !known {
+		if compilerType, err = identifyCompiler(version); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	}
+
+	info := &CompilerInfo{Type: compilerType, Version: version, Path: path}
+	info.MinGW = compilerType == CompilerGCC && isMinGW(path, version)
+	if compilerType != CompilerEmscripten {
+		info.IncludePaths = systemIncludePaths(path)
+	}
+	return info, nil
+}
+
+// versionSuffix matches the version appended to versioned compiler names,
+// as in g++-12 or clang++-15.0
+var versionSuffix = regexp.MustCompile(`-[0-9][0-9.]*$`)
+
+// compilerTypeFromName infers the compiler type from a binary name such as
+// g++-12 or x86_64-w64-mingw32-g++. known is false for names that do not
+// identify one, such as c++.
+func compilerTypeFromName(name string) (compilerType CompilerType, known bool) {
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(name), filepath.Ext(name)))
+	base = versionSuffix.ReplaceAllString(base, "")
+	switch {
+	case base == "cl":
+		return CompilerMSVC, true
+	case strings.HasSuffix(base, "em++") || strings.HasSuffix(base, "emcc"):
+		return CompilerEmscripten, true
+	case base == "icpx" || base == "icx" || base == "icpc":
+		return CompilerIntel, true
+	case strings.HasSuffix(base, "clang++") || strings.HasSuffix(base, "clang"):
+		return CompilerClang, true
+	case strings.HasSuffix(base, "g++") || strings.HasSuffix(base, "gcc"):
+		return CompilerGCC, true
+	}
+	return "", false
+}
+
+// DetectCompilers returns every compiler auto-detection can find on this OS, in
+// the same preference order DetectCompiler uses
+func DetectCompilers() []*CompilerInfo {
+	var checks []func() (*CompilerInfo, error)
+	switch runtime.GOOS {
+	case "windows":
+		checks = []func() (*CompilerInfo, error){checkMSVC, checkGCC}
+	case "linux", "darwin":
+		checks = []func() (*CompilerInfo, error){checkClang, checkIntel, checkGCC}
+	}
+
+	var compilers []*CompilerInfo
+	for _, check := range checks {
+		if info, err := check(); err == nil {
+			compilers = append(compilers, info)
+		}
+	}
+	return compilers
+}
+
+// compilerEnvVars are the environment variables that name the compiler to use,
+// in order of preference
+var compilerEnvVars = []string{"CXX", "CC"}
+
+// detectEnvCompiler checks the compiler named by the first of compilerEnvVars
+// that is set. set reports whether any was, in which case err is non-nil if
+// the compiler it names is unusable.
+func detectEnvCompiler() (info *CompilerInfo, set bool, err error) {
+	for _, name := range compilerEnvVars {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			continue
+		}
+		info, err := checkCompilerAt(value)
+		if err != nil {
+			return nil, true, fmt.Errorf("compiler from $%s: %w", name, err)
+		}
+		return info, true, nil
+	}
+	return nil, false, nil
+}
+
+// checkCompilerAt identifies the compiler at path, or found on PATH under that
+// name, from its version output
+func checkCompilerAt(path string) (*CompilerInfo, error) {
+	base := strings.ToLower(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	if base == "cl" {
+		return detectSpecificCompiler(CompilerMSVC)
+	}
+
+	resolved, err := lookPathAbs(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCompilerNotFound, path)
+	}
+	path = resolved
+
+	ctx := context.Background()
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return nil, fmt.Errorf(ErrVersionCheckFailed, err)
+	}
+
+	version := string(output)
+	compilerType, err := identifyCompiler(version)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	info := &CompilerInfo{Type: compilerType, Version: version, Path: path}
+	info.MinGW = compilerType == CompilerGCC && isMinGW(path, version)
+	if compilerType != CompilerEmscripten {
+		// $CC may name a C driver that cannot link C++
+		if err := probeCxx(path); err != nil {
+			return nil, fmt.Errorf(ErrCxxProbeFailed, path, err)
+		}
+		info.IncludePaths = systemIncludePaths(path)
+	}
+	return info, nil
+}
+
+// identifyCompiler determines the compiler type from --version output. Intel
+// and Emscripten are checked first since they are based on Clang.
+func identifyCompiler(version string) (CompilerType, error) {
+	lower := strings.ToLower(version)
+	switch {
+	case strings.Contains(lower, "emcc") || strings.Contains(lower, "emscripten"):
+		return CompilerEmscripten, nil
+	case strings.Contains(lower, "intel"):
+		return CompilerIntel, nil
+	case strings.Contains(lower, "clang"):
+		return CompilerClang, nil
+	case strings.Contains(lower, "gcc") || strings.Contains(lower, "g++") || strings.Contains(lower, "free software foundation"):
+		return CompilerGCC, nil
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(version), "\n")
+	return "", fmt.Errorf("unrecognized compiler %q", first)
+}
+
 func detectSpecificCompiler(compiler CompilerType) (*CompilerInfo, error) {
 	switch compiler {
 	case CompilerGCC:
 		return checkGCC()
 	case CompilerClang:
 		return checkClang()
+	case CompilerIntel:
+		return checkIntel()
+	case CompilerEmscripten:
+		return checkEmscripten()
 	case CompilerMSVC:
 		if runtime.GOOS != "windows" {
 			return nil, fmt.Errorf("MSVC compiler is only supported on Windows")
 		}
 		return checkMSVC()
 	default:
-		return nil, fmt.Errorf(ErrUnsupportedCompiler, compiler)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedCompiler, compiler)
 	}
 }
 
@@ -90,7 +315,7 @@ func detectWindowsCompiler() (*CompilerInfo, error) {
 		return info, nil
 	}
 
-	return nil, errors.New(ErrNoWindowsCompiler)
+	return nil, fmt.Errorf("%w on Windows", ErrNoCompilerFound)
 }
 
 func detectUnixCompiler() (*CompilerInfo, error) {
@@ -99,36 +324,238 @@ func detectUnixCompiler() (*CompilerInfo, error) {
 		return info, nil
 	}
 
+	// Try Intel oneAPI
+	if info, err := checkIntel(); err == nil {
+		return info, nil
+	}
+
 	// Try GCC
 	if info, err := checkGCC(); err == nil {
 		return info, nil
 	}
 
-	return nil, errors.New(ErrNoCompilerFound)
+	return nil, ErrNoCompilerFound
 }
 
 func checkGCC() (*CompilerInfo, error) {
-	// Try different possible GCC names based on OS
+	// Try different possible GCC names based on OS. The C++ driver comes first:
+	// when gcc and g++ are the same binary, the name it is invoked under decides
+	// whether it behaves as a C or C++ driver.
 	compilerNames := []string{"g++", "gcc"}
 	if runtime.GOOS == "windows" {
 		compilerNames = append(compilerNames, "mingw32-g++", "x86_64-w64-mingw32-g++")
 	}
 
+	path, err := findCxxDriver(compilerNames)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, path, "--version")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(ErrVersionCheckFailed, err)
+	}
+
+	return &CompilerInfo{
+		Type:         CompilerGCC,
+		Version:      string(output),
+		Path:         path,
+		IncludePaths: systemIncludePaths(path),
+		MinGW:        isMinGW(path, string(output)),
+	}, nil
+}
+
+// isMinGW reports whether the GCC at path is a MinGW build, recognized by a
+// binary name such as x86_64-w64-mingw32-g++ or by its version output
+func isMinGW(path, version string) bool {
+	return strings.Contains(strings.ToLower(filepath.Base(path)), "mingw") ||
+		strings.Contains(strings.ToLower(version), "mingw")
+}
+
+// lookPathAbs finds name on PATH like exec.LookPath, but resolves matches from
+// relative PATH entries (which LookPath reports as exec.ErrDot) to absolute paths
+func lookPathAbs(name string) (string, error) {
+	path, err := exec.LookPath(name)
+	if err != nil && !errors.Is(err, exec.ErrDot) {
+		return "", err
+	}
+
+	if !filepath.IsAbs(path) {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return "", fmt.Errorf(ErrInvalidCompilerPath, path)
+		}
+		if _, err := os.Stat(absPath); err != nil {
+			return "", fmt.Errorf(ErrInvalidCompilerPath, path)
+		}
+		path = absPath
+	}
+
+	return path, nil
+}
+
+// findCxxDriver returns the first of names found on PATH that can compile C++.
+// Names resolving to the same real binary are only probed once, under the
+// first (preferred) name.
+func findCxxDriver(names []string) (string, error) {
+	seen := make(map[string]bool)
+	var probeErr error
+
+	for _, name := range names {
+		path, err := lookPathAbs(name)
+		if err != nil {
+			continue
+		}
+
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			realPath = path
+		}
+		if seen[realPath] {
+			continue
+		}
+		seen[realPath] = true
+
+		if err := probeCxx(path); err != nil {
+			probeErr = fmt.Errorf(ErrCxxProbeFailed, path, err)
+			continue
+		}
+		return path, nil
+	}
+
+	if probeErr != nil {
+		return "", probeErr
+	}
+	return "", fmt.Errorf("%w: %s", ErrCompilerNotFound, names[0])
+}
+
+// probeCxx checks that the compiler at path compiles C++ and links the C++
+// runtime. The source is compiled by its .cpp extension, as builds are, and
+// linked into a program using operator new and std::string, which a C driver
+// such as gcc leaves unresolved since it does not link libstdc++.
+func probeCxx(path string) error {
+	tmpDir, err := os.MkdirTemp("", "cp2p-probe")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	srcPath := filepath.Join(tmpDir, "probe.cpp")
+	src := "#include <string>\nint main() { std::string* s = new std::string(\"cp2p\"); int n = (int)s->size(); delete s; return n - 4; }\n"
+	if err := os.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, path, "-o", filepath.Join(tmpDir, "probe"), srcPath)
+	return cmd.Run()
+}
+
+// systemIncludePaths returns the system include search directories of a
+// GCC-compatible compiler, from the detection cache if the binary is unchanged
+// since they were queried
+func systemIncludePaths(path string) []string {
+	if paths, ok := cachedIncludePaths(path); ok {
+		return paths
+	}
+	paths := queryIncludePaths(path)
+	if paths != nil {
+		cacheIncludePaths(path, paths)
+	}
+	return paths
+}
+
+// queryIncludePaths asks a GCC-compatible compiler for its system include
+// search directories, as printed by "-E -x c++ - -v". It returns nil if the
+// compiler cannot be queried.
+func queryIncludePaths(path string) []string {
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, path, "-E", "-x", "c++", "-", "-v")
+	// Stdin is left nil so the compiler reads an empty file from the null device
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil
+	}
+	return parseIncludeSearchList(stderr.String())
+}
+
+// parseIncludeSearchList extracts the directories listed between
+// "#include <...> search starts here:" and "End of search list." in verbose
+// preprocessor output
+func parseIncludeSearchList(output string) []string {
+	var paths []string
+	inList := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimRight(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "#include <...> search starts here:"):
+			inList = true
+		case strings.HasPrefix(line, "End of search list."):
+			return paths
+		case inList:
+			dir := strings.TrimSpace(line)
+			// Clang on macOS marks framework directories, which are not include dirs
+			if dir == "" || strings.HasSuffix(dir, "(framework directory)") {
+				continue
+			}
+			paths = append(paths, filepath.Clean(dir))
+		}
+	}
+	return paths
+}
+
+func checkClang() (*CompilerInfo, error) {
+	// Try different possible Clang names based on OS
+	compilerNames := []string{"clang++", "clang"}
+	if runtime.GOOS == "windows" {
+		compilerNames = append(compilerNames, "llvm-clang++")
+	}
+
 	var path string
 	var err error
 	for _, name := range compilerNames {
-		path, err = exec.LookPath(name)
+		path, err = lookPathAbs(name)
 		if err == nil {
 			break
 		}
 	}
 	if err != nil {
-		return nil, fmt.Errorf(ErrCompilerNotFound, "g++")
+		return nil, fmt.Errorf("%w: %s", ErrCompilerNotFound, "clang++")
 	}
 
-	// Validate path is safe
-	if !filepath.IsAbs(path) {
-		return nil, fmt.Errorf(ErrInvalidCompilerPath, path)
+	ctx := context.Background()
+	cmd := exec.CommandContext(ctx, path, "--version")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf(ErrVersionCheckFailed, err)
+	}
+
+	return &CompilerInfo{
+		Type:         CompilerClang,
+		Version:      string(output),
+		Path:         path,
+		IncludePaths: systemIncludePaths(path),
+	}, nil
+}
+
+func checkIntel() (*CompilerInfo, error) {
+	// icpx is the oneAPI C++ driver; icx also compiles C++ and icpc is the
+	// legacy classic compiler
+	compilerNames := []string{"icpx", "icx", "icpc"}
+
+	var path string
+	var err error
+	for _, name := range compilerNames {
+		path, err = lookPathAbs(name)
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrCompilerNotFound, "icpx")
 	}
 
 	ctx := context.Background()
@@ -139,34 +566,30 @@ func checkGCC() (*CompilerInfo, error) {
 	}
 
 	return &CompilerInfo{
-		Type:    CompilerGCC,
-		Version: string(output),
-		Path:    path,
+		Type:         CompilerIntel,
+		Version:      string(output),
+		Path:         path,
+		IncludePaths: systemIncludePaths(path),
 	}, nil
 }
 
-func checkClang() (*CompilerInfo, error) {
-	// Try different possible Clang names based on OS
-	compilerNames := []string{"clang++", "clang"}
+func checkEmscripten() (*CompilerInfo, error) {
+	compilerNames := []string{"em++", "emcc"}
 	if runtime.GOOS == "windows" {
-		compilerNames = append(compilerNames, "llvm-clang++")
+		// emsdk ships batch wrappers on Windows
+		compilerNames = []string{"em++.bat", "emcc.bat"}
 	}
 
 	var path string
 	var err error
 	for _, name := range compilerNames {
-		path, err = exec.LookPath(name)
+		path, err = lookPathAbs(name)
 		if err == nil {
 			break
 		}
 	}
 	if err != nil {
-		return nil, fmt.Errorf(ErrCompilerNotFound, "clang++")
-	}
-
-	// Validate path is safe
-	if !filepath.IsAbs(path) {
-		return nil, fmt.Errorf(ErrInvalidCompilerPath, path)
+		return nil, fmt.Errorf("%w: %s", ErrCompilerNotFound, "em++")
 	}
 
 	ctx := context.Background()
@@ -177,7 +600,7 @@ func checkClang() (*CompilerInfo, error) {
 	}
 
 	return &CompilerInfo{
-		Type:    CompilerClang,
+		Type:    CompilerEmscripten,
 		Version: string(output),
 		Path:    path,
 	}, nil
@@ -185,14 +608,9 @@ func checkClang() (*CompilerInfo, error) {
 
 func checkMSVC() (*CompilerInfo, error) {
 	// First check if cl.exe is available
-	path, err := exec.LookPath("cl.exe")
+	path, err := lookPathAbs("cl.exe")
 	if err != nil {
-		return nil, fmt.Errorf(ErrCompilerNotFound, "cl.exe")
-	}
-
-	// Validate path is safe
-	if !filepath.IsAbs(path) {
-		return nil, fmt.Errorf(ErrInvalidCompilerPath, path)
+		return nil, fmt.Errorf("%w: %s", ErrCompilerNotFound, "cl.exe")
 	}
 
 	// Get the version info from cl.exe
@@ -1,10 +1,14 @@
 package compiler
 
 import (
+	"errors"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -15,6 +19,12 @@ const (
 
 // mockCompiler creates a mock compiler executable that returns a predefined version string
 func mockCompiler(t *testing.T, dir, name, version string) string {
+	return mockCompilerWithExit(t, dir, name, version, 0)
+}
+
+// mockCompilerWithExit creates a mock compiler that prints version for --version
+// and exits with compileExit for any other invocation
+func mockCompilerWithExit(t *testing.T, dir, name, version string, compileExit int) string {
 	path := filepath.Join(dir, name)
 
 	// Create a Go program that will be compiled into our mock compiler
@@ -27,7 +37,10 @@ import (
 
 func main() {
 	fmt.Println("` + version + `")
-	os.Exit(0)
+	if len(os.Args) > 1 && os.Args[1] == "--version" {
+		os.Exit(0)
+	}
+	os.Exit(` + strconv.Itoa(compileExit) + `)
 }`)
 
 	// Write the Go source
@@ -318,3 +331,412 @@ func TestIncludePathDetection(t *testing.T) {
 		t.Error("Expected to find include directory in parent directory")
 	}
 }
+
+func TestCheckGCCPrefersWorkingCxxDriver(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Mock compiler names are Unix-specific")
+	}
+
+	tmpDir := t.TempDir()
+	mockCompilerWithExit(t, tmpDir, "g++", "g++ (GCC) 9.4.0", 1)
+	gccPath := mockCompiler(t, tmpDir, "gcc", "gcc (GCC) 9.4.0")
+
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", tmpDir)
+
+	info, err := checkGCC()
+	if err != nil {
+		t.Fatalf("checkGCC() error = %v", err)
+	}
+	if info.Path != gccPath {
+		t.Errorf("Expected %s to be chosen, got %s", gccPath, info.Path)
+	}
+}
+
+func TestProbeCxx(t *testing.T) {
+	gxx, err := exec.LookPath("g++")
+	if err != nil {
+		t.Skip("g++ not available")
+	}
+	if err := probeCxx(gxx); err != nil {
+		t.Errorf("probeCxx(%s) error = %v", gxx, err)
+	}
+
+	// gcc compiles C++ by the extension but does not link the C++ runtime
+	gcc, err := exec.LookPath("gcc")
+	if err != nil {
+		t.Skip("gcc not available")
+	}
+	if real, _ := filepath.EvalSymlinks(gcc); strings.Contains(filepath.Base(real), "++") {
+		t.Skip("gcc is a C++ driver")
+	}
+	if err := probeCxx(gcc); err == nil {
+		t.Errorf("probeCxx(%s) succeeded for a C driver", gcc)
+	}
+}
+
+func TestCheckGCCDeduplicatesSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Symlink test is Unix-specific")
+	}
+
+	tmpDir := t.TempDir()
+	gxxPath := mockCompiler(t, tmpDir, "g++", "g++ (GCC) 9.4.0")
+	if err := os.Symlink(gxxPath, filepath.Join(tmpDir, "gcc")); err != nil {
+		t.Skipf("Cannot create symlink: %v", err)
+	}
+
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", tmpDir)
+
+	info, err := checkGCC()
+	if err != nil {
+		t.Fatalf("checkGCC() error = %v", err)
+	}
+	if info.Path != gxxPath {
+		t.Errorf("Expected C++ driver name %s, got %s", gxxPath, info.Path)
+	}
+}
+
+func TestDetectCompilerRelativePath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Mock compiler names are Unix-specific")
+	}
+
+	tmpDir := t.TempDir()
+	binDir := filepath.Join(tmpDir, "bin")
+	if err := os.Mkdir(binDir, 0755); err != nil {
+		t.Fatalf("Failed to create bin directory: %v", err)
+	}
+	mockCompiler(t, binDir, "clang++", "clang version 12.0.0")
+
+	// Put only a relative entry on PATH
+	t.Chdir(tmpDir)
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", "bin")
+
+	info, err := checkClang()
+	if err != nil {
+		t.Fatalf("checkClang() error = %v", err)
+	}
+	if !filepath.IsAbs(info.Path) {
+		t.Errorf(errExpectedAbsPath, info.Path)
+	}
+	// The temp dir may itself sit behind a symlink (e.g. /var on macOS)
+	want, _ := filepath.EvalSymlinks(filepath.Join(binDir, "clang++"))
+	got, _ := filepath.EvalSymlinks(info.Path)
+	if got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestDetectIntelCompiler(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Intel auto-detection test is Unix-specific")
+	}
+
+	tmpDir := t.TempDir()
+	mockCompiler(t, tmpDir, "icpx", "Intel(R) oneAPI DPC++/C++ Compiler 2024.0.0 (2024.0.0.20231017)")
+
+	// Only the mock is on PATH, so Clang and GCC are not found first
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", tmpDir)
+
+	info, err := DetectCompiler(CompilerAuto)
+	if err != nil {
+		t.Fatalf("DetectCompiler() error = %v", err)
+	}
+	if info.Type != CompilerIntel {
+		t.Errorf("Expected compiler type %s, got %s", CompilerIntel, info.Type)
+	}
+	if !filepath.IsAbs(info.Path) {
+		t.Errorf(errExpectedAbsPath, info.Path)
+	}
+	if info.Version == "" {
+		t.Error(errExpectedVersion)
+	}
+
+	args := buildCompileCommand("test.cpp", "libtest.so", info, DefaultCompileOptions())
+	if !slices.Contains(args, "-fPIC") {
+		t.Errorf("Expected Clang-style flags for Intel, got %v", args)
+	}
+}
+
+func TestParseIncludeSearchList(t *testing.T) {
+	output := `ignoring nonexistent directory "/usr/local/include/x86_64-linux-gnu"
+#include "..." search starts here:
+#include <...> search starts here:
+ /usr/include/c++/12
+ /usr/lib/gcc/x86_64-linux-gnu/12/include
+ /usr/include
+ /System/Library/Frameworks (framework directory)
+End of search list.
+# 0 "<stdin>"
+`
+
+	got := parseIncludeSearchList(output)
+	want := []string{
+		filepath.Clean("/usr/include/c++/12"),
+		filepath.Clean("/usr/lib/gcc/x86_64-linux-gnu/12/include"),
+		filepath.Clean("/usr/include"),
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("parseIncludeSearchList() = %v, want %v", got, want)
+	}
+}
+
+func TestCheckGCCIncludePaths(t *testing.T) {
+	if _, err := exec.LookPath("g++"); err != nil {
+		t.Skip("g++ not available")
+	}
+
+	info, err := checkGCC()
+	if err != nil {
+		t.Skipf("Skipping GCC include path test: %v", err)
+	}
+	if len(info.IncludePaths) == 0 {
+		t.Error("Expected GCC system include paths to be detected")
+	}
+}
+
+func TestDetectCompilerFromEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Mock compiler names are Unix-specific")
+	}
+
+	tmpDir := t.TempDir()
+	mockCompiler(t, tmpDir, "g++", "g++ (GCC) 9.4.0")
+	customPath := mockCompiler(t, tmpDir, "my-clang++", "clang version 17.0.1")
+
+	origPath, origCXX, origCC := os.Getenv("PATH"), os.Getenv("CXX"), os.Getenv("CC")
+	defer func() {
+		os.Setenv("PATH", origPath)
+		os.Setenv("CXX", origCXX)
+		os.Setenv("CC", origCC)
+	}()
+	os.Setenv("PATH", tmpDir)
+	os.Setenv("CC", "")
+
+	tests := []struct {
+		name     string
+		cxx      string
+		cc       string
+		wantType CompilerType
+		wantPath string
+		wantErr  bool
+	}{
+		{name: "CXX path", cxx: customPath, wantType: CompilerClang, wantPath: customPath},
+		{name: "CXX on PATH", cxx: "my-clang++", wantType: CompilerClang, wantPath: customPath},
+		{name: "CXX before CC", cxx: "my-clang++", cc: "g++", wantType: CompilerClang, wantPath: customPath},
+		{name: "CC", cc: "g++", wantType: CompilerGCC, wantPath: filepath.Join(tmpDir, "g++")},
+		{name: "Missing compiler", cxx: "no-such-compiler", wantErr: true},
+		{name: "Unset", wantType: CompilerGCC, wantPath: filepath.Join(tmpDir, "g++")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("CXX", tt.cxx)
+			os.Setenv("CC", tt.cc)
+
+			info, err := DetectCompiler(CompilerAuto)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectCompiler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if info.Type != tt.wantType || info.Path != tt.wantPath {
+				t.Errorf("DetectCompiler() = %s at %s, want %s at %s", info.Type, info.Path, tt.wantType, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestIdentifyCompiler(t *testing.T) {
+	tests := []struct {
+		version string
+		want    CompilerType
+	}{
+		{"g++ (Ubuntu 12.3.0-1ubuntu1) 12.3.0\nCopyright (C) 2022 Free Software Foundation, Inc.", CompilerGCC},
+		{"Apple clang version 15.0.0 (clang-1500.1.0.2.5)", CompilerClang},
+		{"Intel(R) oneAPI DPC++/C++ Compiler 2024.0.0 (2024.0.0.20231017)", CompilerIntel},
+		{"emcc (Emscripten gcc/clang-like replacement + linker emulating GNU ld) 3.1.50", CompilerEmscripten},
+	}
+	for _, tt := range tests {
+		if got, err := identifyCompiler(tt.version); err != nil || got != tt.want {
+			t.Errorf("identifyCompiler(%q) = %s, %v, want %s", tt.version, got, err, tt.want)
+		}
+	}
+	if _, err := identifyCompiler("tcc version 0.9.27"); err == nil {
+		t.Error("identifyCompiler() should reject an unknown compiler")
+	}
+}
+
+func TestIsMinGW(t *testing.T) {
+	tests := []struct {
+		path    string
+		version string
+		want    bool
+	}{
+		{"/usr/bin/x86_64-w64-mingw32-g++", "x86_64-w64-mingw32-g++ (GCC) 12-win32", true},
+		{`C:\mingw64\bin\g++.exe`, "g++.exe (MinGW-W64 x86_64-ucrt-posix-seh, built by Brecht Sanders) 13.2.0", true},
+		{`C:\tools\mingw32-g++.exe`, "mingw32-g++.exe (GCC) 9.2.0", true},
+		{"/usr/bin/g++", "g++ (Ubuntu 12.3.0-1ubuntu1) 12.3.0", false},
+	}
+	for _, tt := range tests {
+		if got := isMinGW(tt.path, tt.version); got != tt.want {
+			t.Errorf("isMinGW(%q, %q) = %v, want %v", tt.path, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestCompilerNotFoundErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Detection order test is Unix-specific")
+	}
+
+	origPath, origCXX, origCC := os.Getenv("PATH"), os.Getenv("CXX"), os.Getenv("CC")
+	defer func() {
+		os.Setenv("PATH", origPath)
+		os.Setenv("CXX", origCXX)
+		os.Setenv("CC", origCC)
+	}()
+	os.Setenv("PATH", t.TempDir())
+	os.Setenv("CXX", "")
+	os.Setenv("CC", "")
+
+	_, err := DetectCompiler(CompilerClang)
+	if !errors.Is(err, ErrCompilerNotFound) {
+		t.Errorf("DetectCompiler(clang) error = %v, want ErrCompilerNotFound", err)
+	}
+	if want := "compiler not found: clang++"; err == nil || err.Error() != want {
+		t.Errorf("DetectCompiler(clang) error = %v, want %q", err, want)
+	}
+
+	if _, err := DetectCompiler(CompilerAuto); !errors.Is(err, ErrNoCompilerFound) {
+		t.Errorf("DetectCompiler(auto) error = %v, want ErrNoCompilerFound", err)
+	}
+
+	os.Setenv("CXX", "no-such-compiler")
+	if _, err := DetectCompiler(CompilerAuto); !errors.Is(err, ErrCompilerNotFound) {
+		t.Errorf("DetectCompiler(auto) with $CXX error = %v, want ErrCompilerNotFound", err)
+	}
+}
+
+// mockNoLinkCompiler creates a mock compiler that prints version for
+// --version and compiles objects, but fails to link shared libraries
+func mockNoLinkCompiler(t *testing.T, dir, name, version string) string {
+	path := filepath.Join(dir, name)
+	content := []byte(`package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+)
+
+func main() {
+	fmt.Println("` + version + `")
+	if slices.Contains(os.Args[1:], "-shared") {
+		fmt.Fprintln(os.Stderr, "ld: cannot find crti.o: No such file or directory")
+		os.Exit(1)
+	}
+}`)
+	srcPath := path + ".go"
+	if err := os.WriteFile(srcPath, content, 0644); err != nil {
+		t.Fatalf("Failed to create mock compiler source: %v", err)
+	}
+	if err := exec.Command("go", "build", "-o", path, srcPath).Run(); err != nil {
+		t.Fatalf("Failed to build mock compiler: %v", err)
+	}
+	return path
+}
+
+func TestDetectCompilerValidate(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Mock compiler names are Unix-specific")
+	}
+
+	origCXX := os.Getenv("CXX")
+	defer os.Setenv("CXX", origCXX)
+	os.Setenv("CXX", mockNoLinkCompiler(t, t.TempDir(), "clang++", "clang version 12.0.0"))
+
+	// Detection alone accepts the compiler
+	if _, err := DetectCompilerWithOptions(CompilerAuto, DetectOptions{}); err != nil {
+		t.Fatalf("DetectCompilerWithOptions() error = %v", err)
+	}
+
+	_, err := DetectCompilerWithOptions(CompilerAuto, DetectOptions{Validate: true})
+	if err == nil {
+		t.Fatal("DetectCompilerWithOptions() with Validate succeeded for a compiler that cannot link")
+	}
+	for _, expected := range []string{"cannot build a shared library", "cannot find crti.o"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Validate error %q does not contain %q", err, expected)
+		}
+	}
+}
+
+func TestDetectCompilerByName(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Mock compiler names are Unix-specific")
+	}
+
+	dir := t.TempDir()
+	mockCompiler(t, dir, "g++-12", "g++-12 (Ubuntu 12.3.0-1ubuntu1) 12.3.0")
+	origPath := os.Getenv("PATH")
+	defer os.Setenv("PATH", origPath)
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+origPath)
+
+	info, err := DetectCompilerByName("g++-12")
+	if err != nil {
+		t.Fatalf("DetectCompilerByName() error = %v", err)
+	}
+	if info.Type != CompilerGCC {
+		t.Errorf("DetectCompilerByName() type = %s, want %s", info.Type, CompilerGCC)
+	}
+	if want := filepath.Join(dir, "g++-12"); info.Path != want {
+		t.Errorf("DetectCompilerByName() path = %s, want %s", info.Path, want)
+	}
+
+	if _, err := DetectCompilerByName("g++-999"); !errors.Is(err, ErrCompilerNotFound) {
+		t.Errorf("DetectCompilerByName() for a missing binary error = %v, want %v", err, ErrCompilerNotFound)
+	}
+}
+
+func TestCompilerTypeFromName(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  CompilerType
+		known bool
+	}{
+		{"g++-12", CompilerGCC, true},
+		{"/usr/bin/gcc-13", CompilerGCC, true},
+		{"x86_64-w64-mingw32-g++", CompilerGCC, true},
+		{"clang++-15", CompilerClang, true},
+		{"clang-17.0", CompilerClang, true},
+		{"icpx", CompilerIntel, true},
+		{"em++", CompilerEmscripten, true},
+		{"cl.exe", CompilerMSVC, true},
+		{"c++", "", false},
+	}
+	for _, tt := range tests {
+		got, known := compilerTypeFromName(tt.name)
+		if got != tt.want || known != tt.known {
+			t.Errorf("compilerTypeFromName(%q) = %q, %v, want %q, %v", tt.name, got, known, tt.want, tt.known)
+		}
+	}
+}
+
+func TestValidateRealCompiler(t *testing.T) {
+	if _, err := DetectCompiler(CompilerAuto); err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+	if _, err := DetectCompilerWithOptions(CompilerAuto, DetectOptions{Validate: true}); err != nil {
+		t.Errorf("DetectCompilerWithOptions() with Validate error = %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package compiler
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Diagnostic severities
+const (
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+	SeverityNote    = "note"
+)
+
+// Diagnostic is a message a compiler reported about a source location
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int    // 0 if the compiler did not report one
+	Severity string // One of the Severity* constants
+	Message  string
+}
+
+var (
+	// gccDiagnostic matches GCC, Clang and Emscripten diagnostics, e.g.
+	// "math.cpp:3:5: warning: unused variable 'x' [-Wunused-variable]"
+	gccDiagnostic = regexp.MustCompile(`^(.+?):(\d+):(?:(\d+):)? (?:fatal )?(warning|error|note): (.*)$`)
+	// msvcDiagnostic matches MSVC diagnostics, e.g.
+	// "math.cpp(3): warning C4996: 'strcpy': This function may be unsafe"
+	msvcDiagnostic = regexp.MustCompile(`^(.+?)\((\d+)(?:,(\d+))?\) ?: (?:fatal )?(warning|error) (\w+: .*)$`)
+)
+
+// ParseDiagnostics returns the diagnostics in a compiler's stderr, in the
+// order reported. Lines that are not diagnostics, such as source excerpts,
+// are skipped.
+func ParseDiagnostics(stderr string) []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimRight(line, "\r")
+		match := gccDiagnostic.FindStringSubmatch(line)
+		if match == nil {
+			match = msvcDiagnostic.FindStringSubmatch(line)
+		}
+		if match == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(match[2])
+		column, _ := strconv.Atoi(match[3])
+		diagnostics = append(diagnostics, Diagnostic{
+			File:     match[1],
+			Line:     lineNo,
+			Column:   column,
+			Severity: match[4],
+			Message:  match[5],
+		})
+	}
+	return diagnostics
+}
+
+// Warnings returns the warnings among diagnostics
+func Warnings(diagnostics []Diagnostic) []Diagnostic {
+	var warnings []Diagnostic
+	for _, d := range diagnostics {
+		if d.Severity == SeverityWarning {
+			warnings = append(warnings, d)
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,37 @@
+package compiler
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	stderr := `math.cpp: In function 'int add(int, int)':
+math.cpp:3:9: warning: unused variable 'x' [-Wunused-variable]
+    3 |     int x;
+      |         ^
+math.cpp:5:12: error: 'y' was not declared in this scope
+math.cpp:1:5: note: declared here
+math.cpp:7: warning: no column
+C:\src\math.cpp(4): warning C4996: 'strcpy': This function or variable may be unsafe.
+C:\src\math.cpp(9,3): error C2065: 'z': undeclared identifier
+math.cpp:2:1: fatal error: missing.h: No such file or directory
+`
+	want := []Diagnostic{
+		{File: "math.cpp", Line: 3, Column: 9, Severity: SeverityWarning, Message: "unused variable 'x' [-Wunused-variable]"},
+		{File: "math.cpp", Line: 5, Column: 12, Severity: SeverityError, Message: "'y' was not declared in this scope"},
+		{File: "math.cpp", Line: 1, Column: 5, Severity: SeverityNote, Message: "declared here"},
+		{File: "math.cpp", Line: 7, Severity: SeverityWarning, Message: "no column"},
+		{File: `C:\src\math.cpp`, Line: 4, Severity: SeverityWarning, Message: "C4996: 'strcpy': This function or variable may be unsafe."},
+		{File: `C:\src\math.cpp`, Line: 9, Column: 3, Severity: SeverityError, Message: "C2065: 'z': undeclared identifier"},
+		{File: "math.cpp", Line: 2, Column: 1, Severity: SeverityError, Message: "missing.h: No such file or directory"},
+	}
+
+	got := ParseDiagnostics(stderr)
+	if !slices.Equal(got, want) {
+		t.Fatalf("ParseDiagnostics() =\n%+v\nwant\n%+v", got, want)
+	}
+	if warnings := Warnings(got); len(warnings) != 3 {
+		t.Errorf("Warnings() = %+v, want 3", warnings)
+	}
+}
@@ -0,0 +1,30 @@
+package compiler
+
+import "errors"
+
+// Sentinel errors for use with errors.Is. Errors returned by this package
+// wrap them with details, such as the compiler or file involved.
+var (
+	ErrCompilerNotFound    = errors.New("compiler not found")
+	ErrNoCompilerFound     = errors.New("no supported compiler found")
+	ErrUnsupportedCompiler = errors.New("unsupported compiler type")
+	ErrSourceNotFound      = errors.New("source file not found")
+	ErrNoSymbolTool        = errors.New("no symbol listing tool found")
+	ErrCMakeNotFound       = errors.New("cmake not found")
+	ErrCompilerWarnings    = errors.New("compiler reported warnings")
+)
+
+// CompileError reports a compiler run that failed, with what it wrote to
+// stderr. Use errors.As to retrieve it.
+type CompileError struct {
+	Err    error  // Error from running the compiler, usually an *exec.ExitError
+	Stderr string // The compiler's diagnostics
+}
+
+func (e *CompileError) Error() string {
+	return "compilation failed: " + e.Err.Error()
+}
+
+func (e *CompileError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,86 @@
+package compiler
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// flagKey identifies a SupportsFlag result
+type flagKey struct {
+	path string
+	flag string
+}
+
+// flagSupport caches SupportsFlag results, which need a compiler run each
+var flagSupport sync.Map // flagKey -> bool
+
+// SupportsFlag reports whether the compiler accepts flag, by building an
+// empty shared library with it, so option builders can leave out flags such
+// as -flto or -fsanitize=address where they are unsupported. Warnings count
+// as failures, since compilers only warn about some unknown flags. Results
+// are cached per compiler path and flag.
+func (c *CompilerInfo) SupportsFlag(flag string) bool {
+	key := flagKey{c.Path, flag}
+	if supported, ok := flagSupport.Load(key); ok {
+		return supported.(bool)
+	}
+	supported := c.probeFlag(flag) == nil
+	flagSupport.Store(key, supported)
+	return supported
+}
+
+// probeFlag builds an empty shared library with flag, returning an error if
+// the compiler rejects or warns about it
+func (c *CompilerInfo) probeFlag(flag string) error {
+	if c.Type == CompilerMSVC {
+		return c.buildProbe("/WX", flag)
+	}
+	return c.buildProbe("-Werror", flag)
+}
+
+// buildProbe compiles and links a shared library exporting one trivial
+// function, with flags added, in a temporary directory. Failures are
+// reported as a *CompileError with the compiler's diagnostics.
+func (c *CompilerInfo) buildProbe(flags ...string) error {
+	tmpDir, err := os.MkdirTemp("", "cp2p-probe")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	srcPath := filepath.Join(tmpDir, "probe.cpp")
+	src := `extern "C" int cp2p_probe(void) { return 0; }` + "\n"
+	if err := os.WriteFile(srcPath, []byte(src), 0644); err != nil {
+		return err
+	}
+	outputPath := filepath.Join(tmpDir, generateLibraryName(srcPath, c))
+
+	var args []string
+	switch c.Type {
+	case CompilerMSVC:
+		args = append([]string{"/nologo", "/LD"}, flags...)
+		args = append(args, "/Fe:"+outputPath, "/Fo:"+msvcIntermediates(outputPath)[0], srcPath)
+	case CompilerEmscripten:
+		args = append(slices.Clone(flags), "-o", outputPath, srcPath)
+	default:
+		args = append([]string{"-shared", "-fPIC"}, flags...)
+		args = append(args, "-o", outputPath, srcPath)
+	}
+
+	// MSVC needs its environment set up by a batch script
+	if c.EnvSetup != nil {
+		return runCompiler(c, outputPath, args, DefaultCompileOptions())
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(context.Background(), c.Path, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return &CompileError{Err: err, Stderr: stderr.String()}
+	}
+	return nil
+}
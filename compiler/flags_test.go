@@ -0,0 +1,81 @@
+package compiler
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mockFlagCompiler creates a mock compiler that fails when given any of the
+// rejected flags and appends a line to log for every run
+func mockFlagCompiler(t *testing.T, dir, log string, rejected ...string) string {
+	path := filepath.Join(dir, "mockcc")
+	content := []byte(`package main
+
+import (
+	"os"
+	"slices"
+)
+
+func main() {
+	f, _ := os.OpenFile(` + "`" + log + "`" + `, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	f.WriteString("run\n")
+	f.Close()
+	for _, flag := range []string{"` + strings.Join(rejected, `", "`) + `"} {
+		if slices.Contains(os.Args[1:], flag) {
+			os.Exit(1)
+		}
+	}
+}`)
+	srcPath := path + ".go"
+	if err := os.WriteFile(srcPath, content, 0644); err != nil {
+		t.Fatalf("Failed to create mock compiler source: %v", err)
+	}
+	if err := exec.Command("go", "build", "-o", path, srcPath).Run(); err != nil {
+		t.Fatalf("Failed to build mock compiler: %v", err)
+	}
+	return path
+}
+
+func TestSupportsFlag(t *testing.T) {
+	tmpDir := t.TempDir()
+	log := filepath.Join(tmpDir, "runs.log")
+	cc := &CompilerInfo{Type: CompilerGCC, Path: mockFlagCompiler(t, tmpDir, log, "-fsanitize=address")}
+
+	if !cc.SupportsFlag("-flto") {
+		t.Error("SupportsFlag(-flto) = false, want true")
+	}
+	if cc.SupportsFlag("-fsanitize=address") {
+		t.Error("SupportsFlag(-fsanitize=address) = true, want false")
+	}
+
+	// Repeated queries are answered from the cache
+	cc.SupportsFlag("-flto")
+	cc.SupportsFlag("-fsanitize=address")
+	runs, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("Failed to read run log: %v", err)
+	}
+	if n := strings.Count(string(runs), "run\n"); n != 2 {
+		t.Errorf("Mock compiler ran %d times, want 2", n)
+	}
+}
+
+func TestSupportsFlagRealCompiler(t *testing.T) {
+	cc, err := DetectCompiler(CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+	if cc.Type == CompilerMSVC {
+		t.Skip("GCC-style flags")
+	}
+
+	if !cc.SupportsFlag("-O2") {
+		t.Error("SupportsFlag(-O2) = false, want true")
+	}
+	if cc.SupportsFlag("-fcp2p-no-such-flag") {
+		t.Error("SupportsFlag(-fcp2p-no-such-flag) = true, want false")
+	}
+}
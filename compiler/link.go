@@ -0,0 +1,140 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cp2p/util"
+)
+
+// CompileObject compiles sourceFile into an object file in outputDir and
+// returns its path. An object newer than its source is reused as is, so
+// after editing one source of a multi-source library only that source is
+// recompiled before calling Link. Changes to included headers or to opts are
+// not detected; remove the object to force a rebuild.
+func CompileObject(sourceFile, outputDir string, compiler *CompilerInfo, opts *CompileOptions) (string, error) {
+	if err := checkSourceFile(sourceFile); err != nil {
+		return "", err
+	}
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if err := util.EnsureWritableDir(outputDir); err != nil {
+		return "", fmt.Errorf("failed to prepare output directory: %w", err)
+	}
+
+	objectPath := filepath.Join(outputDir, objectFileName(sourceFile, compiler))
+	if isUpToDate(objectPath, sourceFile) {
+		return objectPath, nil
+	}
+
+	var args []string
+	switch compiler.Type {
+	case CompilerGCC, CompilerClang, CompilerIntel:
+		args = append([]string{"-c", "-fPIC", "-o", objectPath}, gccCompileFlags(opts)...)
+	case CompilerEmscripten:
+		args = append([]string{"-c", "-o", objectPath}, gccCompileFlags(opts)...)
+	case CompilerMSVC:
+		args = append([]string{"/c", runtimeFlags[opts.RuntimeLibrary], "/Fo:" + objectPath}, msvcCompileFlags(opts)...)
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedCompiler, compiler.Type)
+	}
+	args = append(args, opts.ExtraFlags...)
+	args = append(args, sourceFile)
+
+	if err := runCompiler(compiler, objectPath, args, opts); err != nil {
+		return "", err
+	}
+	return objectPath, nil
+}
+
+// Link links objects into a shared library in outputDir, named after name the
+// way a library compiled from a source of that name would be, and returns its
+// path. It always re-links, which is cheap next to compiling.
+func Link(objects []string, outputDir, name string, compiler *CompilerInfo, opts *CompileOptions) (string, error) {
+	if len(objects) == 0 {
+		return "", errors.New("no object files to link")
+	}
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	if err := util.EnsureWritableDir(outputDir); err != nil {
+		return "", fmt.Errorf("failed to prepare output directory: %w", err)
+	}
+
+	outputPath := filepath.Join(outputDir, generateLibraryName(name, compiler))
+
+	var args []string
+	var intermediates []string
+	switch compiler.Type {
+	case CompilerGCC, CompilerClang, CompilerIntel:
+		args = append([]string{"-shared", "-o", outputPath}, staticRuntimeFlags(compiler)...)
+		args = append(args, objects...)
+		for _, lib := range opts.LibraryPaths {
+			args = append(args, "-L"+lib)
+		}
+		args = append(args, opts.LinkerFlags...)
+		// Libraries must follow the objects that reference them
+		for _, lib := range opts.Libraries {
+			args = append(args, "-l"+lib)
+		}
+	case CompilerEmscripten:
+		// Drop the compile step from the single-shot command; objects replace the source
+		jsPath := strings.TrimSuffix(outputPath, filepath.Ext(outputPath)) + ".js"
+		if opts.OptimizationLevel != "" {
+			args = append(args, opts.OptimizationLevel)
+		}
+		args = append(args, "-o", jsPath)
+		args = append(args, objects...)
+		for _, lib := range opts.LibraryPaths {
+			args = append(args, "-L"+lib)
+		}
+		args = append(args, opts.LinkerFlags...)
+		for _, lib := range opts.Libraries {
+			args = append(args, "-l"+lib)
+		}
+		args = append(args, emscriptenSettings(opts)...)
+	case CompilerMSVC:
+		args = append([]string{"/LD", runtimeFlags[opts.RuntimeLibrary], "/Fe:" + outputPath}, objects...)
+		for _, lib := range opts.Libraries {
+			args = append(args, strings.TrimSuffix(lib, ".lib")+".lib")
+		}
+		args = append(args, msvcLinkerArgs(opts)...)
+		// The export file is only needed to build the import library
+		intermediates = msvcIntermediates(outputPath)[1:]
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedCompiler, compiler.Type)
+	}
+
+	err := runCompiler(compiler, outputPath, args, opts)
+	removeIntermediates(intermediates, opts)
+	if err != nil {
+		return "", err
+	}
+	return outputPath, nil
+}
+
+// objectFileName returns the object file name for sourceFile
+func objectFileName(sourceFile string, compiler *CompilerInfo) string {
+	base := strings.TrimSuffix(filepath.Base(sourceFile), filepath.Ext(sourceFile))
+	if compiler.Type == CompilerMSVC {
+		return base + ".obj"
+	}
+	return base + ".o"
+}
+
+// isUpToDate reports whether target exists and is at least as new as source
+func isUpToDate(target, source string) bool {
+	targetInfo, err := os.Stat(target)
+	if err != nil {
+		return false
+	}
+	sourceInfo, err := os.Stat(source)
+	if err != nil {
+		return false
+	}
+	return !targetInfo.ModTime().Before(sourceInfo.ModTime())
+}
@@ -0,0 +1,95 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompileObjectAndLink(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	sources := map[string]string{
+		"add.cpp": `extern "C" int add(int a, int b) { return a + b; }`,
+		"sub.cpp": `extern "C" int sub(int a, int b) { return a - b; }`,
+	}
+	var srcPaths []string
+	for name, content := range sources {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte(content+"\n"), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		srcPaths = append(srcPaths, path)
+	}
+
+	opts := DefaultCompileOptions()
+	objDir := filepath.Join(tmpDir, "obj")
+
+	var objects []string
+	for _, src := range srcPaths {
+		obj, err := CompileObject(src, objDir, compiler, opts)
+		if err != nil {
+			t.Fatalf("CompileObject(%s) error = %v", src, err)
+		}
+		objects = append(objects, obj)
+	}
+
+	libPath, err := Link(objects, tmpDir, "mathlib.cpp", compiler, opts)
+	if err != nil {
+		t.Fatalf("Link() error = %v", err)
+	}
+	if filepath.Base(libPath) != generateLibraryName("mathlib.cpp", compiler) {
+		t.Errorf("Link() = %s, want library named after mathlib.cpp", libPath)
+	}
+	if _, err := os.Stat(libPath); err != nil {
+		t.Errorf("Expected library at %s: %v", libPath, err)
+	}
+
+	// An up-to-date object is reused rather than recompiled
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(srcPaths[0], past, past); err != nil {
+		t.Fatalf("Failed to age source: %v", err)
+	}
+	before, err := os.Stat(objects[0])
+	if err != nil {
+		t.Fatalf("Failed to stat object: %v", err)
+	}
+	if _, err := CompileObject(srcPaths[0], objDir, compiler, opts); err != nil {
+		t.Fatalf("CompileObject() error = %v", err)
+	}
+	after, err := os.Stat(objects[0])
+	if err != nil {
+		t.Fatalf("Failed to stat object: %v", err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("CompileObject() recompiled an up-to-date object")
+	}
+}
+
+func TestLinkNoObjects(t *testing.T) {
+	compiler := &CompilerInfo{Type: CompilerGCC, Path: "g++"}
+	if _, err := Link(nil, t.TempDir(), fileName, compiler, DefaultCompileOptions()); err == nil {
+		t.Error("Link() with no objects should fail")
+	}
+}
+
+func TestObjectFileName(t *testing.T) {
+	tests := []struct {
+		compiler CompilerType
+		want     string
+	}{
+		{CompilerGCC, "test.o"},
+		{CompilerClang, "test.o"},
+		{CompilerMSVC, "test.obj"},
+	}
+	for _, tt := range tests {
+		if got := objectFileName(filepath.Join("src", fileName), &CompilerInfo{Type: tt.compiler}); got != tt.want {
+			t.Errorf("objectFileName(%s) = %s, want %s", tt.compiler, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package compiler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"cp2p/util"
+)
+
+// precompiledHeader locates the files a precompiled header is built into
+type precompiledHeader struct {
+	header string // Header the PCH is built from
+	path   string // The built PCH
+	object string // Object file MSVC builds with the PCH, which libraries using it must link
+	stub   string // Empty source MSVC compiles to build the PCH
+}
+
+// pchFiles returns where the PCH of header is built in outputDir. GCC looks
+// for header.gch next to the header it is told to include, so the PCH is
+// used by force-including that path, which need not exist itself.
+func pchFiles(header, outputDir string, compiler *CompilerInfo) (precompiledHeader, error) {
+	pch := precompiledHeader{header: header}
+	base := filepath.Join(outputDir, filepath.Base(header))
+	switch compiler.Type {
+	case CompilerGCC:
+		pch.path = base + ".gch"
+	case CompilerClang, CompilerIntel:
+		pch.path = base + ".pch"
+	case CompilerMSVC:
+		pch.path = base + ".pch"
+		pch.object = base + ".pch.obj"
+		pch.stub = base + ".pch.cpp"
+	default:
+		return pch, fmt.Errorf("%w: precompiled headers are not supported with %s", ErrUnsupportedCompiler, compiler.Type)
+	}
+	return pch, nil
+}
+
+// buildArgs returns the arguments building the PCH with opts
+func (p precompiledHeader) buildArgs(compiler *CompilerInfo, opts *CompileOptions) []string {
+	var args []string
+	if compiler.Type == CompilerMSVC {
+		// /Yc ends the precompiled part after the force-included header
+		args = append([]string{"/c", runtimeFlags[opts.RuntimeLibrary], "/Yc" + p.header, "/FI" + p.header,
+			"/Fp" + p.path, "/Fo:" + p.object}, msvcCompileFlags(opts)...)
+		args = append(args, opts.ExtraFlags...)
+		return append(args, p.stub)
+	}
+	// -fPIC must match the compiles using the PCH, or GCC rejects it
+	args = append([]string{"-x", "c++-header", "-fPIC", "-o", p.path}, gccCompileFlags(opts)...)
+	args = append(args, opts.ExtraFlags...)
+	return append(args, p.header)
+}
+
+// useArgs returns the arguments compiling a library with the PCH
+func (p precompiledHeader) useArgs(compiler *CompilerInfo) []string {
+	switch compiler.Type {
+	case CompilerGCC:
+		return []string{"-Winvalid-pch", "-include", strings.TrimSuffix(p.path, ".gch")}
+	case CompilerMSVC:
+		return []string{"/Yu" + p.header, "/FI" + p.header, "/Fp" + p.path, p.object}
+	}
+	return []string{"-include-pch", p.path}
+}
+
+// PrecompileHeader builds opts.PrecompiledHeader into a precompiled header in
+// outputDir for compiler and returns its path. A PCH built from the same
+// header with the same flags is reused, so it is built once however many
+// compiles use it; changes to headers it includes are not detected.
+func PrecompileHeader(outputDir string, compiler *CompilerInfo, opts *CompileOptions) (string, error) {
+	pch, err := pchFiles(opts.PrecompiledHeader, outputDir, compiler)
+	if err != nil {
+		return "", err
+	}
+	if err := checkSourceFile(pch.header); err != nil {
+		return "", err
+	}
+	if err := util.EnsureWritableDir(outputDir); err != nil {
+		return "", fmt.Errorf("failed to prepare output directory: %w", err)
+	}
+
+	// The flags the PCH was built with are recorded next to it, since a PCH
+	// built with different ones is unusable
+	args := pch.buildArgs(compiler, opts)
+	stamp := pch.path + ".args"
+	if pchCurrent(pch, stamp, args) {
+		return pch.path, nil
+	}
+
+	var intermediates []string
+	if pch.stub != "" {
+		if err := os.WriteFile(pch.stub, nil, 0644); err != nil {
+			return "", fmt.Errorf("failed to create precompiled header source: %v", err)
+		}
+		intermediates = append(intermediates, pch.stub)
+	}
+	err = runCompiler(compiler, pch.path, args, opts)
+	removeIntermediates(intermediates, opts)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(stamp, []byte(strings.Join(args, "\n")), 0644); err != nil {
+		return "", fmt.Errorf("failed to record precompiled header flags: %v", err)
+	}
+	return pch.path, nil
+}
+
+// pchCurrent reports whether pch was built from its header as it is now,
+// with args
+func pchCurrent(pch precompiledHeader, stamp string, args []string) bool {
+	if !isUpToDate(pch.path, pch.header) {
+		return false
+	}
+	if pch.object != "" && !isUpToDate(pch.object, pch.header) {
+		return false
+	}
+	recorded, err := os.ReadFile(stamp)
+	return err == nil && slices.Equal(strings.Split(string(recorded), "\n"), args)
+}
+
+// precompiledHeaderArgs returns the arguments using the PCH of
+// opts.PrecompiledHeader, building it first if build is set; none if no
+// header is set
+func precompiledHeaderArgs(outputDir string, compiler *CompilerInfo, opts *CompileOptions, build bool) ([]string, error) {
+	if opts.PrecompiledHeader == "" {
+		return nil, nil
+	}
+	pch, err := pchFiles(opts.PrecompiledHeader, outputDir, compiler)
+	if err != nil {
+		return nil, err
+	}
+	if build {
+		if _, err := PrecompileHeader(outputDir, compiler, opts); err != nil {
+			return nil, fmt.Errorf("failed to precompile %s: %w", opts.PrecompiledHeader, err)
+		}
+	}
+	return pch.useArgs(compiler), nil
+}
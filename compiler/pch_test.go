@@ -0,0 +1,96 @@
+package compiler
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrecompiledHeader(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerGCC)
+	if err != nil {
+		t.Skipf("GCC not available: %v", err)
+	}
+
+	srcDir := t.TempDir()
+	outDir := t.TempDir()
+	header := filepath.Join(srcDir, "heavy.h")
+	if err := os.WriteFile(header, []byte("#ifndef HEAVY_H\n#define HEAVY_H\n#include <vector>\ninline int heavy_size() { return (int)std::vector<int>(3).size(); }\n#endif\n"), 0644); err != nil {
+		t.Fatalf("Failed to create header: %v", err)
+	}
+	source := filepath.Join(srcDir, "light.cpp")
+	if err := os.WriteFile(source, []byte("#include \"heavy.h\"\nextern \"C\" int light(void) { return heavy_size(); }\n"), 0644); err != nil {
+		t.Fatalf("Failed to create source: %v", err)
+	}
+
+	opts := DefaultCompileOptions()
+	opts.PrecompiledHeader = header
+	opts.FailOnWarnings = true // -Winvalid-pch warns if the PCH is rejected
+
+	// The force-included path does not exist, so the build only succeeds if
+	// GCC uses the PCH next to it
+	if _, err := CompileWithOptions(source, outDir, compiler, opts); err != nil {
+		t.Fatalf("CompileWithOptions() error = %v", err)
+	}
+	pch := filepath.Join(outDir, "heavy.h.gch")
+	built, err := os.Stat(pch)
+	if err != nil {
+		t.Fatalf("Expected precompiled header %s: %v", pch, err)
+	}
+
+	// A second build reuses it
+	if _, err := CompileWithOptions(source, outDir, compiler, opts); err != nil {
+		t.Fatalf("CompileWithOptions() error = %v", err)
+	}
+	reused, err := os.Stat(pch)
+	if err != nil {
+		t.Fatalf("Precompiled header missing after rebuild: %v", err)
+	}
+	if !reused.ModTime().Equal(built.ModTime()) {
+		t.Error("Precompiled header was rebuilt although the header and flags are unchanged")
+	}
+
+	// Different flags need a new one
+	opts.OptimizationLevel = "-O0"
+	if _, err := CompileWithOptions(source, outDir, compiler, opts); err != nil {
+		t.Fatalf("CompileWithOptions() with -O0 error = %v", err)
+	}
+	recorded, err := os.ReadFile(pch + ".args")
+	if err != nil {
+		t.Fatalf("Failed to read recorded flags: %v", err)
+	}
+	if !strings.Contains(string(recorded), "-O0") {
+		t.Errorf("Precompiled header was not rebuilt with -O0, recorded flags:\n%s", recorded)
+	}
+}
+
+func TestCompileCommandStringPrecompiledHeader(t *testing.T) {
+	tests := []struct {
+		compiler *CompilerInfo
+		want     string
+	}{
+		{&CompilerInfo{Type: CompilerGCC, Path: "/usr/bin/g++"}, "-Winvalid-pch -include out/heavy.h "},
+		{&CompilerInfo{Type: CompilerClang, Path: "/usr/bin/clang++"}, "-include-pch out/heavy.h.pch "},
+		{&CompilerInfo{Type: CompilerMSVC, Path: `C:\cl.exe`}, "/Yuheavy.h /FIheavy.h /Fpout/heavy.h.pch out/heavy.h.pch.obj "},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.compiler.Type), func(t *testing.T) {
+			opts := DefaultCompileOptions()
+			opts.PrecompiledHeader = "heavy.h"
+			command, err := CompileCommandString(fileName, "out", tt.compiler, opts)
+			if err != nil {
+				t.Fatalf("CompileCommandString() error = %v", err)
+			}
+			if !strings.Contains(filepath.ToSlash(command), tt.want) {
+				t.Errorf("CompileCommandString() = %s, want it to contain %s", command, tt.want)
+			}
+		})
+	}
+
+	opts := DefaultCompileOptions()
+	opts.PrecompiledHeader = "heavy.h"
+	if _, err := CompileCommandString(fileName, "out", &CompilerInfo{Type: CompilerEmscripten, Path: "/usr/bin/em++"}, opts); err == nil {
+		t.Error("CompileCommandString() should reject a precompiled header with Emscripten")
+	}
+}
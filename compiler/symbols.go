@@ -0,0 +1,106 @@
+package compiler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// ExportedSymbols lists the symbols a shared library exports, using dumpbin
+// for MSVC builds and nm otherwise. It returns ErrNoSymbolTool if the tool is
+// not installed.
+func ExportedSymbols(libPath string, compiler *CompilerInfo) ([]string, error) {
+	ctx := context.Background()
+	if compiler.Type == CompilerMSVC {
+		dumpbin, err := lookPathAbs("dumpbin")
+		if err != nil {
+			return nil, fmt.Errorf("%w: dumpbin", ErrNoSymbolTool)
+		}
+		output, err := exec.CommandContext(ctx, dumpbin, "/NOLOGO", "/EXPORTS", libPath).Output()
+		if err != nil {
+			return nil, fmt.Errorf("dumpbin failed on %s: %v", libPath, err)
+		}
+		return parseDumpbinExports(string(output)), nil
+	}
+
+	nm, err := lookPathAbs("nm")
+	if err != nil {
+		return nil, fmt.Errorf("%w: nm", ErrNoSymbolTool)
+	}
+	args := []string{"-D", "--defined-only"}
+	switch runtime.GOOS {
+	case "darwin":
+		args = []string{"-gU"}
+	case "windows":
+		args = []string{"-g", "--defined-only"}
+	}
+	output, err := exec.CommandContext(ctx, nm, append(args, libPath)...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("nm failed on %s: %v", libPath, err)
+	}
+	return parseNMSymbols(string(output), runtime.GOOS == "darwin"), nil
+}
+
+// MissingSymbols returns the names the library at libPath does not export, in
+// the order given
+func MissingSymbols(libPath string, compiler *CompilerInfo, names []string) ([]string, error) {
+	exported, err := ExportedSymbols(libPath, compiler)
+	if err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	for _, name := range names {
+		if !slices.Contains(exported, name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing, nil
+}
+
+// parseNMSymbols extracts symbol names from nm output lines such as
+// "00000000000010f9 T add". Mach-O symbols carry a leading underscore, which
+// is removed when stripUnderscore is set.
+func parseNMSymbols(output string, stripUnderscore bool) []string {
+	var symbols []string
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		name := fields[len(fields)-1]
+		name, _, _ = strings.Cut(name, "@") // Symbol version, e.g. add@@V1
+		if stripUnderscore {
+			name = strings.TrimPrefix(name, "_")
+		}
+		symbols = append(symbols, name)
+	}
+	return symbols
+}
+
+// parseDumpbinExports extracts symbol names from the export table printed by
+// dumpbin /EXPORTS, whose rows read "ordinal hint RVA name"
+func parseDumpbinExports(output string) []string {
+	var symbols []string
+	inTable := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		switch {
+		case len(fields) == 4 && fields[0] == "ordinal" && fields[3] == "name":
+			inTable = true
+		case inTable && len(fields) == 0:
+			if len(symbols) > 0 {
+				inTable = false
+			}
+		case inTable && len(fields) >= 4:
+			symbols = append(symbols, fields[3])
+		}
+	}
+	return symbols
+}
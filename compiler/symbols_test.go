@@ -0,0 +1,84 @@
+package compiler
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMissingSymbols(t *testing.T) {
+	compiler, err := DetectCompiler(CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+
+	// sub lacks extern "C", so only its mangled name is exported
+	tmpDir := t.TempDir()
+	src := filepath.Join(tmpDir, "symbols.cpp")
+	content := "extern \"C\" int add(int a, int b) { return a + b; }\n" +
+		"int sub(int a, int b) { return a - b; }\n"
+	if err := os.WriteFile(src, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	opts := DefaultCompileOptions()
+	libPath, err := CompileWithOptions(src, tmpDir, compiler, opts)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	missing, err := MissingSymbols(libPath, compiler, []string{"add", "sub"})
+	if errors.Is(err, ErrNoSymbolTool) {
+		t.Skipf("Skipping symbol check: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("MissingSymbols() error = %v", err)
+	}
+	if !slices.Equal(missing, []string{"sub"}) {
+		t.Errorf("MissingSymbols() = %v, want [sub]", missing)
+	}
+}
+
+func TestParseNMSymbols(t *testing.T) {
+	output := "00000000000010f9 T add\n" +
+		"000000000000110d T _Z3subii\n" +
+		"0000000000001120 T mul@@LIB_1.0\n"
+	if got, want := parseNMSymbols(output, false), []string{"add", "_Z3subii", "mul"}; !slices.Equal(got, want) {
+		t.Errorf("parseNMSymbols() = %v, want %v", got, want)
+	}
+
+	// Mach-O names carry a leading underscore
+	if got, want := parseNMSymbols("0000000000003f90 T _add\n", true), []string{"add"}; !slices.Equal(got, want) {
+		t.Errorf("parseNMSymbols() = %v, want %v", got, want)
+	}
+}
+
+func TestParseDumpbinExports(t *testing.T) {
+	output := `
+Dump of file test.dll
+
+File Type: DLL
+
+  Section contains the following exports for test.dll
+
+    00000000 characteristics
+    FFFFFFFF time date stamp
+        0.00 version
+           1 ordinal base
+           2 number of functions
+           2 number of names
+
+    ordinal hint RVA      name
+
+          1    0 00001000 add
+          2    1 00001010 sub = sub
+
+  Summary
+
+        1000 .data
+`
+	if got, want := parseDumpbinExports(output), []string{"add", "sub"}; !slices.Equal(got, want) {
+		t.Errorf("parseDumpbinExports() = %v, want %v", got, want)
+	}
+}
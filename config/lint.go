@@ -0,0 +1,105 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Lint issue severities. Errors make generation fail or produce a broken
+// module; warnings point at likely mistakes.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem Lint found in a config
+type Issue struct {
+	Severity string
+	Message  string
+}
+
+func (i Issue) String() string {
+	return i.Severity + ": " + i.Message
+}
+
+// freeWords are the name parts that mark a function as releasing memory
+var freeWords = []string{"free", "release", "destroy", "delete", "dispose"}
+
+// Lint checks cfg for common mistakes: everything validation rejects,
+// functions without descriptions, unnamed parameters, types that are neither
+// mapped nor declared, and pointers returned with no function to free them.
+// isKnownType reports whether the generator can bind a C type.
+func (c *Config) Lint(isKnownType func(string) bool) []Issue {
+	var issues []Issue
+	add := func(severity, format string, args ...any) {
+		issues = append(issues, Issue{Severity: severity, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if err := validateConfig(c); err != nil {
+		add(SeverityError, "%v", err)
+	}
+
+	for _, fn := range c.Functions {
+		if fn.Name == "" {
+			continue // Reported by validation
+		}
+		if fn.Description == "" && fn.Docstring == "" {
+			add(SeverityWarning, "function %s has no description", fn.Name)
+		}
+		for i, p := range fn.Parameters {
+			if p.Name == "" {
+				add(SeverityError, "parameter %d of function %s has no name", i, fn.Name)
+			}
+		}
+
+		// The generator skips functions using types it cannot bind
+		types := []string{fn.ReturnType}
+		for _, p := range fn.Parameters {
+			types = append(types, p.Type)
+		}
+		for _, t := range types {
+			if t != "" && !isKnownType(t) {
+				add(SeverityWarning, "function %s uses undeclared type %s and will be skipped", fn.Name, t)
+			}
+		}
+
+		if returnsOwnedPointer(fn) && !c.hasFreeFunction(fn.ReturnType) {
+			add(SeverityWarning, "function %s returns %s but no function frees it", fn.Name, fn.ReturnType)
+		}
+	}
+
+	for _, typ := range c.Types {
+		for _, field := range typ.Fields {
+			if !isKnownType(field.Type) {
+				add(SeverityError, "field %s of %s %s has undeclared type %s", field.Name, typ.Kind, typ.Name, field.Type)
+			}
+		}
+	}
+
+	return issues
+}
+
+// returnsOwnedPointer reports whether fn returns a pointer the caller likely
+// owns. Pointers to const are taken to stay owned by the library.
+func returnsOwnedPointer(fn FunctionConfig) bool {
+	return strings.HasSuffix(fn.ReturnType, "*") && !strings.HasPrefix(fn.ReturnType, "const ")
+}
+
+// hasFreeFunction reports whether some function named like a deallocator
+// takes a parameter of type t or void*
+func (c *Config) hasFreeFunction(t string) bool {
+	t = strings.ReplaceAll(t, " ", "")
+	for _, fn := range c.Functions {
+		name := strings.ToLower(fn.Name)
+		if !slices.ContainsFunc(freeWords, func(word string) bool { return strings.Contains(name, word) }) {
+			continue
+		}
+		for _, p := range fn.Parameters {
+			if pt := strings.ReplaceAll(p.Type, " ", ""); pt == t || pt == "void*" {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	cfg, err := DecodeConfig(strings.NewReader(`{
+		"functions": [
+			{"name": "add", "description": "Adds.", "return_type": "int", "parameters": [{"name": "a", "type": "int"}, {"name": "", "type": "int"}]},
+			{"name": "make_buffer", "description": "Allocates.", "return_type": "char*", "parameters": []},
+			{"name": "make_image", "description": "Allocates.", "return_type": "Image*", "parameters": []},
+			{"name": "free_image", "description": "Frees.", "return_type": "void", "parameters": [{"name": "img", "type": "Image*"}]},
+			{"name": "version", "return_type": "const char*", "parameters": []},
+			{"name": "area", "description": "Area.", "return_type": "double", "parameters": [{"name": "s", "type": "Shape"}]},
+			{"name": "", "return_type": "int"}
+		],
+		"types": [
+			{"name": "Point", "kind": "struct", "fields": [{"name": "x", "type": "int"}, {"name": "tag", "type": "Label"}]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("DecodeConfig() error = %v", err)
+	}
+
+	known := []string{"int", "double", "void", "char*", "const char*", "Image*", "Point"}
+	var got []string
+	for _, issue := range cfg.Lint(func(t string) bool { return slices.Contains(known, t) }) {
+		got = append(got, issue.String())
+	}
+
+	want := []string{
+		"error: function at index 6 has no name",
+		"error: parameter 1 of function add has no name",
+		"warning: function make_buffer returns char* but no function frees it",
+		"warning: function version has no description",
+		"warning: function area uses undeclared type Shape and will be skipped",
+		"error: field tag of struct Point has undeclared type Label",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintCleanConfig(t *testing.T) {
+	cfg := &Config{
+		Functions: []FunctionConfig{
+			{Name: "add", Description: "Adds two integers.", ReturnType: "int", Parameters: []Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}},
+		},
+	}
+	if issues := cfg.Lint(func(string) bool { return true }); len(issues) != 0 {
+		t.Errorf("Lint() = %v, want no issues", issues)
+	}
+}
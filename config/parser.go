@@ -3,25 +3,112 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"slices"
+	"strings"
 )
 
 // Config represents the binding configuration
 type Config struct {
-	Functions []FunctionConfig `json:"functions"`
-	Includes  []string         `json:"includes"`
-	Libraries []string         `json:"libraries"`
-	Types     []TypeConfig     `json:"types"` // Complex types (structs, classes, etc.)
+	Functions     []FunctionConfig `json:"functions"`
+	Includes      []string         `json:"includes"`       // Include directories
+	Libraries     []string         `json:"libraries"`      // Libraries to link against
+	Types         []TypeConfig     `json:"types"`          // Complex types (structs, classes, etc.)
+	Constants     []ConstantConfig `json:"constants"`      // Module-level constants
+	ArrayMode     bool             `json:"array_mode"`     // Accept numpy arrays for int*, float* and double* parameters
+	TypeMappings  []TypeMapping    `json:"type_mappings"`  // Extra C type mappings, e.g. for project typedefs
+	Async         bool             `json:"async"`          // Generate an awaitable <name>_async wrapper for every function
+	OutputBackend string           `json:"output_backend"` // Python FFI the generated module uses: ctypes (default) or cffi
+	OutputLang    string           `json:"output_lang"`    // Language of the generated bindings: python (the default)
+	CompilerFlags []string         `json:"compiler_flags"` // Extra flags passed verbatim to the compiler
+	LinkerFlags   []string         `json:"linker_flags"`   // Extra flags passed verbatim to the linker
+	LibFileName   string           `json:"lib_file_name"`  // Library file name the generated loader opens (defaults to the built library's)
+
+	// OptimizationLevel is -O0, -O1, -O2, -O3, -Os or -Og, spelled as for GCC
+	// and mapped to the closest MSVC option; empty keeps the default, -O2
+	OptimizationLevel string `json:"optimization_level"`
+
+	// PrecompiledHeader is a heavy header the source includes, compiled once
+	// into a precompiled header that later builds reuse while it is unchanged
+	PrecompiledHeader string `json:"precompiled_header"`
+
+	// CrossPlatformLoader makes the generated loader pick the library file name
+	// for the running platform, using the conventional name on Windows, Linux
+	// and macOS unless LibFileNames (keyed by GOOS) overrides it
+	CrossPlatformLoader bool              `json:"cross_platform_loader"`
+	LibFileNames        map[string]string `json:"lib_file_names"`
+
+	VerifyChecksum bool `json:"verify_checksum"` // Refuse to load a library whose SHA-256 differs from the one built
+	GenerateTests  bool `json:"generate_tests"`  // Also write test_<module>.py, pytest smoke tests of the bound functions
+	EmbedLibrary   bool `json:"embed_library"`   // Embed the library in the module, extracting it to a temporary file at import
+	StrictInt      bool `json:"strict_int"`      // Raise OverflowError for integer arguments out of their C type's range instead of truncating them
+
+	// CallingConvention of the bound functions and callbacks: cdecl (the
+	// default) or stdcall, which 32-bit Windows APIs often use
+	CallingConvention string `json:"calling_convention"`
+
+	// PythonVersion is the oldest Python the generated module supports, e.g.
+	// "3.8". From 3.10, optional type hints use the X | None syntax.
+	PythonVersion string `json:"python_version"`
+
+	// LibrarySearchPath lists where the generated loader looks for the library,
+	// in order: module, lib, resources, env and system (the default order)
+	LibrarySearchPath []string `json:"library_search_path"`
+
+	// DependencyDirs lists directories holding libraries the shared library
+	// depends on, made loadable before it is; relative ones are taken from the
+	// generated module's directory
+	DependencyDirs []string `json:"dependency_dirs"`
+
+	// LineEndings of the generated files: lf (the default) or crlf, whatever
+	// the platform
+	LineEndings string `json:"line_endings"`
+
+	Exclude []string `json:"exclude"` // Functions to leave unbound, by C or Python name
+
+	ModuleDocstring string `json:"module_docstring"` // Docstring of the generated module (defaults to a generic one)
+	OmitTimestamp   bool   `json:"omit_timestamp"`   // Leave the generation time out of the module header
+
+	// Reproducible makes generated files byte-identical across runs and
+	// machines, leaving out the generation time and absolute paths
+	Reproducible bool `json:"reproducible"`
+
+	// SourceFile is the C++ source the bindings are for, named in the module
+	// header. Parsers and the CLI set it; it is not read from config files.
+	SourceFile string `json:"-"`
+
+	// BuildFiles are the files the build wrote to the output directory, which
+	// the binding manifest records as generated. Set by the CLI and pipeline.
+	BuildFiles []string `json:"-"`
+}
+
+// TypeMapping maps a project-specific C type to a ctypes expression
+type TypeMapping struct {
+	CType      string `json:"c_type"`      // C type as spelled in signatures, e.g. "myfloat"
+	Ctypes     string `json:"ctypes"`      // ctypes expression, e.g. "ctypes.c_float"
+	PythonHint string `json:"python_hint"` // Python type hint (defaults to Any)
+}
+
+// ConstantConfig represents a constant or macro exposed as a module-level
+// Python variable
+type ConstantConfig struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`  // C type, used for the Python type hint
+	Value       string `json:"value"` // C literal, e.g. "100", "0x1F", "2.5f" or "\"v1\""
+	Description string `json:"description"`
 }
 
 // TypeConfig represents a complex type definition
 type TypeConfig struct {
 	Name        string   `json:"name"`        // Name of the type
-	Kind        string   `json:"kind"`        // struct, class, enum, union
+	Kind        string   `json:"kind"`        // struct, class, enum, union, handle, opaque
 	Fields      []Field  `json:"fields"`      // For structs/classes
 	Values      []string `json:"values"`      // For enums
 	BaseType    string   `json:"base_type"`   // For enums
 	Description string   `json:"description"` // Documentation
+	Constructor string   `json:"constructor"` // For handles: C function returning a new handle
+	Destructor  string   `json:"destructor"`  // For handles: C function releasing a handle
 }
 
 // Field represents a field in a struct/class
@@ -33,45 +120,218 @@ type Field struct {
 
 // FunctionConfig represents the configuration for a single function
 type FunctionConfig struct {
-	Name        string  `json:"name"`
-	Description string  `json:"description"`
-	Parameters  []Param `json:"parameters"`
-	ReturnType  string  `json:"return_type"`
-	Docstring   string  `json:"docstring"`
+	Name         string           `json:"name"`
+	Description  string           `json:"description"`
+	Parameters   []Param          `json:"parameters"`
+	ReturnType   string           `json:"return_type"`
+	ReturnPyType string           `json:"return_py_type"` // Python type hint of the return value (defaults to the one mapped for ReturnType)
+	Docstring    string           `json:"docstring"`
+	PythonName   string           `json:"python_name"` // Name of the Python wrapper (defaults to Name)
+	Symbol       string           `json:"symbol"`      // Exported symbol to bind (defaults to Name)
+	Includes     []string         `json:"includes"`    // Include directories needed by this function
+	Libraries    []string         `json:"libraries"`   // Libraries needed by this function
+	Async        bool             `json:"async"`       // Also generate an awaitable <name>_async wrapper
+	Callbacks    []CallbackConfig `json:"callbacks"`   // Function pointer types used by the parameters
+	ErrCheck     string           `json:"errcheck"`    // Check of each call's result that raises on failure: null, negative or nonzero
+}
+
+// Result checks a function's errcheck can apply
+const (
+	ErrCheckNull     = "null"     // A pointer result is NULL
+	ErrCheckNegative = "negative" // An integer result is negative
+	ErrCheckNonZero  = "nonzero"  // An integer result is an error code other than 0
+)
+
+// CallbackConfig declares a function pointer type that parameters can name as
+// their type, e.g. {"name": "visit_fn", "return_type": "void", "parameters": ["int"]}
+// for void (*visit_fn)(int)
+type CallbackConfig struct {
+	Name       string   `json:"name"`
+	ReturnType string   `json:"return_type"`
+	Parameters []string `json:"parameters"` // Parameter types, in order
+}
+
+// IsExcluded reports whether fn is listed in the config's Exclude list, by its
+// C name or its Python name
+func (c *Config) IsExcluded(fn FunctionConfig) bool {
+	return slices.Contains(c.Exclude, fn.Name) || slices.Contains(c.Exclude, fn.PyName())
+}
+
+// PyName returns the name of the generated Python function
+func (f FunctionConfig) PyName() string {
+	if f.PythonName != "" {
+		return f.PythonName
+	}
+	return f.Name
+}
+
+// CSymbol returns the exported library symbol the function binds to
+func (f FunctionConfig) CSymbol() string {
+	if f.Symbol != "" {
+		return f.Symbol
+	}
+	return f.Name
 }
 
 // Param represents a function parameter
 type Param struct {
 	Name        string `json:"name"`
 	Type        string `json:"type"`
+	PyType      string `json:"py_type"`   // Python type hint (defaults to the one mapped for Type)
+	Default     string `json:"default"`   // C literal used as the Python default, making the argument optional
+	Direction   string `json:"direction"` // in (default), out or inout; out and inout parameters must be pointers
 	Description string `json:"description"`
 }
 
+// Line endings of generated files
+const (
+	LineEndingsLF   = "lf"
+	LineEndingsCRLF = "crlf"
+)
+
+// Parameter directions
+const (
+	DirectionIn    = "in"
+	DirectionOut   = "out"
+	DirectionInOut = "inout"
+)
+
+// IsOutput reports whether the function writes a value through the parameter
+// that the Python wrapper returns
+func (p Param) IsOutput() bool {
+	return p.Direction == DirectionOut || p.Direction == DirectionInOut
+}
+
+// ValueType returns the type of the value a Python caller passes or gets
+// back for the parameter: the pointee for output parameters, Type otherwise
+func (p Param) ValueType() string {
+	if !p.IsOutput() {
+		return p.Type
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(p.Type), "*"))
+}
+
+// Inputs returns the parameters a Python caller passes, leaving out the out
+// parameters the wrapper allocates itself
+func (f FunctionConfig) Inputs() []Param {
+	var params []Param
+	for _, p := range f.Parameters {
+		if p.Direction != DirectionOut {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
+// Outputs returns the out and inout parameters, in declaration order
+func (f FunctionConfig) Outputs() []Param {
+	var params []Param
+	for _, p := range f.Parameters {
+		if p.IsOutput() {
+			params = append(params, p)
+		}
+	}
+	return params
+}
+
 // ParseConfig parses a JSON configuration file
 func ParseConfig(configPath string) (*Config, error) {
-	data, err := os.ReadFile(configPath)
+	f, err := os.Open(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %v", err)
 	}
+	defer f.Close()
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse JSON config: %v", err)
+	return ParseConfigReader(f)
+}
+
+// ParseConfigReader parses a JSON configuration read from r, such as stdin or
+// a file in an embedded filesystem
+func ParseConfigReader(r io.Reader) (*Config, error) {
+	cfg, err := DecodeConfig(r)
+	if err != nil {
+		return nil, err
 	}
 
 	// Validate config
-	if err := validateConfig(&cfg); err != nil {
+	if err := validateConfig(cfg); err != nil {
 		return nil, err
 	}
 
+	return cfg, nil
+}
+
+// DecodeConfig reads a JSON configuration from r without validating it, for
+// tools such as Lint that report problems themselves
+func DecodeConfig(r io.Reader) (*Config, error) {
+	var cfg Config
+	if err := json.NewDecoder(r).Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON config: %v", err)
+	}
 	return &cfg, nil
 }
 
+// WriteConfig writes cfg to w as indented JSON that ParseConfigReader reads
+// back. Unset fields are left out, so the file holds only what was configured.
+func WriteConfig(w io.Writer, cfg *Config) error {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(pruneZero(tree))
+}
+
+// pruneZero removes null, false, empty string and empty collection values from
+// the objects in a decoded JSON tree
+func pruneZero(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			value = pruneZero(value)
+			if isZeroJSON(value) {
+				delete(v, key)
+			} else {
+				v[key] = value
+			}
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = pruneZero(value)
+		}
+	}
+	return v
+}
+
+// isZeroJSON reports whether a decoded JSON value is its type's zero value
+func isZeroJSON(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
 func validateConfig(cfg *Config) error {
 	if len(cfg.Functions) == 0 {
 		return fmt.Errorf("no functions specified in config")
 	}
 
+	pyNames := make(map[string]bool)
 	for i, fn := range cfg.Functions {
 		if fn.Name == "" {
 			return fmt.Errorf("function at index %d has no name", i)
@@ -79,6 +339,72 @@ func validateConfig(cfg *Config) error {
 		if fn.ReturnType == "" {
 			return fmt.Errorf("function %s has no return type", fn.Name)
 		}
+		if pyNames[fn.PyName()] {
+			return fmt.Errorf("duplicate Python function name %s (set python_name to disambiguate overloads)", fn.PyName())
+		}
+		pyNames[fn.PyName()] = true
+
+		switch fn.ErrCheck {
+		case "", ErrCheckNull, ErrCheckNegative, ErrCheckNonZero:
+		default:
+			return fmt.Errorf("function %s has unknown errcheck %q (expected %s, %s or %s)", fn.Name, fn.ErrCheck, ErrCheckNull, ErrCheckNegative, ErrCheckNonZero)
+		}
+
+		for _, p := range fn.Parameters {
+			switch p.Direction {
+			case "", DirectionIn:
+			case DirectionOut, DirectionInOut:
+				if !strings.HasSuffix(strings.TrimSpace(p.Type), "*") {
+					return fmt.Errorf("%s parameter %s of function %s must be a pointer", p.Direction, p.Name, fn.Name)
+				}
+				if p.Direction == DirectionOut && p.Default != "" {
+					return fmt.Errorf("out parameter %s of function %s cannot have a default", p.Name, fn.Name)
+				}
+			default:
+				return fmt.Errorf("parameter %s of function %s has unknown direction %q (expected in, out or inout)", p.Name, fn.Name, p.Direction)
+			}
+		}
+
+		// Python only allows defaults on trailing parameters
+		inputs := fn.Inputs()
+		for j := 1; j < len(inputs); j++ {
+			if inputs[j].Default == "" && inputs[j-1].Default != "" {
+				return fmt.Errorf("parameter %s of function %s has no default but follows one that does", inputs[j].Name, fn.Name)
+			}
+		}
+
+		for j, cb := range fn.Callbacks {
+			if cb.Name == "" || cb.ReturnType == "" {
+				return fmt.Errorf("callback at index %d of function %s needs both a name and a return type", j, fn.Name)
+			}
+		}
+	}
+
+	switch cfg.LineEndings {
+	case "", LineEndingsLF, LineEndingsCRLF:
+	default:
+		return fmt.Errorf("unknown line_endings %q (expected %s or %s)", cfg.LineEndings, LineEndingsLF, LineEndingsCRLF)
+	}
+
+	for i, m := range cfg.TypeMappings {
+		if m.CType == "" || m.Ctypes == "" {
+			return fmt.Errorf("type mapping at index %d needs both c_type and ctypes", i)
+		}
+	}
+
+	for i, c := range cfg.Constants {
+		if c.Name == "" || c.Value == "" {
+			return fmt.Errorf("constant at index %d needs both a name and a value", i)
+		}
+	}
+
+	for i, typ := range cfg.Types {
+		if typ.Name == "" {
+			return fmt.Errorf("type at index %d has no name", i)
+		}
+		if typ.Kind == "handle" && (typ.Constructor == "" || typ.Destructor == "") {
+			return fmt.Errorf("handle type %s needs both a constructor and a destructor", typ.Name)
+		}
 	}
 
 	return nil
@@ -94,6 +420,35 @@ func (c *Config) GetFunctionConfig(name string) *FunctionConfig {
 	return nil
 }
 
+// AllIncludes returns the config's include directories merged with those of
+// every function, without duplicates and in first-seen order
+func (c *Config) AllIncludes() []string {
+	all := appendUnique(nil, c.Includes...)
+	for _, fn := range c.Functions {
+		all = appendUnique(all, fn.Includes...)
+	}
+	return all
+}
+
+// AllLibraries returns the config's libraries merged with those of every
+// function, without duplicates and in first-seen order
+func (c *Config) AllLibraries() []string {
+	all := appendUnique(nil, c.Libraries...)
+	for _, fn := range c.Functions {
+		all = appendUnique(all, fn.Libraries...)
+	}
+	return all
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		if !slices.Contains(list, item) {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
 // DefaultConfig returns a default configuration
 func DefaultConfig() *Config {
 	return &Config{
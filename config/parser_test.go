@@ -0,0 +1,101 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAllIncludesAndLibraries(t *testing.T) {
+	cfg := &Config{
+		Includes:  []string{"/usr/local/include"},
+		Libraries: []string{"m"},
+		Functions: []FunctionConfig{
+			{Name: "draw", Includes: []string{"/opt/widget/include"}, Libraries: []string{"widget", "m"}},
+			{Name: "area", Includes: []string{"/usr/local/include", "/opt/geo/include"}},
+		},
+	}
+
+	wantIncludes := []string{"/usr/local/include", "/opt/widget/include", "/opt/geo/include"}
+	if got := cfg.AllIncludes(); !slices.Equal(got, wantIncludes) {
+		t.Errorf("AllIncludes() = %v, want %v", got, wantIncludes)
+	}
+
+	wantLibraries := []string{"m", "widget"}
+	if got := cfg.AllLibraries(); !slices.Equal(got, wantLibraries) {
+		t.Errorf("AllLibraries() = %v, want %v", got, wantLibraries)
+	}
+}
+
+func TestParseConfigReader(t *testing.T) {
+	cfg, err := ParseConfigReader(strings.NewReader(`{
+		"functions": [{"name": "add", "return_type": "int", "parameters": [{"name": "a", "type": "int"}]}],
+		"libraries": ["m"]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseConfigReader() error = %v", err)
+	}
+	if len(cfg.Functions) != 1 || cfg.Functions[0].Name != "add" {
+		t.Errorf("ParseConfigReader() functions = %+v, want add", cfg.Functions)
+	}
+	if !slices.Equal(cfg.Libraries, []string{"m"}) {
+		t.Errorf("ParseConfigReader() libraries = %v, want [m]", cfg.Libraries)
+	}
+
+	if _, err := ParseConfigReader(strings.NewReader(`{"functions": []}`)); err == nil {
+		t.Error("ParseConfigReader() with no functions should fail validation")
+	}
+	if _, err := ParseConfigReader(strings.NewReader(`{"functions": [{"name": "f", "return_type": "int", "parameters": [
+		{"name": "a", "type": "int", "default": "1"}, {"name": "b", "type": "int"}]}]}`)); err == nil {
+		t.Error("ParseConfigReader() should reject a parameter without a default after one with a default")
+	}
+	if _, err := ParseConfigReader(strings.NewReader(`{"functions": [{"name": "f", "return_type": "int", "parameters": [
+		{"name": "a", "type": "int", "default": "1"}, {"name": "out", "type": "int*", "direction": "out"}]}]}`)); err != nil {
+		t.Errorf("ParseConfigReader() should allow an out parameter after a default, got %v", err)
+	}
+	if _, err := ParseConfigReader(strings.NewReader(`{"functions": [{"name": "f", "return_type": "int", "parameters": [
+		{"name": "out", "type": "int", "direction": "out"}]}]}`)); err == nil {
+		t.Error("ParseConfigReader() should reject an out parameter that is not a pointer")
+	}
+	if _, err := ParseConfigReader(strings.NewReader(`{"functions": [{"name": "f", "return_type": "int", "parameters": [
+		{"name": "a", "type": "int*", "direction": "both"}]}]}`)); err == nil {
+		t.Error("ParseConfigReader() should reject an unknown direction")
+	}
+	if _, err := ParseConfigReader(strings.NewReader(`{"functions": [{"name": "f", "return_type": "int", "errcheck": "zero"}]}`)); err == nil {
+		t.Error("ParseConfigReader() should reject an unknown errcheck")
+	}
+	if _, err := ParseConfigReader(strings.NewReader(`{`)); err == nil {
+		t.Error("ParseConfigReader() with malformed JSON should fail")
+	}
+}
+
+func TestWriteConfig(t *testing.T) {
+	cfg := &Config{
+		Functions: []FunctionConfig{
+			{Name: "add", ReturnType: "int", Parameters: []Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}},
+			{Name: "reset", ReturnType: "void", Parameters: []Param{}},
+		},
+		Constants: []ConstantConfig{{Name: "MAX", Type: "int", Value: "10"}},
+	}
+
+	var buf strings.Builder
+	if err := WriteConfig(&buf, cfg); err != nil {
+		t.Fatalf("WriteConfig() error = %v", err)
+	}
+	for _, unset := range []string{"null", "false", `""`, "includes", "parameters\": []"} {
+		if strings.Contains(buf.String(), unset) {
+			t.Errorf("WriteConfig() output contains unset value %s:\n%s", unset, buf.String())
+		}
+	}
+
+	got, err := ParseConfigReader(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ParseConfigReader() error = %v", err)
+	}
+	if len(got.Functions) != 2 || len(got.Functions[0].Parameters) != 2 || got.Functions[1].Name != "reset" {
+		t.Errorf("Round trip functions = %+v", got.Functions)
+	}
+	if len(got.Constants) != 1 || got.Constants[0].Value != "10" {
+		t.Errorf("Round trip constants = %+v", got.Constants)
+	}
+}
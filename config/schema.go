@@ -0,0 +1,99 @@
+package config
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// schemaRequired lists the properties validateConfig rejects a config without
+var schemaRequired = map[reflect.Type][]string{
+	reflect.TypeFor[Config]():         {"functions"},
+	reflect.TypeFor[FunctionConfig](): {"name", "return_type"},
+	reflect.TypeFor[CallbackConfig](): {"name", "return_type"},
+	reflect.TypeFor[TypeMapping]():    {"c_type", "ctypes"},
+	reflect.TypeFor[TypeConfig]():     {"name"},
+}
+
+// schemaEnums lists the accepted values of string properties, keyed by
+// "<struct>.<json name>"
+var schemaEnums = map[string][]string{
+	"Config.output_backend": {"ctypes", "cffi"},
+	"TypeConfig.kind":       {"struct", "class", "enum", "union", "handle", "opaque"},
+}
+
+// Schema returns a JSON Schema describing the config file format. It is built
+// from the Config struct definitions, so it stays in step with the fields
+// ParseConfig accepts.
+func Schema() map[string]any {
+	defs := map[string]any{}
+	root := structSchema(reflect.TypeFor[Config](), defs)
+
+	// Let configs reference the schema for editor validation
+	root["properties"].(map[string]any)["$schema"] = map[string]any{"type": "string"}
+
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["title"] = "cp2p binding configuration"
+	root["$defs"] = defs
+	return root
+}
+
+// WriteSchema writes the indented JSON Schema returned by Schema to w
+func WriteSchema(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Schema())
+}
+
+// structSchema returns the object schema for struct type t, adding the
+// schemas of nested structs to defs
+func structSchema(t reflect.Type, defs map[string]any) map[string]any {
+	properties := map[string]any{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" || !field.IsExported() {
+			continue
+		}
+		prop := typeSchema(field.Type, defs)
+		if enum, ok := schemaEnums[t.Name()+"."+name]; ok {
+			prop["enum"] = enum
+		}
+		properties[name] = prop
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if required, ok := schemaRequired[t]; ok {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// typeSchema returns the schema for a field of type t
+func typeSchema(t reflect.Type, defs map[string]any) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Slice:
+		return map[string]any{"type": "array", "items": typeSchema(t.Elem(), defs)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(t.Elem(), defs)}
+	case reflect.Struct:
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // Reserve the name so recursive types terminate
+			defs[t.Name()] = structSchema(t, defs)
+		}
+		return map[string]any{"$ref": "#/$defs/" + t.Name()}
+	default:
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// sampleConfig exercises every section of the config format
+const sampleConfig = `{
+	"$schema": "./cp2p.schema.json",
+	"functions": [
+		{"name": "add", "return_type": "int", "parameters": [{"name": "a", "type": "int"}, {"name": "b", "type": "int"}], "async": true},
+		{"name": "each", "return_type": "void", "parameters": [{"name": "visit", "type": "visit_fn"}],
+		 "callbacks": [{"name": "visit_fn", "return_type": "void", "parameters": ["int"]}]}
+	],
+	"includes": ["/usr/local/include"],
+	"libraries": ["m"],
+	"types": [
+		{"name": "Point", "kind": "struct", "fields": [{"name": "x", "type": "double"}]},
+		{"name": "Color", "kind": "enum", "values": ["RED", "GREEN"], "base_type": "uint8_t"}
+	],
+	"type_mappings": [{"c_type": "myfloat", "ctypes": "ctypes.c_float", "python_hint": "float"}],
+	"output_backend": "ctypes",
+	"lib_file_names": {"linux": "libmath.so.1"}
+}`
+
+func TestSchemaValidatesSampleConfig(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteSchema(&buf); err != nil {
+		t.Fatalf("WriteSchema() error = %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+
+	if _, err := ParseConfigReader(strings.NewReader(sampleConfig)); err != nil {
+		t.Fatalf("ParseConfigReader() error = %v", err)
+	}
+	var doc any
+	if err := json.Unmarshal([]byte(sampleConfig), &doc); err != nil {
+		t.Fatalf("Sample config is not valid JSON: %v", err)
+	}
+	if err := validateSchema(schema, schema, doc, "$"); err != nil {
+		t.Errorf("Sample config does not match schema: %v", err)
+	}
+
+	invalid := []string{
+		`{"functions": [{"name": "add"}]}`,
+		`{"functions": [{"name": "add", "return_type": "int"}], "output_backend": "swig"}`,
+		`{"functions": [{"name": "add", "return_type": "int"}], "fucntions": []}`,
+		`{"functions": [{"name": "add", "return_type": "int", "async": "yes"}]}`,
+	}
+	for _, cfg := range invalid {
+		var doc any
+		if err := json.Unmarshal([]byte(cfg), &doc); err != nil {
+			t.Fatalf("Invalid config %s is not valid JSON: %v", cfg, err)
+		}
+		if err := validateSchema(schema, schema, doc, "$"); err == nil {
+			t.Errorf("Schema accepted invalid config %s", cfg)
+		}
+	}
+}
+
+// validateSchema checks value against the subset of JSON Schema that Schema
+// emits: type, properties, required, additionalProperties, items, enum and
+// local $ref
+func validateSchema(root, schema map[string]any, value any, path string) error {
+	if ref, ok := schema["$ref"].(string); ok {
+		def := root["$defs"].(map[string]any)[strings.TrimPrefix(ref, "#/$defs/")]
+		return validateSchema(root, def.(map[string]any), value, path)
+	}
+	if enum, ok := schema["enum"].([]any); ok && !slices.Contains(enum, value) {
+		return fmt.Errorf("%s: %v is not one of %v", path, value, enum)
+	}
+
+	switch schema["type"] {
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected string", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", path)
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array", path)
+		}
+		for i, item := range items {
+			if err := validateSchema(root, schema["items"].(map[string]any), item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object", path)
+		}
+		required, _ := schema["required"].([]any)
+		for _, name := range required {
+			if _, ok := obj[name.(string)]; !ok {
+				return fmt.Errorf("%s: missing required %s", path, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, v := range obj {
+			prop, ok := properties[name].(map[string]any)
+			if !ok {
+				additional, ok := schema["additionalProperties"].(map[string]any)
+				if !ok {
+					return fmt.Errorf("%s: unknown property %s", path, name)
+				}
+				prop = additional
+			}
+			if err := validateSchema(root, prop, v, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cp2p/binding"
+	"cp2p/config"
+	"cp2p/parser"
+	"cp2p/util"
+)
+
+// runDumpAST implements the dump-ast subcommand, printing what the parser
+// extracts from a C++ file without compiling it or generating bindings
+func runDumpAST(args []string) {
+	fs := flag.NewFlagSet("dump-ast", flag.ExitOnError)
+	input := fs.String("input", "", "C++ source file to parse")
+	strict := fs.Bool("strict", false, "Fail on malformed EXPORT declarations and functions using unmapped types")
+	fs.Parse(args)
+
+	if *input == "" {
+		fmt.Println("Error: --input flag is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if err := dumpAST(os.Stdout, *input, *strict); err != nil {
+		util.NewLogger().Fatalf("%v", err)
+	}
+}
+
+// dumpAST parses the C++ file at path as the default command would and
+// writes the functions, types and constants found, and any warnings, to w
+func dumpAST(w io.Writer, path string, strict bool) error {
+	opts := parser.ParseOptions{Strict: strict, IsMapped: binding.DefaultTypeRegistry().Has}
+	cfg, warnings, err := parser.ParseCppFile(path, opts)
+	if err != nil {
+		return fmt.Errorf("failed to parse C++ file: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Functions (%d):\n", len(cfg.Functions))
+	for _, fn := range cfg.Functions {
+		fmt.Fprintf(&b, "  %s\n", formatFunction(fn))
+		if fn.Description != "" {
+			fmt.Fprintf(&b, "    description: %q\n", fn.Description)
+		}
+	}
+	fmt.Fprintf(&b, "Types (%d):\n", len(cfg.Types))
+	for _, typ := range cfg.Types {
+		fmt.Fprintf(&b, "  %s %s\n", typ.Kind, typ.Name)
+		for _, field := range typ.Fields {
+			fmt.Fprintf(&b, "    %s %s\n", field.Type, field.Name)
+		}
+		for _, value := range typ.Values {
+			fmt.Fprintf(&b, "    %s\n", value)
+		}
+	}
+	fmt.Fprintf(&b, "Constants (%d):\n", len(cfg.Constants))
+	for _, c := range cfg.Constants {
+		fmt.Fprintf(&b, "  %s %s = %s\n", c.Type, c.Name, c.Value)
+	}
+	if len(warnings) > 0 {
+		fmt.Fprintf(&b, "Warnings (%d):\n", len(warnings))
+		for _, warning := range warnings {
+			fmt.Fprintf(&b, "  %s\n", warning)
+		}
+	}
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// formatFunction renders fn as a C declaration, followed by the Python name
+// and symbol where they differ from its name
+func formatFunction(fn config.FunctionConfig) string {
+	var params []string
+	for _, p := range fn.Parameters {
+		param := strings.TrimSpace(p.Type + " " + p.Name)
+		if p.Default != "" {
+			param += " = " + p.Default
+		}
+		params = append(params, param)
+	}
+	decl := fmt.Sprintf("%s %s(%s)", fn.ReturnType, fn.Name, strings.Join(params, ", "))
+	if fn.PyName() != fn.Name {
+		decl += " as " + fn.PyName()
+	}
+	if fn.CSymbol() != fn.Name {
+		decl += " @" + fn.CSymbol()
+	}
+	if fn.Async {
+		decl += " [async]"
+	}
+	return decl
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDumpAST(t *testing.T) {
+	source := filepath.Join(t.TempDir(), "math.cpp")
+	content := `
+// EXPORT: int add(int a, int b = 2) -> "Adds two integers."
+extern "C" int add(int a, int b) { return a + b; }
+
+// EXPORT: double scale(double x, double factor) -> "Scales x." @cpp_scale
+extern "C" double cpp_scale(double x, double factor) { return x * factor; }
+
+// EXPORT: int broken(
+`
+	if err := os.WriteFile(source, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+
+	var out strings.Builder
+	if err := dumpAST(&out, source, false); err != nil {
+		t.Fatalf("dumpAST() error = %v", err)
+	}
+	for _, expected := range []string{
+		"Functions (2):",
+		"int add(int a, int b = 2)",
+		`description: "Adds two integers."`,
+		"double scale(double x, double factor) @cpp_scale",
+		"Warnings (1):",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Dump missing %q:\n%s", expected, out.String())
+		}
+	}
+
+	if err := dumpAST(&out, filepath.Join(t.TempDir(), "missing.cpp"), false); err == nil {
+		t.Error("dumpAST() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"cp2p/binding"
+	"cp2p/compiler"
+	"cp2p/config"
+	"cp2p/parser"
+	"cp2p/util"
+)
+
+// wizardCompilers are the compiler preferences the init wizard accepts
+var wizardCompilers = []compiler.CompilerType{
+	compiler.CompilerAuto,
+	compiler.CompilerGCC,
+	compiler.CompilerClang,
+	compiler.CompilerMSVC,
+	compiler.CompilerIntel,
+	compiler.CompilerEmscripten,
+}
+
+// runInit implements the init subcommand, prompting on stdin for the
+// settings of a starter config
+func runInit(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	fs.Parse(args)
+
+	if err := initWizard(os.Stdin, os.Stdout); err != nil {
+		util.NewLogger().Fatalf("%v", err)
+	}
+}
+
+// initWizard asks for a C++ source file, compiler preference, output directory
+// and config path, writes a config describing the source's EXPORT comments and
+// prints the command that builds it
+func initWizard(in io.Reader, out io.Writer) error {
+	p := &prompter{in: bufio.NewReader(in), out: out}
+
+	var source string
+	var cfg *config.Config
+	for cfg == nil {
+		var err error
+		if source, err = p.ask("C++ source file", ""); err != nil {
+			return err
+		}
+		if source == "" {
+			continue
+		}
+		parsed, warnings, err := parser.ParseCppFile(source, parser.ParseOptions{IsMapped: binding.DefaultTypeRegistry().Has})
+		if err != nil {
+			fmt.Fprintf(out, "Cannot use %s: %v\n", source, err)
+			continue
+		}
+		for _, w := range warnings {
+			fmt.Fprintf(out, "Warning: %s\n", w)
+		}
+		if len(parsed.Functions) == 0 {
+			fmt.Fprintf(out, "No EXPORT comments found in %s; mark functions with // EXPORT: <signature>\n", source)
+			continue
+		}
+		cfg = parsed
+	}
+
+	var choice compiler.CompilerType
+	for choice == "" {
+		names := make([]string, len(wizardCompilers))
+		for i, c := range wizardCompilers {
+			names[i] = string(c)
+		}
+		answer, err := p.ask("Compiler ("+strings.Join(names, ", ")+")", string(compiler.CompilerAuto))
+		if err != nil {
+			return err
+		}
+		if slices.Contains(wizardCompilers, compiler.CompilerType(answer)) {
+			choice = compiler.CompilerType(answer)
+		} else {
+			fmt.Fprintf(out, "Unknown compiler %q\n", answer)
+		}
+	}
+
+	output, err := p.ask("Output directory", "./bindings")
+	if err != nil {
+		return err
+	}
+
+	var configPath string
+	for configPath == "" {
+		if configPath, err = p.ask("Config file to write", "cp2p.json"); err != nil {
+			return err
+		}
+		if util.FileExists(configPath) {
+			overwrite, err := p.ask(configPath+" exists; overwrite? (y/n)", "n")
+			if err != nil {
+				return err
+			}
+			if !strings.EqualFold(overwrite, "y") {
+				configPath = ""
+			}
+		}
+	}
+
+	err = util.WriteFileAtomic(configPath, 0644, func(w io.Writer) error {
+		return config.WriteConfig(util.NewlineWriter(w, cfg.LineEndings == config.LineEndingsCRLF), cfg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to write config: %v", err)
+	}
+
+	fmt.Fprintf(out, "Wrote %s with %d functions. Build the bindings with:\n", configPath, len(cfg.Functions))
+	fmt.Fprintf(out, "  cp2p --input %s --config %s --compiler %s --output %s\n", source, configPath, choice, output)
+	return nil
+}
+
+// prompter reads answers to questions, one per line
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// ask prints question and returns the trimmed answer, or def if the answer
+// is empty. It fails if the input ends before an answer is given.
+func (p *prompter) ask(question, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		if errors.Is(err, io.EOF) {
+			return "", fmt.Errorf("input ended before %q was answered", question)
+		}
+		return "", err
+	}
+
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
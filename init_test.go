@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestInitWizard(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "math.cpp")
+	content := `
+// EXPORT: int add(int a, int b) -> "Adds two integers."
+extern "C" int add(int a, int b) { return a + b; }
+
+// EXPORT: int get_count() -> "Returns the count."
+extern "C" int get_count() { return 0; }
+`
+	if err := os.WriteFile(source, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	configPath := filepath.Join(tmpDir, "math.json")
+
+	// A missing source and an unknown compiler are asked again; empty
+	// answers take the defaults
+	input := strings.Join([]string{
+		filepath.Join(tmpDir, "missing.cpp"),
+		source,
+		"tcc",
+		"gcc",
+		"",
+		configPath,
+	}, "\n") + "\n"
+	var out strings.Builder
+	if err := initWizard(strings.NewReader(input), &out); err != nil {
+		t.Fatalf("initWizard() error = %v\n%s", err, out.String())
+	}
+
+	cfg, err := config.ParseConfig(configPath)
+	if err != nil {
+		t.Fatalf("ParseConfig() error = %v", err)
+	}
+	if len(cfg.Functions) != 2 || cfg.Functions[0].Name != "add" || cfg.Functions[1].Name != "get_count" {
+		t.Fatalf("Wizard config functions = %+v, want add and get_count", cfg.Functions)
+	}
+	if cfg.Functions[0].Description != "Adds two integers." || len(cfg.Functions[0].Parameters) != 2 {
+		t.Errorf("Wizard config add = %+v", cfg.Functions[0])
+	}
+
+	for _, expected := range []string{
+		"Cannot use " + filepath.Join(tmpDir, "missing.cpp"),
+		`Unknown compiler "tcc"`,
+		"--config " + configPath + " --compiler gcc --output ./bindings",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Wizard output missing %q:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestInitWizardEndOfInput(t *testing.T) {
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "math.cpp")
+	if err := os.WriteFile(source, []byte("// EXPORT: int add(int a, int b) -> \"Adds two integers.\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	configPath := filepath.Join(tmpDir, "cp2p.json")
+	if err := os.WriteFile(configPath, []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	// Declining to overwrite an existing config asks for another path, which
+	// the input never gives
+	input := source + "\n\n\n" + configPath + "\nn\n"
+	var out strings.Builder
+	if err := initWizard(strings.NewReader(input), &out); err == nil {
+		t.Fatal("initWizard() should fail when input ends early")
+	}
+	if data, err := os.ReadFile(configPath); err != nil || string(data) != "{}" {
+		t.Errorf("Existing config was modified: %q, %v", data, err)
+	}
+}
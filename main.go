@@ -1,10 +1,16 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 
 	"cp2p/binding"
 	"cp2p/compiler"
@@ -13,65 +19,394 @@ import (
 	"cp2p/util"
 )
 
-var (
-	inputFile   = flag.String("input", "", "Path to the C++ source file or project entry point")
-	outputDir   = flag.String("output", "./bindings", "Output directory for generated bindings")
-	compilerOpt = flag.String("compiler", "auto", "Compiler choice (gcc, clang, msvc, auto)")
-	configFile  = flag.String("config", "", "Optional JSON config file (if not provided, will parse C++ file)")
-)
+// listFlag is a flag that may be repeated, each value holding one or more
+// comma-separated items
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
 
 func main() {
-	flag.Parse()
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "verify":
+			runVerify(os.Args[2:])
+			return
+		case "init":
+			runInit(os.Args[2:])
+			return
+		case "lint":
+			runLint(os.Args[2:])
+			return
+		case "dump-ast", "--dump-ast":
+			runDumpAST(os.Args[2:])
+			return
+		case "types", "--list-types":
+			if err := binding.WriteTypeTable(os.Stdout); err != nil {
+				os.Exit(1)
+			}
+			return
+		case "config-schema", "--config-schema":
+			if err := config.WriteSchema(os.Stdout); err != nil {
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		util.NewLogger().Fatalf("%v", err)
+	}
+}
+
+// run implements the default command, compiling the input and generating its
+// bindings, with output written to stdout
+func run(args []string, stdout io.Writer) error {
+	flags := flag.NewFlagSet("cp2p", flag.ExitOnError)
+	inputFile := flags.String("input", "", "Path to the C++ source file or project entry point, or a CMake project directory")
+	outputDir := flags.String("output", "./bindings", "Output directory for generated bindings")
+	compilerOpt := flags.String("compiler", "auto", "Compiler choice (gcc, clang, msvc, intel, emscripten, auto)")
+	compilerName := flags.String("compiler-name", "", "Compiler binary to use, such as g++-12 or clang++-15; overrides --compiler")
+	configFile := flags.String("config", "", "Optional JSON config file (if not provided, will parse C++ file)")
+	headerFile := flags.String("header", "", "Optional header whose extern \"C\" declarations are bound instead of the input's EXPORT comments")
+	var includes, libraryPaths, libraries listFlag
+	flags.Var(&includes, "include", "Include directory, comma-separated or repeated")
+	flags.Var(&libraryPaths, "library-path", "Library search directory, comma-separated or repeated")
+	flags.Var(&libraries, "library", "Library to link against by name (e.g. m), comma-separated or repeated")
+	validate := flags.Bool("validate-compiler", false, "Build a trivial shared library with the detected compiler before anything else, to report a broken toolchain early")
+	fallback := flags.Bool("fallback", false, "Retry with other detected compilers if compilation fails")
+	keepInter := flags.Bool("keep-intermediates", false, "Keep batch scripts and object files produced during compilation")
+	libFileName := flags.String("lib-file-name", "", "Library file name the generated loader opens (default: the built library's name)")
+	dryRun := flags.Bool("dry-run", false, "Print the compile command instead of building and generating bindings")
+	outputFmt := flags.String("output-format", "", "Python FFI backend for the generated module (ctypes, cffi); overrides the config")
+	outputLang := flags.String("output-lang", "", "Language of the generated bindings (python); overrides the config")
+	strict := flags.Bool("strict", false, "Fail on malformed EXPORT declarations, functions using unmapped types, functions the library does not export and outputs overwriting other files")
+	pchHeader := flags.String("precompiled-header", "", "Header to precompile once and force-include in the build (default: the config's)")
+	optLevel := flags.String("opt-level", "", "Optimization level: -O0, -O1, -O2, -O3, -Os or -Og (default: the config's, else -O2)")
+	strictCompile := flags.Bool("strict-compile", false, "Fail the build if the compiler reports warnings, even though it succeeded")
+	jobs := flags.Int("jobs", runtime.NumCPU(), "Maximum number of compilations run in parallel, CMake build jobs included; 1 compiles serially")
+	reproduce := flags.Bool("reproducible", false, "Generate byte-identical output across runs, without timestamps or absolute paths")
+	exclude := flags.String("exclude", "", "Comma-separated functions to leave unbound, by C or Python name")
+	genTests := flags.Bool("generate-tests", false, "Also generate test_<module>.py with pytest smoke tests of the bound functions")
+	force := flags.Bool("force", false, "Regenerate the bindings even if the config and library are unchanged since they were generated")
+	embedLib := flags.Bool("embed-library", false, "Embed the library in the generated module, base64-encoded, for single-file distribution")
+	flags.Parse(args)
 
 	// Validate required flags
 	if *inputFile == "" {
-		fmt.Println("Error: --input flag is required")
-		flag.Usage()
-		os.Exit(1)
+		flags.Usage()
+		return errors.New("--input flag is required")
+	}
+
+	if _, err := os.Stat(*inputFile); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("input file not found: %s", *inputFile)
+		}
+		return fmt.Errorf("failed to read input file: %v", err)
 	}
 
 	// Create output directory if it doesn't exist
-	if err := os.MkdirAll(*outputDir, 0755); err != nil {
-		fmt.Printf("Error creating output directory: %v\n", err)
-		os.Exit(1)
+	if err := util.EnsureWritableDir(*outputDir); err != nil {
+		return fmt.Errorf("failed to prepare output directory: %w", err)
+	}
+
+	// A CMake project is built with its own build, so it has no source to
+	// parse and needs a config
+	projectDir, isCMake := compiler.IsCMakeProject(*inputFile)
+	if isCMake && *configFile == "" {
+		return errors.New("--config is required when --input is a CMake project")
 	}
 
 	// Initialize logger
-	logger := util.NewLogger()
+	logger := util.NewLoggerWithWriter(stdout)
 
-	// Detect compiler
-	detectedCompiler, err := compiler.DetectCompiler(compiler.CompilerType(*compilerOpt))
-	if err != nil {
-		logger.Fatalf("Failed to detect compiler: %v", err)
+	// Detect compiler. CMake picks its own unless one is named.
+	var detectedCompiler *compiler.CompilerInfo
+	var err error
+	if !isCMake || compiler.CompilerType(*compilerOpt) != compiler.CompilerAuto || *compilerName != "" {
+		detectedCompiler, err = compiler.DetectCompilerWithOptions(compiler.CompilerType(*compilerOpt), compiler.DetectOptions{Name: *compilerName, Validate: *validate})
+		if err != nil {
+			return fmt.Errorf("failed to detect compiler: %w", err)
+		}
 	}
 
 	// Parse config or C++ file
 	var cfg *config.Config
+	var parseWarnings []parser.Warning
+	var excluded []string
+	if *exclude != "" {
+		excluded = strings.Split(*exclude, ",")
+	}
+	parseOpts := parser.ParseOptions{Strict: *strict, IsMapped: binding.DefaultTypeRegistry().Has, Exclude: excluded}
 	if *configFile != "" {
 		cfg, err = config.ParseConfig(*configFile)
 		if err != nil {
-			logger.Fatalf("Failed to parse config file: %v", err)
+			return fmt.Errorf("failed to parse config file: %w", err)
+		}
+		cfg.Exclude = append(cfg.Exclude, excluded...)
+		cfg.SourceFile = *inputFile
+	} else if *headerFile != "" {
+		cfg, parseWarnings, err = parser.ParseFile(*headerFile, parseOpts)
+		if err != nil {
+			return fmt.Errorf("failed to parse header file: %w", err)
 		}
 	} else {
-		cfg, err = parser.ParseCppFile(*inputFile)
+		cfg, parseWarnings, err = parser.ParseCppFile(*inputFile, parseOpts)
 		if err != nil {
-			logger.Fatalf("Failed to parse C++ file: %v", err)
+			return fmt.Errorf("failed to parse C++ file: %w", err)
 		}
 	}
+	for _, w := range parseWarnings {
+		logger.Warn("%s", w)
+	}
+
+	if *outputFmt != "" {
+		cfg.OutputBackend = *outputFmt
+	}
+	if *outputLang != "" {
+		cfg.OutputLang = *outputLang
+	}
+	if *libFileName != "" {
+		cfg.LibFileName = *libFileName
+	}
+	if *reproduce {
+		cfg.Reproducible = true
+	}
+	if *genTests {
+		cfg.GenerateTests = true
+	}
+	if *embedLib {
+		cfg.EmbedLibrary = true
+	}
+
+	// Warn about types whose size varies across platforms
+	for _, w := range binding.CheckABISafety(cfg, runtime.GOOS) {
+		logger.Warn("%s", w)
+	}
 
 	// Compile C++ code
-	libPath, err := compiler.Compile(*inputFile, *outputDir, detectedCompiler)
-	if err != nil {
-		logger.Fatalf("Failed to compile C++ code: %v", err)
+	compileOpts := compiler.DefaultCompileOptions()
+	compileOpts.IncludePaths = slices.Concat(cfg.AllIncludes(), includes)
+	if detectedCompiler != nil {
+		compileOpts.IncludePaths = slices.Concat(detectedCompiler.CommandIncludePaths(), compileOpts.IncludePaths)
+	}
+	compileOpts.LibraryPaths = libraryPaths
+	compileOpts.Libraries = slices.Concat(cfg.AllLibraries(), libraries)
+	compileOpts.Fallback = *fallback
+	compileOpts.KeepIntermediates = *keepInter
+	compileOpts.FailOnWarnings = *strictCompile
+	if *optLevel != "" {
+		cfg.OptimizationLevel = *optLevel
+	}
+	if cfg.OptimizationLevel != "" {
+		compileOpts.OptimizationLevel = cfg.OptimizationLevel
+	}
+	if *pchHeader != "" {
+		cfg.PrecompiledHeader = *pchHeader
+	}
+	compileOpts.PrecompiledHeader = cfg.PrecompiledHeader
+	compileOpts.ExtraFlags = cfg.CompilerFlags
+	compileOpts.LinkerFlags = cfg.LinkerFlags
+	compileOpts.Jobs = *jobs
+	for _, fn := range cfg.Functions {
+		compileOpts.ExportedFunctions = append(compileOpts.ExportedFunctions, fn.CSymbol())
+	}
+
+	if *dryRun && isCMake {
+		fmt.Fprintln(stdout, compiler.CMakeCommandString(projectDir, *outputDir, detectedCompiler, compileOpts))
+		return nil
+	}
+	if *dryRun {
+		command, err := compiler.CompileCommandString(*inputFile, *outputDir, detectedCompiler, compileOpts)
+		if err != nil {
+			return fmt.Errorf("invalid compile options: %w", err)
+		}
+		fmt.Fprintln(stdout, command)
+		return nil
 	}
 
-	// Generate Python bindings
 	moduleName := filepath.Base(*inputFile)
 	moduleName = moduleName[:len(moduleName)-len(filepath.Ext(moduleName))]
+	if isCMake {
+		absDir, err := filepath.Abs(projectDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve project directory: %v", err)
+		}
+		moduleName = filepath.Base(absDir)
+	}
 
-	if err := binding.GenerateBindings(moduleName, libPath, *outputDir, cfg); err != nil {
-		logger.Fatalf("Failed to generate Python bindings: %v", err)
+	// An output directory holding other files, such as the source directory,
+	// may have files named like the outputs; the manifest of an earlier run
+	// tells generated ones from the user's own. WebAssembly builds write no
+	// manifest, so they are not checked.
+	if isCMake || detectedCompiler.Type != compiler.CompilerEmscripten {
+		outputs := binding.OutputFiles(moduleName, *outputDir, cfg)
+		if !isCMake {
+			outputs = append(compiler.OutputFiles(*inputFile, *outputDir, detectedCompiler), outputs...)
+		}
+		userFiles := binding.UserFiles(*outputDir, outputs)
+		if len(userFiles) > 0 && *strict {
+			return fmt.Errorf("output would overwrite %v, which cp2p did not generate; choose another --output", userFiles)
+		} else if len(userFiles) > 0 {
+			logger.Warn("Overwriting %v, which cp2p did not generate", userFiles)
+		}
 	}
 
-	logger.Info(fmt.Sprintf("Successfully generated Python bindings in %s", *outputDir))
+	var libPath string
+	var usedCompiler *compiler.CompilerInfo
+	if isCMake {
+		libPath, err = compiler.BuildCMake(projectDir, *outputDir, detectedCompiler, compileOpts)
+		if err != nil {
+			return fmt.Errorf("failed to build CMake project: %w", err)
+		}
+		usedCompiler = detectedCompiler
+		if usedCompiler == nil {
+			usedCompiler = &compiler.CompilerInfo{Type: compiler.CompilerAuto}
+		}
+	} else {
+		libPath, usedCompiler, err = compiler.CompileWithFallback(*inputFile, *outputDir, detectedCompiler, compileOpts)
+		if err != nil {
+			return fmt.Errorf("failed to compile C++ code: %w", err)
+		}
+	}
+	cfg.BuildFiles = []string{libPath}
+	if !isCMake {
+		cfg.BuildFiles = compiler.OutputFiles(*inputFile, *outputDir, usedCompiler)
+	}
+	if !isCMake && usedCompiler.Path != detectedCompiler.Path {
+		logger.Warn("Compilation with %s failed, fell back to %s (%s)", detectedCompiler.Type, usedCompiler.Type, usedCompiler.Path)
+	}
+
+	// WebAssembly builds are loaded from JavaScript through the loader em++
+	// emits next to the module, so there are no Python bindings to generate
+	if usedCompiler.Type == compiler.CompilerEmscripten {
+		jsPath := strings.TrimSuffix(libPath, filepath.Ext(libPath)) + ".js"
+		logger.Info("Built WebAssembly module %s with JavaScript loader %s", libPath, jsPath)
+		return nil
+	}
+
+	// Bindings for symbols the library does not export fail at import. Without
+	// nm or dumpbin the check is skipped.
+	missing, err := compiler.MissingSymbols(libPath, usedCompiler, compileOpts.ExportedFunctions)
+	if err != nil && !errors.Is(err, compiler.ErrNoSymbolTool) {
+		logger.Warn("Could not list exported symbols: %v", err)
+	}
+	if len(missing) > 0 && *strict {
+		return fmt.Errorf("library does not export %v; check the names and that they are declared extern \"C\"", missing)
+	} else if len(missing) > 0 {
+		logger.Warn("Library does not export %v; check the names and that they are declared extern \"C\"", missing)
+	}
+
+	// Generate Python bindings
+	// Bindings generated from the same config and library need not be generated again
+	result, upToDate := binding.UpToDate(moduleName, libPath, *outputDir, cfg)
+	if upToDate && !*force {
+		logger.Info("Python bindings in %s are up to date; use --force to regenerate them", *outputDir)
+	} else {
+		result, err = binding.GenerateBindings(moduleName, libPath, *outputDir, cfg)
+		if err != nil {
+			return fmt.Errorf("failed to generate Python bindings: %w", err)
+		}
+		logger.Info(fmt.Sprintf("Successfully generated Python bindings in %s", *outputDir))
+	}
+	logger.Info("Bound %d functions, generated %d types, wrote %d files",
+		result.FunctionsBound, result.TypesGenerated, len(result.FilesWritten))
+	if len(result.SkippedFunctions) > 0 && *strict {
+		return fmt.Errorf("skipped functions %v using unmapped types %v", result.SkippedFunctions, result.UnmappedTypes)
+	} else if len(result.SkippedFunctions) > 0 {
+		logger.Warn("Skipped functions %v using unmapped types %v", result.SkippedFunctions, result.UnmappedTypes)
+	}
+	return nil
+}
+
+// runVerify implements the verify subcommand, importing each generated module
+// in a Python subprocess to confirm it loads
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	dir := fs.String("output", "./bindings", "Directory containing the generated bindings")
+	module := fs.String("module", "", "Module to verify (default: every module generated in the output directory)")
+	fs.Parse(args)
+
+	logger := util.NewLogger()
+
+	modules := []string{*module}
+	if *module == "" {
+		var err error
+		modules, err = binding.GeneratedModules(*dir)
+		if err != nil {
+			logger.Fatalf("Failed to list generated modules: %v", err)
+		}
+		if len(modules) == 0 {
+			logger.Fatalf("No generated modules found in %s", *dir)
+		}
+	}
+
+	failed := 0
+	for _, name := range modules {
+		err := binding.Verify(*dir, name)
+		if errors.Is(err, binding.ErrPythonNotFound) {
+			logger.Warn("Skipping verification: %v", err)
+			return
+		}
+		if err != nil {
+			logger.Error("%v", err)
+			failed++
+			continue
+		}
+		logger.Info("Module %s loaded successfully", name)
+	}
+
+	if failed > 0 {
+		logger.Fatalf("%d of %d modules failed verification", failed, len(modules))
+	}
+}
+
+// runLint implements the lint subcommand, reporting likely mistakes in a
+// config. It exits with status 1 if any are errors.
+func runLint(args []string) {
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	configPath := fs.String("config", "", "Config file to check")
+	fs.Parse(args)
+
+	if *configPath == "" {
+		fmt.Println("Error: --config flag is required")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	logger := util.NewLogger()
+
+	f, err := os.Open(*configPath)
+	if err != nil {
+		logger.Fatalf("Failed to read config file: %v", err)
+	}
+	defer f.Close()
+	cfg, err := config.DecodeConfig(f)
+	if err != nil {
+		logger.Fatalf("Failed to parse config file: %v", err)
+	}
+
+	gen := binding.NewGenerator("", "", "", cfg)
+	errorCount := 0
+	issues := cfg.Lint(gen.KnowsType)
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if issue.Severity == config.SeverityError {
+			errorCount++
+		}
+	}
+	fmt.Printf("%d errors, %d warnings\n", errorCount, len(issues)-errorCount)
+	if errorCount > 0 {
+		os.Exit(1)
+	}
 }
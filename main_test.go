@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"cp2p/compiler"
+)
+
+func TestRunIncludeFlags(t *testing.T) {
+	detected, err := compiler.DetectCompiler(compiler.CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+	if detected.Type == compiler.CompilerMSVC {
+		t.Skip("GCC-style flags")
+	}
+
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "math.cpp")
+	content := `// EXPORT: int add(int a, int b) -> "Adds two integers."
+extern "C" int add(int a, int b) { return a + b; }
+`
+	if err := os.WriteFile(source, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	includeA := filepath.Join(tmpDir, "include_a")
+	includeB := filepath.Join(tmpDir, "include_b")
+	includeC := filepath.Join(tmpDir, "include_c")
+	libDir := filepath.Join(tmpDir, "lib")
+
+	var out strings.Builder
+	err = run([]string{
+		"--input", source,
+		"--output", filepath.Join(tmpDir, "bindings"),
+		"--dry-run",
+		"--include", includeA + "," + includeB,
+		"--include", includeC,
+		"--library-path", libDir,
+		"--library", "m",
+	}, &out)
+	if err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+
+	command := out.String()
+	for _, expected := range []string{"-I" + includeA, "-I" + includeB, "-I" + includeC, "-L" + libDir, "-lm"} {
+		if !strings.Contains(command, expected) {
+			t.Errorf("Compile command missing %q: %s", expected, command)
+		}
+	}
+	// The compiler searches its own system directories without being told
+	for _, path := range detected.IncludePaths {
+		if strings.Contains(command, "-I"+path+" ") {
+			t.Errorf("Compile command passes system include directory %s: %s", path, command)
+		}
+	}
+}
+
+func TestRunJobs(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tmpDir, "CMakeLists.txt"), []byte("project(math CXX)\n"), 0644); err != nil {
+		t.Fatalf("Failed to write CMakeLists.txt: %v", err)
+	}
+	configPath := filepath.Join(tmpDir, "math.json")
+	if err := os.WriteFile(configPath, []byte(`{"functions": [{"name": "add", "return_type": "int"}]}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	args := []string{"--input", tmpDir, "--config", configPath, "--output", filepath.Join(tmpDir, "bindings"), "--dry-run"}
+
+	var out strings.Builder
+	if err := run(append(args, "--jobs", "3"), &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "--parallel 3") {
+		t.Errorf("CMake build does not run 3 jobs: %s", out.String())
+	}
+
+	out.Reset()
+	if err := run(args, &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if want := fmt.Sprintf("--parallel %d", runtime.NumCPU()); !strings.Contains(out.String(), want) {
+		t.Errorf("CMake build does not default to %s: %s", want, out.String())
+	}
+}
+
+func TestRunOutputInSourceDir(t *testing.T) {
+	if _, err := compiler.DetectCompiler(compiler.CompilerAuto); err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "math.cpp")
+	content := `// EXPORT: int add(int a, int b) -> "Adds two integers."
+extern "C" int add(int a, int b) { return a + b; }
+`
+	if err := os.WriteFile(source, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	userModule := filepath.Join(tmpDir, "math.py")
+	userContent := "# The user's own math.py\n"
+	if err := os.WriteFile(userModule, []byte(userContent), 0644); err != nil {
+		t.Fatalf("Failed to write user module: %v", err)
+	}
+
+	// Strict mode refuses to overwrite the user's file
+	var out strings.Builder
+	err := run([]string{"--input", source, "--output", tmpDir, "--strict"}, &out)
+	if err == nil || !strings.Contains(err.Error(), userModule) {
+		t.Fatalf("run() with --strict error = %v, want one naming %s", err, userModule)
+	}
+	if data, _ := os.ReadFile(userModule); string(data) != userContent {
+		t.Error("run() with --strict overwrote the user's file")
+	}
+
+	// Otherwise it warns and overwrites it
+	out.Reset()
+	if err := run([]string{"--input", source, "--output", tmpDir}, &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !strings.Contains(out.String(), "Overwriting") {
+		t.Errorf("run() did not warn about overwriting %s:\n%s", userModule, out.String())
+	}
+
+	// Files from an earlier run are known to be generated
+	out.Reset()
+	if err := run([]string{"--input", source, "--output", tmpDir, "--strict", "--force"}, &out); err != nil {
+		t.Fatalf("Second run() error = %v", err)
+	}
+	if strings.Contains(out.String(), "Overwriting") {
+		t.Errorf("Second run() warned about its own files:\n%s", out.String())
+	}
+}
+
+func TestRunOptLevel(t *testing.T) {
+	detected, err := compiler.DetectCompiler(compiler.CompilerAuto)
+	if err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+	want := "-Os"
+	if detected.Type == compiler.CompilerMSVC {
+		want = "/O1"
+	}
+
+	tmpDir := t.TempDir()
+	source := filepath.Join(tmpDir, "math.cpp")
+	content := `// EXPORT: int add(int a, int b) -> "Adds two integers."
+extern "C" int add(int a, int b) { return a + b; }
+`
+	if err := os.WriteFile(source, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write source: %v", err)
+	}
+	args := []string{"--input", source, "--output", filepath.Join(tmpDir, "bindings"), "--dry-run"}
+
+	var out strings.Builder
+	if err := run(append(args, "--opt-level", "-Os"), &out); err != nil {
+		t.Fatalf("run() error = %v", err)
+	}
+	if !strings.Contains(out.String(), " "+want+" ") || strings.Contains(out.String(), " -O2 ") {
+		t.Errorf("Compile command does not optimize with %s only: %s", want, out.String())
+	}
+
+	if err := run(append(args, "--opt-level", "-O9"), &out); err == nil {
+		t.Error("run() with an unknown optimization level succeeded")
+	}
+}
@@ -5,77 +5,248 @@ import (
 	"fmt"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 
 	"cp2p/config"
 )
 
-// ParseCppFile parses a C++ file and extracts functions marked with EXPORT comments
-func ParseCppFile(filePath string) (*config.Config, error) {
+var (
+	exportRegex  = regexp.MustCompile(`//\s*EXPORT:\s*([\w\s*&:]*?[\w*&])\s*\b(\w+)\s*\((.*?)\)\s*->\s*"([^"]*)"(?:\s*@\s*(\w+))?`)
+	exportMarker = regexp.MustCompile(`//\s*EXPORT:`)
+	constRegex   = regexp.MustCompile(`//\s*EXPORT_CONST:\s*([\w\s*&:]*?[\w*&])\s*\b(\w+)\s*=\s*(.+?)\s*;?\s*(?:->\s*"([^"]*)")?\s*$`)
+	descRegex    = regexp.MustCompile(`->\s*"[^"]*"`)
+)
+
+// Warning reports an EXPORT comment that could not be parsed
+type Warning struct {
+	Line    int
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+// ParseOptions controls how strictly source files are parsed
+type ParseOptions struct {
+	// Strict turns malformed EXPORT declarations and functions using unmapped
+	// types into errors instead of skipping them
+	Strict bool
+	// IsMapped reports whether a C type can be bound. When nil, types are not
+	// checked.
+	IsMapped func(cType string) bool
+	// Exclude lists functions to leave out of the parsed config, as for
+	// config.Config.Exclude
+	Exclude []string
+}
+
+// apply drops the excluded functions from a parsed cfg, then checks the types
+// of the remaining ones
+func (o ParseOptions) apply(cfg *config.Config) error {
+	cfg.Exclude = o.Exclude
+	cfg.Functions = slices.DeleteFunc(cfg.Functions, cfg.IsExcluded)
+	return o.checkTypes(cfg.Functions)
+}
+
+// checkTypes returns an error naming the first unmapped type used by
+// functions when strict type checking is enabled
+func (o ParseOptions) checkTypes(functions []config.FunctionConfig) error {
+	if !o.Strict || o.IsMapped == nil {
+		return nil
+	}
+	for _, fn := range functions {
+		if !o.IsMapped(fn.ReturnType) {
+			return fmt.Errorf("function %s returns unmapped type %s", fn.Name, fn.ReturnType)
+		}
+		for _, p := range fn.Parameters {
+			if !o.IsMapped(p.Type) {
+				return fmt.Errorf("function %s parameter %s has unmapped type %s", fn.Name, p.Name, p.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseCppFile parses a C++ file and extracts functions marked with EXPORT comments.
+// An optional trailing "@symbol" binds the function to a differently named export:
+//
+//	// EXPORT: int add(int a, int b) -> "Adds two integers." @cpp_add_v2
+//
+// EXPORT comments that do not match this form are skipped and reported as
+// warnings, or fail the parse in strict mode. Constants are marked with
+// EXPORT_CONST and an optional description:
+//
+//	// EXPORT_CONST: int MAX = 100 -> "Largest supported size."
+func ParseCppFile(filePath string, opts ParseOptions) (*config.Config, []Warning, error) {
 	file, err := os.Open(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file: %v", err)
+		return nil, nil, fmt.Errorf("failed to open file: %v", err)
 	}
 	defer file.Close()
 
 	scanner := bufio.NewScanner(file)
 	var functions []config.FunctionConfig
-	exportRegex := regexp.MustCompile(`//\s*EXPORT:\s*(\w+)\s+(\w+)\s*\((.*?)\)\s*->\s*"([^"]*)"`)
+	var constants []config.ConstantConfig
+	var warnings []Warning
 
-	for scanner.Scan() {
+	for lineNum := 1; scanner.Scan(); lineNum++ {
 		line := scanner.Text()
+		if m := constRegex.FindStringSubmatch(line); m != nil {
+			constants = append(constants, config.ConstantConfig{
+				Name:        m[2],
+				Type:        canonicalType(m[1]),
+				Value:       m[3],
+				Description: m[4],
+			})
+			continue
+		}
+
 		matches := exportRegex.FindStringSubmatch(line)
-		if matches != nil {
-			// matches[1] = return type
-			// matches[2] = function name
-			// matches[3] = parameters
-			// matches[4] = description
-			fn := config.FunctionConfig{
-				Name:        matches[2],
-				Description: matches[4],
-				ReturnType:  matches[1],
-				Parameters:  parseParameters(matches[3]),
+		if matches == nil {
+			if loc := exportMarker.FindStringIndex(line); loc != nil {
+				w := Warning{Line: lineNum, Message: exportProblem(line[loc[1]:])}
+				if opts.Strict {
+					return nil, nil, fmt.Errorf("%s", w)
+				}
+				warnings = append(warnings, w)
 			}
-			functions = append(functions, fn)
+			continue
 		}
+
+		// matches[1] = return type
+		// matches[2] = function name
+		// matches[3] = parameters
+		// matches[4] = description
+		// matches[5] = exported symbol (optional)
+		fn := config.FunctionConfig{
+			Name:        matches[2],
+			Description: matches[4],
+			ReturnType:  canonicalType(matches[1]),
+			Parameters:  parseParameters(matches[3]),
+			Symbol:      matches[5],
+		}
+		functions = append(functions, fn)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %v", err)
 	}
+	cfg := &config.Config{
+		Functions:  functions,
+		Constants:  constants,
+		Includes:   []string{},
+		Libraries:  []string{},
+		SourceFile: filePath,
+	}
+	if err := opts.apply(cfg); err != nil {
+		return nil, nil, err
+	}
+	return cfg, warnings, nil
+}
 
-	return &config.Config{
-		Functions: functions,
-		Includes:  []string{},
-		Libraries: []string{},
-	}, nil
+// exportProblem describes why the declaration following an EXPORT marker
+// does not parse
+func exportProblem(decl string) string {
+	signature, _, hasArrow := strings.Cut(decl, "->")
+	switch {
+	case strings.Count(signature, "(") != strings.Count(signature, ")"):
+		return "malformed EXPORT declaration: mismatched parentheses"
+	case !strings.Contains(signature, "("):
+		return "malformed EXPORT declaration: missing parameter list"
+	case !hasArrow:
+		return `malformed EXPORT declaration: missing -> "description"`
+	case !descRegex.MatchString(decl):
+		return "malformed EXPORT declaration: description must be a double-quoted string"
+	default:
+		return `malformed EXPORT declaration: expected <return type> <name>(<parameters>) -> "description"`
+	}
+}
+
+// typeKeywords are identifiers that can end a type, so a parameter ending in
+// one of them is unnamed
+var typeKeywords = map[string]bool{
+	"void": true, "bool": true, "char": true, "short": true, "int": true,
+	"long": true, "float": true, "double": true, "signed": true,
+	"unsigned": true, "const": true, "volatile": true,
 }
 
+var (
+	paramNameRegex = regexp.MustCompile(`^(.*?)\b(\w+)\s*$`)
+	pointerRegex   = regexp.MustCompile(`\s*([*&])`)
+)
+
 func parseParameters(paramStr string) []config.Param {
-	if paramStr == "" {
+	paramStr = strings.TrimSpace(paramStr)
+	if paramStr == "" || paramStr == "void" {
 		return []config.Param{}
 	}
 
-	params := strings.Split(paramStr, ",")
+	params := splitParameters(paramStr)
 	var result []config.Param
 
-	for _, p := range params {
+	for i, p := range params {
 		p = strings.TrimSpace(p)
 		if p == "" {
 			continue
 		}
 
-		// Split type and name
-		parts := strings.Fields(p)
-		if len(parts) >= 2 {
-			paramType := parts[0]
-			paramName := parts[1]
-			// Remove any trailing semicolons or other characters
-			paramName = strings.TrimRight(paramName, ";")
-
-			result = append(result, config.Param{
-				Name:        paramName,
-				Type:        paramType,
-				Description: "", // Could be enhanced to parse parameter descriptions from comments
-			})
+		// A C++ default argument becomes the Python default
+		var defaultValue string
+		if decl, value, ok := strings.Cut(p, "="); ok {
+			p, defaultValue = strings.TrimSpace(decl), strings.TrimSpace(value)
+		}
+
+		// Everything up to the trailing identifier is the type, so qualifiers
+		// and pointers stay with it: "const char *name" -> "const char*", "name"
+		paramType, paramName := p, fmt.Sprintf("arg%d", i)
+		if m := paramNameRegex.FindStringSubmatch(p); m != nil && strings.TrimSpace(m[1]) != "" && !typeKeywords[m[2]] {
+			paramType, paramName = m[1], m[2]
 		}
+
+		result = append(result, config.Param{
+			Name:        paramName,
+			Type:        canonicalType(paramType),
+			Default:     defaultValue,
+			Description: "", // Could be enhanced to parse parameter descriptions from comments
+		})
 	}
 
 	return result
 }
+
+// canonicalType collapses whitespace in a C type and attaches pointer and
+// reference markers to the type, so "char *" and "char*" read the same
+func canonicalType(t string) string {
+	t = strings.Join(strings.Fields(t), " ")
+	return pointerRegex.ReplaceAllString(t, "$1")
+}
+
+// splitParameters splits a parameter list at its top-level commas, keeping
+// those nested in brackets, as in std::map<int, int>, or in quoted default
+// arguments
+func splitParameters(paramStr string) []string {
+	var params []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(paramStr); i++ {
+		c := paramStr[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++ // Skip the escaped character
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '<' || c == '(' || c == '[' || c == '{':
+			depth++
+		case (c == '>' || c == ')' || c == ']' || c == '}') && depth > 0:
+			depth--
+		case c == ',' && depth == 0:
+			params = append(params, paramStr[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, paramStr[start:])
+}
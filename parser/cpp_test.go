@@ -0,0 +1,225 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cp2p/config"
+)
+
+// writeSource writes content to a temporary C++ file and returns its path
+func writeSource(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "test.cpp")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	return path
+}
+
+func TestParseCppFileSymbol(t *testing.T) {
+	path := writeSource(t, `
+// EXPORT: int add(int a, int b) -> "Adds two integers." @cpp_add_v2
+int cpp_add_v2(int a, int b) { return a + b; }
+
+// EXPORT: int sub(int a, int b) -> "Subtracts two integers."
+int sub(int a, int b) { return a - b; }
+`)
+
+	cfg, _, err := ParseCppFile(path, ParseOptions{})
+	if err != nil {
+		t.Fatalf("ParseCppFile() error = %v", err)
+	}
+	if len(cfg.Functions) != 2 {
+		t.Fatalf("Expected 2 functions, got %d", len(cfg.Functions))
+	}
+
+	add := cfg.Functions[0]
+	if add.Name != "add" || add.Symbol != "cpp_add_v2" {
+		t.Errorf("Expected add bound to cpp_add_v2, got %s bound to %s", add.Name, add.Symbol)
+	}
+	if add.Description != "Adds two integers." {
+		t.Errorf("Unexpected description %q", add.Description)
+	}
+
+	sub := cfg.Functions[1]
+	if sub.Symbol != "" || sub.CSymbol() != "sub" {
+		t.Errorf("Expected sub to default to its own symbol, got %q", sub.CSymbol())
+	}
+}
+
+func TestParseParameters(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []config.Param
+	}{
+		{input: "", want: []config.Param{}},
+		{input: "void", want: []config.Param{}},
+		{input: " void ", want: []config.Param{}},
+		{input: "  ", want: []config.Param{}},
+		{input: "const char* s", want: []config.Param{{Name: "s", Type: "const char*"}}},
+		{input: "const char *s", want: []config.Param{{Name: "s", Type: "const char*"}}},
+		{input: "int* p", want: []config.Param{{Name: "p", Type: "int*"}}},
+		{input: "int * * pp", want: []config.Param{{Name: "pp", Type: "int**"}}},
+		{input: "unsigned long n", want: []config.Param{{Name: "n", Type: "unsigned long"}}},
+		{input: "double& out", want: []config.Param{{Name: "out", Type: "double&"}}},
+		{input: "int a, unsigned long", want: []config.Param{{Name: "a", Type: "int"}, {Name: "arg1", Type: "unsigned long"}}},
+		{input: "double x, int base = 10", want: []config.Param{{Name: "x", Type: "double"}, {Name: "base", Type: "int", Default: "10"}}},
+		{input: "std::map<int,int> m", want: []config.Param{{Name: "m", Type: "std::map<int,int>"}}},
+		{input: "std::pair<int,int> p, int n", want: []config.Param{{Name: "p", Type: "std::pair<int,int>"}, {Name: "n", Type: "int"}}},
+		{input: "std::map<std::string, std::vector<int>> &m", want: []config.Param{{Name: "m", Type: "std::map<std::string, std::vector<int>>&"}}},
+		{input: "std::array<int, 3>, int", want: []config.Param{{Name: "arg0", Type: "std::array<int, 3>"}, {Name: "arg1", Type: "int"}}},
+		{input: `const char* sep = ", ", int n`, want: []config.Param{{Name: "sep", Type: "const char*", Default: `", "`}, {Name: "n", Type: "int"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := parseParameters(tt.input)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseParameters(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCppFileQualifiedReturnType(t *testing.T) {
+	path := writeSource(t, `
+// EXPORT: const char * greet(const char *name) -> "Greets someone."
+// EXPORT: unsigned long count() -> "Counts things."
+`)
+
+	cfg, _, err := ParseCppFile(path, ParseOptions{})
+	if err != nil {
+		t.Fatalf("ParseCppFile() error = %v", err)
+	}
+	if len(cfg.Functions) != 2 {
+		t.Fatalf("Expected 2 functions, got %d", len(cfg.Functions))
+	}
+
+	greet := cfg.Functions[0]
+	if greet.Name != "greet" || greet.ReturnType != "const char*" {
+		t.Errorf("Expected greet returning const char*, got %s returning %q", greet.Name, greet.ReturnType)
+	}
+	if len(greet.Parameters) != 1 || greet.Parameters[0].Type != "const char*" {
+		t.Errorf("Unexpected greet parameters %+v", greet.Parameters)
+	}
+
+	count := cfg.Functions[1]
+	if count.Name != "count" || count.ReturnType != "unsigned long" {
+		t.Errorf("Expected count returning unsigned long, got %s returning %q", count.Name, count.ReturnType)
+	}
+}
+
+func TestParseCppFileMalformedExport(t *testing.T) {
+	path := writeSource(t, `#include <cmath>
+
+// EXPORT: int add(int a, int b) -> "Adds two integers."
+int add(int a, int b) { return a + b; }
+
+// EXPORT: int sub(int a, int b) "Subtracts two integers."
+int sub(int a, int b) { return a - b; }
+
+// EXPORT: double root(double x -> "Square root."
+double root(double x) { return std::sqrt(x); }
+`)
+
+	cfg, warnings, err := ParseCppFile(path, ParseOptions{})
+	if err != nil {
+		t.Fatalf("ParseCppFile() error = %v", err)
+	}
+	if len(cfg.Functions) != 1 || cfg.Functions[0].Name != "add" {
+		t.Errorf("Expected only add to parse, got %+v", cfg.Functions)
+	}
+
+	want := []Warning{
+		{Line: 6, Message: `malformed EXPORT declaration: missing -> "description"`},
+		{Line: 9, Message: "malformed EXPORT declaration: mismatched parentheses"},
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("ParseCppFile() warnings = %v, want %v", warnings, want)
+	}
+}
+
+func TestParseCppFileStrict(t *testing.T) {
+	malformed := writeSource(t, `
+// EXPORT: int add(int a, int b) -> "Adds two integers."
+int add(int a, int b) { return a + b; }
+
+// EXPORT: int sub(int a, int b "Subtracts two integers."
+int sub(int a, int b) { return a - b; }
+`)
+	if _, _, err := ParseCppFile(malformed, ParseOptions{}); err != nil {
+		t.Errorf("Lenient ParseCppFile() error = %v", err)
+	}
+	if _, _, err := ParseCppFile(malformed, ParseOptions{Strict: true}); err == nil || !strings.Contains(err.Error(), "line 5") {
+		t.Errorf("Strict ParseCppFile() error = %v, want line 5 reported", err)
+	}
+
+	unmapped := writeSource(t, `
+// EXPORT: void draw(Widget* w) -> "Draws a widget."
+void draw(Widget* w) {}
+`)
+	isMapped := func(t string) bool { return t == "void" || t == "int" }
+	if _, _, err := ParseCppFile(unmapped, ParseOptions{IsMapped: isMapped}); err != nil {
+		t.Errorf("Lenient ParseCppFile() error = %v", err)
+	}
+	if _, _, err := ParseCppFile(unmapped, ParseOptions{Strict: true, IsMapped: isMapped}); err == nil || !strings.Contains(err.Error(), "Widget*") {
+		t.Errorf("Strict ParseCppFile() error = %v, want unmapped Widget* reported", err)
+	}
+}
+
+func TestParseCppFileExclude(t *testing.T) {
+	path := writeSource(t, `
+// EXPORT: int add(int a, int b) -> "Adds two integers."
+int add(int a, int b) { return a + b; }
+
+// EXPORT: void dump(Widget* w) -> "Prints internal state."
+void dump(Widget* w) {}
+`)
+
+	isMapped := func(t string) bool { return t == "int" }
+	cfg, _, err := ParseCppFile(path, ParseOptions{Strict: true, IsMapped: isMapped, Exclude: []string{"dump"}})
+	if err != nil {
+		t.Fatalf("ParseCppFile() error = %v, want excluded dump to be ignored", err)
+	}
+	if len(cfg.Functions) != 1 || cfg.Functions[0].Name != "add" {
+		t.Errorf("Expected only add, got %+v", cfg.Functions)
+	}
+	if !reflect.DeepEqual(cfg.Exclude, []string{"dump"}) {
+		t.Errorf("Expected the config to record the exclusion, got %v", cfg.Exclude)
+	}
+}
+
+func TestParseCppFileConstants(t *testing.T) {
+	path := writeSource(t, `
+// EXPORT_CONST: int MAX = 100 -> "Largest supported size."
+#define MAX 100
+
+// EXPORT_CONST: const char* VERSION = "1.2";
+const char* VERSION = "1.2";
+
+// EXPORT: int add(int a, int b) -> "Adds two integers."
+int add(int a, int b) { return a + b; }
+`)
+
+	cfg, warnings, err := ParseCppFile(path, ParseOptions{})
+	if err != nil {
+		t.Fatalf("ParseCppFile() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+
+	want := []config.ConstantConfig{
+		{Name: "MAX", Type: "int", Value: "100", Description: "Largest supported size."},
+		{Name: "VERSION", Type: "const char*", Value: `"1.2"`},
+	}
+	if !reflect.DeepEqual(cfg.Constants, want) {
+		t.Errorf("ParseCppFile() constants = %+v, want %+v", cfg.Constants, want)
+	}
+	if len(cfg.Functions) != 1 {
+		t.Errorf("Expected 1 function, got %d", len(cfg.Functions))
+	}
+}
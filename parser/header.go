@@ -0,0 +1,110 @@
+package parser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"cp2p/config"
+)
+
+// headerExtensions are the file extensions ParseFile treats as headers
+var headerExtensions = map[string]bool{".h": true, ".hh": true, ".hpp": true, ".hxx": true}
+
+// ParseFile parses filePath as a header if it has a header extension and as a
+// C++ source with EXPORT comments otherwise. Headers never produce warnings.
+func ParseFile(filePath string, opts ParseOptions) (*config.Config, []Warning, error) {
+	if headerExtensions[strings.ToLower(filepath.Ext(filePath))] {
+		cfg, err := ParseHeaderFile(filePath)
+		if err == nil {
+			err = opts.apply(cfg)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		return cfg, nil, nil
+	}
+	return ParseCppFile(filePath, opts)
+}
+
+var (
+	commentRegex    = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+	directiveRegex  = regexp.MustCompile(`(?m)^[ \t]*#.*$`)
+	attributeRegex  = regexp.MustCompile(`__declspec\s*\([^)]*\)|__attribute__\s*\(\(.*?\)\)|\bCP2P_EXPORT\b`)
+	externBlockRe   = regexp.MustCompile(`extern\s+"C"\s*\{`)
+	externSingleRe  = regexp.MustCompile(`extern\s+"C"\s+([^{};]*;)`)
+	declarationRe   = regexp.MustCompile(`([\w\s*&:]*?[\w*&])\s*\b(\w+)\s*\(([^()]*)\)\s*;`)
+	storageKeywords = regexp.MustCompile(`\b(extern|static|inline)\s+`)
+)
+
+// ParseHeaderFile extracts the function prototypes declared extern "C" in a
+// C/C++ header, either inside an extern "C" { ... } block or on a single
+// extern "C" declaration. Unlike ParseCppFile it reads declarations, which
+// end in ";" rather than a body, so the implementation is compiled separately.
+func ParseHeaderFile(filePath string) (*config.Config, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %v", err)
+	}
+
+	src := commentRegex.ReplaceAllString(string(data), " ")
+	src = directiveRegex.ReplaceAllString(src, "")
+	src = attributeRegex.ReplaceAllString(src, " ")
+
+	var decls []string
+	for _, loc := range externBlockRe.FindAllStringIndex(src, -1) {
+		decls = append(decls, topLevel(src[loc[1]:]))
+	}
+	for _, m := range externSingleRe.FindAllStringSubmatch(src, -1) {
+		decls = append(decls, m[1])
+	}
+
+	var functions []config.FunctionConfig
+	for _, block := range decls {
+		for _, m := range declarationRe.FindAllStringSubmatch(block, -1) {
+			returnType := strings.TrimSpace(storageKeywords.ReplaceAllString(m[1], ""))
+			if returnType == "" || strings.HasPrefix(returnType, "typedef") || returnType == "return" {
+				continue
+			}
+			functions = append(functions, config.FunctionConfig{
+				Name:       m[2],
+				ReturnType: canonicalType(returnType),
+				Parameters: parseParameters(m[3]),
+			})
+		}
+	}
+
+	return &config.Config{
+		Functions:  functions,
+		Includes:   []string{},
+		Libraries:  []string{},
+		SourceFile: filePath,
+	}, nil
+}
+
+// topLevel returns the text of a brace block whose opening brace has already
+// been consumed, up to its closing brace. Nested blocks such as struct
+// definitions and inline function bodies are dropped, so that only the
+// block's own declarations remain.
+func topLevel(src string) string {
+	var b strings.Builder
+	depth := 0
+	for _, c := range src {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}' && depth == 0:
+			return b.String()
+		case c == '}':
+			depth--
+			if depth == 0 {
+				b.WriteByte(' ')
+			}
+		case depth == 0:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"cp2p/config"
+)
+
+func TestParseHeaderFile(t *testing.T) {
+	header := `#pragma once
+#include <stddef.h>
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+typedef struct { int x; } Point;
+
+/* Adds two integers. */
+CP2P_EXPORT int add(int a, int b);
+const char *greet(const char *name);  // returns a static string
+
+static inline int twice(int v) { return add(v, v); }
+
+#ifdef __cplusplus
+}
+#endif
+
+extern "C" __declspec(dllexport) size_t count(void);
+int not_exported(int a);
+`
+	path := filepath.Join(t.TempDir(), "api.h")
+	if err := os.WriteFile(path, []byte(header), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	cfg, _, err := ParseFile(path, ParseOptions{})
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	want := []config.FunctionConfig{
+		{Name: "add", ReturnType: "int", Parameters: []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}}},
+		{Name: "greet", ReturnType: "const char*", Parameters: []config.Param{{Name: "name", Type: "const char*"}}},
+		{Name: "count", ReturnType: "size_t", Parameters: []config.Param{}},
+	}
+	if !reflect.DeepEqual(cfg.Functions, want) {
+		t.Errorf("ParseHeaderFile() functions = %+v, want %+v", cfg.Functions, want)
+	}
+}
@@ -0,0 +1,125 @@
+// Package pipeline runs cp2p's whole build, from compiler detection through
+// compilation to binding generation, for programs using cp2p as a library.
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"cp2p/binding"
+	"cp2p/compiler"
+	"cp2p/config"
+	"cp2p/parser"
+)
+
+// BuildOptions controls Build
+type BuildOptions struct {
+	OutputDir      string                   // Directory for the library and bindings; a new temporary directory if empty
+	ModuleName     string                   // Python module name; defaults to the source file's base name
+	Compiler       compiler.CompilerType    // Compiler to detect; empty means compiler.CompilerAuto
+	CompileOptions *compiler.CompileOptions // Defaults to compiler.DefaultCompileOptions(); the config's includes, libraries and flags are added
+	Parse          parser.ParseOptions      // Used to read EXPORT comments when no config is given
+	Force          bool                     // Generate the bindings even if the config and library are unchanged since they were generated
+}
+
+// BuildResult describes what Build produced
+type BuildResult struct {
+	OutputDir   string                    // Directory everything was written to
+	LibraryPath string                    // Path of the compiled library
+	Compiler    *compiler.CompilerInfo    // Compiler that built the library, which differs from the detected one after a fallback
+	Bindings    *binding.GenerationResult // Nil for WebAssembly builds, which have no Python bindings
+	Warnings    []parser.Warning          // Problems found reading EXPORT comments
+	Files       []string                  // Every file produced: the library first, then the bindings
+	Skipped     bool                      // Whether binding generation was skipped because the bindings were up to date
+}
+
+// Build detects a compiler, compiles source into a shared library and
+// generates its Python bindings. If cfg is nil, the functions are read from
+// EXPORT comments in source. Bindings already generated from the same
+// config and library are kept unless opts.Force is set. Unlike the command line, Build reports every
+// failure as an error and never exits; cfg is not modified.
+func Build(cfg *config.Config, source string, opts BuildOptions) (*BuildResult, error) {
+	result := &BuildResult{OutputDir: opts.OutputDir}
+
+	if cfg == nil {
+		parsed, warnings, err := parser.ParseCppFile(source, opts.Parse)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse C++ file: %w", err)
+		}
+		cfg = parsed
+		result.Warnings = warnings
+	} else {
+		copied := *cfg
+		copied.SourceFile = source
+		cfg = &copied
+	}
+
+	compilerType := opts.Compiler
+	if compilerType == "" {
+		compilerType = compiler.CompilerAuto
+	}
+	detected, err := compiler.DetectCompiler(compilerType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect compiler: %w", err)
+	}
+
+	if result.OutputDir == "" {
+		result.OutputDir, err = os.MkdirTemp("", "cp2p-build-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output directory: %v", err)
+		}
+	}
+
+	compileOpts := compiler.DefaultCompileOptions()
+	if opts.CompileOptions != nil {
+		copied := *opts.CompileOptions
+		compileOpts = &copied
+	}
+	compileOpts.IncludePaths = slices.Concat(detected.CommandIncludePaths(), compileOpts.IncludePaths, cfg.AllIncludes())
+	compileOpts.Libraries = slices.Concat(compileOpts.Libraries, cfg.AllLibraries())
+	if cfg.OptimizationLevel != "" {
+		compileOpts.OptimizationLevel = cfg.OptimizationLevel
+	}
+	if cfg.PrecompiledHeader != "" {
+		compileOpts.PrecompiledHeader = cfg.PrecompiledHeader
+	}
+	compileOpts.ExtraFlags = slices.Concat(compileOpts.ExtraFlags, cfg.CompilerFlags)
+	compileOpts.LinkerFlags = slices.Concat(compileOpts.LinkerFlags, cfg.LinkerFlags)
+	compileOpts.ExportedFunctions = slices.Clone(compileOpts.ExportedFunctions)
+	for _, fn := range cfg.Functions {
+		compileOpts.ExportedFunctions = append(compileOpts.ExportedFunctions, fn.CSymbol())
+	}
+
+	result.LibraryPath, result.Compiler, err = compiler.CompileWithFallback(source, result.OutputDir, detected, compileOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile C++ code: %w", err)
+	}
+	result.Files = append(result.Files, result.LibraryPath)
+	cfg.BuildFiles = compiler.OutputFiles(source, result.OutputDir, result.Compiler)
+
+	// WebAssembly builds are loaded from JavaScript through the loader em++
+	// emits next to the module
+	if result.Compiler.Type == compiler.CompilerEmscripten {
+		result.Files = append(result.Files, strings.TrimSuffix(result.LibraryPath, filepath.Ext(result.LibraryPath))+".js")
+		return result, nil
+	}
+
+	moduleName := opts.ModuleName
+	if moduleName == "" {
+		moduleName = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	}
+	if !opts.Force {
+		result.Bindings, result.Skipped = binding.UpToDate(moduleName, result.LibraryPath, result.OutputDir, cfg)
+	}
+	if !result.Skipped {
+		result.Bindings, err = binding.GenerateBindings(moduleName, result.LibraryPath, result.OutputDir, cfg)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate Python bindings: %w", err)
+	}
+	result.Files = append(result.Files, result.Bindings.FilesWritten...)
+	return result, nil
+}
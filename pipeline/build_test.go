@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"cp2p/binding"
+	"cp2p/compiler"
+	"cp2p/config"
+)
+
+const testSource = `// EXPORT: int add(int a, int b) -> "Adds two integers."
+extern "C" int add(int a, int b) { return a + b; }
+`
+
+func writeSource(t *testing.T, dir string) string {
+	if _, err := compiler.DetectCompiler(compiler.CompilerAuto); err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+	src := filepath.Join(dir, "mathlib.cpp")
+	if err := os.WriteFile(src, []byte(testSource), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	return src
+}
+
+func TestBuild(t *testing.T) {
+	src := writeSource(t, t.TempDir())
+	outputDir := t.TempDir()
+
+	result, err := Build(nil, src, BuildOptions{OutputDir: outputDir})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if result.OutputDir != outputDir {
+		t.Errorf("OutputDir = %s, want %s", result.OutputDir, outputDir)
+	}
+	if len(result.Files) == 0 || result.Files[0] != result.LibraryPath {
+		t.Errorf("Files = %v, want the library %s first", result.Files, result.LibraryPath)
+	}
+	module := filepath.Join(outputDir, "mathlib.py")
+	if !slices.Contains(result.Files, module) {
+		t.Errorf("Files = %v, want %s", result.Files, module)
+	}
+	for _, path := range result.Files {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Reported file %s: %v", path, err)
+		}
+	}
+	if result.Bindings.FunctionsBound != 1 {
+		t.Errorf("FunctionsBound = %d, want 1", result.Bindings.FunctionsBound)
+	}
+
+	if _, err := binding.FindPython(); err == nil {
+		if err := binding.Verify(outputDir, "mathlib"); err != nil {
+			t.Errorf("Verify() error = %v", err)
+		}
+	}
+}
+
+func TestBuildConfig(t *testing.T) {
+	src := writeSource(t, t.TempDir())
+	cfg := &config.Config{
+		Functions: []config.FunctionConfig{{
+			Name:        "add",
+			ReturnType:  "int",
+			Description: "Adds two integers.",
+			Parameters:  []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+		}},
+	}
+
+	// Without an output directory, Build writes to a new temporary one
+	result, err := Build(cfg, src, BuildOptions{ModuleName: "fastmath"})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	defer os.RemoveAll(result.OutputDir)
+
+	if result.OutputDir == "" {
+		t.Fatal("OutputDir is empty")
+	}
+	if _, err := os.Stat(filepath.Join(result.OutputDir, "fastmath.py")); err != nil {
+		t.Errorf("Module not written: %v", err)
+	}
+	if cfg.SourceFile != "" {
+		t.Errorf("Build() modified the config's SourceFile to %q", cfg.SourceFile)
+	}
+}
+
+func TestBuildMissingSource(t *testing.T) {
+	if _, err := compiler.DetectCompiler(compiler.CompilerAuto); err != nil {
+		t.Skipf("No compiler available: %v", err)
+	}
+
+	cfg := &config.Config{}
+	_, err := Build(cfg, filepath.Join(t.TempDir(), "missing.cpp"), BuildOptions{OutputDir: t.TempDir()})
+	if !errors.Is(err, compiler.ErrSourceNotFound) {
+		t.Errorf("Build() error = %v, want ErrSourceNotFound", err)
+	}
+}
+
+func TestBuildSkipsUnchangedBindings(t *testing.T) {
+	src := writeSource(t, t.TempDir())
+	outputDir := t.TempDir()
+	cfg := &config.Config{
+		Functions: []config.FunctionConfig{{
+			Name:        "add",
+			ReturnType:  "int",
+			Description: "Adds two integers.",
+			Parameters:  []config.Param{{Name: "a", Type: "int"}, {Name: "b", Type: "int"}},
+		}},
+	}
+
+	build := func(cfg *config.Config, opts BuildOptions) *BuildResult {
+		opts.OutputDir = outputDir
+		result, err := Build(cfg, src, opts)
+		if err != nil {
+			t.Fatalf("Build() error = %v", err)
+		}
+		return result
+	}
+
+	if build(cfg, BuildOptions{}).Skipped {
+		t.Error("First Build() skipped generation")
+	}
+	second := build(cfg, BuildOptions{})
+	if !second.Skipped {
+		t.Error("Unchanged second Build() regenerated the bindings")
+	}
+	if second.Bindings == nil || second.Bindings.FunctionsBound != 1 {
+		t.Errorf("Skipped Build() Bindings = %+v, want the earlier result", second.Bindings)
+	}
+	if !slices.Contains(second.Files, filepath.Join(outputDir, "mathlib.py")) {
+		t.Errorf("Skipped Build() Files = %v, want the module", second.Files)
+	}
+
+	if build(cfg, BuildOptions{Force: true}).Skipped {
+		t.Error("Build() with Force skipped generation")
+	}
+
+	changed := *cfg
+	changed.Functions = slices.Clone(cfg.Functions)
+	changed.Functions[0].Description = "Returns a plus b."
+	if build(&changed, BuildOptions{}).Skipped {
+		t.Error("Build() with a changed config skipped generation")
+	}
+	module, err := os.ReadFile(filepath.Join(outputDir, "mathlib.py"))
+	if err != nil {
+		t.Fatalf("Failed to read module: %v", err)
+	}
+	if !strings.Contains(string(module), "Returns a plus b.") {
+		t.Error("Regenerated module lacks the changed description")
+	}
+}
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Locations a generated module searches for its shared library
+const (
+	LibSearchModule    = "module"    // Next to the module
+	LibSearchLibDir    = "lib"       // In a lib directory next to the module
+	LibSearchResources = "resources" // Among the package resources, for zipped imports
+	LibSearchEnv       = "env"       // In the directories listed by LibraryPathVar
+	LibSearchSystem    = "system"    // On the dynamic loader's default search path
+)
+
+// DefaultLibrarySearchOrder is the order generated modules search the
+// library locations in unless the config sets one
+var DefaultLibrarySearchOrder = []string{
+	LibSearchModule,
+	LibSearchLibDir,
+	LibSearchResources,
+	LibSearchEnv,
+	LibSearchSystem,
+}
+
+// ValidateLibrarySearchOrder checks that order names only known locations,
+// each at most once
+func ValidateLibrarySearchOrder(order []string) error {
+	for i, location := range order {
+		if !slices.Contains(DefaultLibrarySearchOrder, location) {
+			return fmt.Errorf("unknown library search location %q (expected one of %s)", location, strings.Join(DefaultLibrarySearchOrder, ", "))
+		}
+		if slices.Contains(order[:i], location) {
+			return fmt.Errorf("library search location %q listed twice", location)
+		}
+	}
+	return nil
+}
+
+// LibraryPathVar returns the environment variable listing library
+// directories on goos
+func LibraryPathVar(goos string) string {
+	switch goos {
+	case "windows":
+		return "PATH"
+	case "darwin":
+		return "DYLD_LIBRARY_PATH"
+	default:
+		return "LD_LIBRARY_PATH"
+	}
+}
+
+// LibraryCandidates returns the paths at which a module in moduleDir looks
+// for the library name, following order as the generated loader does.
+// Package resources and the loader's default path have no fixed file path,
+// so they are left out.
+func LibraryCandidates(moduleDir, name string, order []string) []string {
+	var candidates []string
+	for _, location := range order {
+		switch location {
+		case LibSearchModule:
+			candidates = append(candidates, filepath.Join(moduleDir, name))
+		case LibSearchLibDir:
+			candidates = append(candidates, filepath.Join(moduleDir, "lib", name))
+		case LibSearchEnv:
+			for _, dir := range filepath.SplitList(os.Getenv(LibraryPathVar(runtime.GOOS))) {
+				if dir != "" {
+					candidates = append(candidates, filepath.Join(dir, name))
+				}
+			}
+		}
+	}
+	return candidates
+}
+
+// FindLibrary returns the first of LibraryCandidates that exists, or an
+// error listing every path tried
+func FindLibrary(moduleDir, name string, order []string) (string, error) {
+	candidates := LibraryCandidates(moduleDir, name, order)
+	for _, path := range candidates {
+		if FileExists(path) {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("library %s not found; tried: %s", name, strings.Join(candidates, ", "))
+}
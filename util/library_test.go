@@ -0,0 +1,74 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+)
+
+func TestFindLibrary(t *testing.T) {
+	moduleDir := t.TempDir()
+	envDir := t.TempDir()
+	pathVar := LibraryPathVar(runtime.GOOS)
+	origPath := os.Getenv(pathVar)
+	defer os.Setenv(pathVar, origPath)
+	os.Setenv(pathVar, envDir)
+
+	name := "libmath" + GetLibraryExtension()
+	want := []string{
+		filepath.Join(moduleDir, name),
+		filepath.Join(moduleDir, "lib", name),
+		filepath.Join(envDir, name),
+	}
+	if got := LibraryCandidates(moduleDir, name, DefaultLibrarySearchOrder); !slices.Equal(got, want) {
+		t.Errorf("LibraryCandidates() = %v, want %v", got, want)
+	}
+
+	if _, err := FindLibrary(moduleDir, name, DefaultLibrarySearchOrder); err == nil {
+		t.Error("FindLibrary() with no library should fail")
+	}
+
+	// Later locations are used when earlier ones have no library
+	if err := os.WriteFile(want[2], nil, 0644); err != nil {
+		t.Fatalf("Failed to write library: %v", err)
+	}
+	if got, err := FindLibrary(moduleDir, name, DefaultLibrarySearchOrder); err != nil || got != want[2] {
+		t.Errorf("FindLibrary() = %s, %v, want %s", got, err, want[2])
+	}
+
+	if err := os.MkdirAll(filepath.Dir(want[1]), 0755); err != nil {
+		t.Fatalf("Failed to create lib directory: %v", err)
+	}
+	if err := os.WriteFile(want[1], nil, 0644); err != nil {
+		t.Fatalf("Failed to write library: %v", err)
+	}
+	if got, err := FindLibrary(moduleDir, name, DefaultLibrarySearchOrder); err != nil || got != want[1] {
+		t.Errorf("FindLibrary() = %s, %v, want %s", got, err, want[1])
+	}
+
+	// The order decides which copy wins
+	order := []string{LibSearchEnv, LibSearchLibDir}
+	if got, err := FindLibrary(moduleDir, name, order); err != nil || got != want[2] {
+		t.Errorf("FindLibrary(%v) = %s, %v, want %s", order, got, err, want[2])
+	}
+}
+
+func TestValidateLibrarySearchOrder(t *testing.T) {
+	tests := []struct {
+		order   []string
+		wantErr bool
+	}{
+		{DefaultLibrarySearchOrder, false},
+		{[]string{LibSearchLibDir, LibSearchModule}, false},
+		{nil, false},
+		{[]string{"cwd"}, true},
+		{[]string{LibSearchModule, LibSearchModule}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidateLibrarySearchOrder(tt.order); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateLibrarySearchOrder(%v) error = %v, wantErr %v", tt.order, err, tt.wantErr)
+		}
+	}
+}
@@ -2,22 +2,65 @@ package util
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 )
 
+// ANSI color codes for level tags
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorYellow = "\033[33m"
+	colorBlue   = "\033[34m"
+	colorGray   = "\033[90m"
+)
+
 // Logger provides logging functionality for the application
 type Logger struct {
 	*log.Logger
 	debug bool
+	color bool
 }
 
 // NewLogger creates a new logger instance
 func NewLogger() *Logger {
+	return NewLoggerWithWriter(os.Stdout)
+}
+
+// NewLoggerWithWriter creates a logger writing to w. Level tags are colored
+// only when w is a terminal and NO_COLOR is not set.
+func NewLoggerWithWriter(w io.Writer) *Logger {
 	return &Logger{
-		Logger: log.New(os.Stdout, "", log.LstdFlags),
+		Logger: log.New(w, "", log.LstdFlags),
 		debug:  false,
+		color:  supportsColor(w),
+	}
+}
+
+// supportsColor reports whether ANSI colors should be written to w
+func supportsColor(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// tag returns the level tag, wrapped in color when enabled
+func (l *Logger) tag(level, color string) string {
+	if l.color {
+		return color + "[" + level + "]" + colorReset + " "
 	}
+	return "[" + level + "] "
 }
 
 // SetDebug enables or disables debug logging
@@ -28,28 +71,28 @@ func (l *Logger) SetDebug(debug bool) {
 // Debug logs a debug message
 func (l *Logger) Debug(format string, v ...interface{}) {
 	if l.debug {
-		l.Printf("[DEBUG] "+format, v...)
+		l.Printf(l.tag("DEBUG", colorGray)+format, v...)
 	}
 }
 
 // Info logs an info message
 func (l *Logger) Info(format string, v ...interface{}) {
-	l.Printf("[INFO] "+format, v...)
+	l.Printf(l.tag("INFO", colorBlue)+format, v...)
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(format string, v ...interface{}) {
-	l.Printf("[WARN] "+format, v...)
+	l.Printf(l.tag("WARN", colorYellow)+format, v...)
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, v ...interface{}) {
-	l.Printf("[ERROR] "+format, v...)
+	l.Printf(l.tag("ERROR", colorRed)+format, v...)
 }
 
 // Fatal logs a fatal message and exits
 func (l *Logger) Fatal(format string, v ...interface{}) {
-	l.Printf("[FATAL] "+format, v...)
+	l.Printf(l.tag("FATAL", colorRed)+format, v...)
 	os.Exit(1)
 }
 
@@ -0,0 +1,37 @@
+package util
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLoggerNoColorForNonTerminal(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLoggerWithWriter(&buf)
+	logger.SetDebug(true)
+
+	logger.Debug("debug %d", 1)
+	logger.Info("info %d", 2)
+	logger.Warn("warn %d", 3)
+	logger.Error("error %d", 4)
+
+	output := buf.String()
+	if strings.Contains(output, "\033[") {
+		t.Errorf("Expected no ANSI codes in non-terminal output, got %q", output)
+	}
+	for _, expected := range []string{"[DEBUG] debug 1", "[INFO] info 2", "[WARN] warn 3", "[ERROR] error 4"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Output missing %q", expected)
+		}
+	}
+}
+
+func TestLoggerColorTag(t *testing.T) {
+	logger := NewLoggerWithWriter(&bytes.Buffer{})
+	logger.color = true
+
+	if tag := logger.tag("ERROR", colorRed); tag != colorRed+"[ERROR]"+colorReset+" " {
+		t.Errorf("Unexpected colored tag %q", tag)
+	}
+}
@@ -1,16 +1,65 @@
 package util
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 )
 
+var (
+	// ErrNotDirectory is returned when a path expected to be a directory is something else
+	ErrNotDirectory = errors.New("path exists but is not a directory")
+	// ErrDirNotWritable is returned when files cannot be created in a directory
+	ErrDirNotWritable = errors.New("directory is not writable")
+)
+
 // EnsureDir creates a directory if it doesn't exist
 func EnsureDir(path string) error {
 	return os.MkdirAll(path, 0755)
 }
 
+// EnsureWritableDir creates a directory if it doesn't exist and checks that
+// files can be created in it. It distinguishes a non-directory in the way
+// (ErrNotDirectory) from missing permissions (ErrDirNotWritable or os.ErrPermission).
+func EnsureWritableDir(path string) error {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return fmt.Errorf("%s: %w", path, ErrNotDirectory)
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("cannot create directory %s: %w", path, err)
+	}
+
+	probe, err := os.CreateTemp(path, ".cp2p-write-check-*")
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, ErrDirNotWritable)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+
+	return nil
+}
+
+// FileSHA256 returns the hex-encoded SHA-256 digest of the file at path
+func FileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // GetExecutableDir returns the directory containing the current executable
 func GetExecutableDir() (string, error) {
 	exe, err := os.Executable()
@@ -79,3 +128,83 @@ func IsDir(path string) bool {
 func GetTempDir() string {
 	return os.TempDir()
 }
+
+// CacheDirEnv names the environment variable that overrides GetCacheDir
+const CacheDirEnv = "CP2P_CACHE_DIR"
+
+// GetCacheDir returns the directory for cached artifacts such as compiler
+// detection results and build hashes. It honors CP2P_CACHE_DIR, falling back
+// to a cp2p directory in the user cache dir, or the temp dir if there is none.
+// The directory is not created.
+func GetCacheDir() string {
+	if dir := os.Getenv(CacheDirEnv); dir != "" {
+		return dir
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "cp2p")
+	}
+	return filepath.Join(os.TempDir(), "cp2p-cache")
+}
+
+// WriteFileAtomic writes a file by streaming into a temporary file in the same
+// directory and renaming it into place, so readers never see a partial file.
+// If write fails, the temporary file is removed and any existing file is left as is.
+func WriteFileAtomic(path string, perm os.FileMode, write func(io.Writer) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to set permissions on %s: %w", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move %s into place: %w", path, err)
+	}
+
+	return nil
+}
+
+// newlineWriter converts line endings as it writes; see NewlineWriter
+type newlineWriter struct {
+	w    io.Writer
+	crlf bool
+	buf  []byte
+}
+
+// NewlineWriter returns a writer that passes text to w with every line ending
+// written as "\n", or "\r\n" if crlf is set, however it was written to it.
+// Carriage returns are dropped, so mixed "\n" and "\r\n" endings come out
+// consistent.
+func NewlineWriter(w io.Writer, crlf bool) io.Writer {
+	return &newlineWriter{w: w, crlf: crlf}
+}
+
+func (nw *newlineWriter) Write(p []byte) (int, error) {
+	nw.buf = nw.buf[:0]
+	for _, b := range p {
+		switch {
+		case b == '\r':
+		case b == '\n' && nw.crlf:
+			nw.buf = append(nw.buf, '\r', '\n')
+		default:
+			nw.buf = append(nw.buf, b)
+		}
+	}
+	if _, err := nw.w.Write(nw.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
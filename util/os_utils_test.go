@@ -0,0 +1,171 @@
+package util
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestEnsureWritableDir(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Missing directories are created
+	dir := filepath.Join(tmpDir, "a", "b")
+	if err := EnsureWritableDir(dir); err != nil {
+		t.Fatalf("EnsureWritableDir() error = %v", err)
+	}
+	if !IsDir(dir) {
+		t.Errorf("Expected %s to be created", dir)
+	}
+
+	// Existing directories are accepted
+	if err := EnsureWritableDir(dir); err != nil {
+		t.Errorf("EnsureWritableDir() on existing dir error = %v", err)
+	}
+
+	// The write check leaves nothing behind
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected empty directory, found %d entries", len(entries))
+	}
+}
+
+func TestEnsureWritableDirFileExists(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bindings")
+	if err := os.WriteFile(file, []byte("not a dir"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if err := EnsureWritableDir(file); !errors.Is(err, ErrNotDirectory) {
+		t.Errorf("EnsureWritableDir() error = %v, want ErrNotDirectory", err)
+	}
+}
+
+func TestEnsureWritableDirUnwritable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Directory permission bits are not enforced on Windows")
+	}
+	if os.Geteuid() == 0 {
+		t.Skip("Permission checks do not apply to root")
+	}
+
+	dir := filepath.Join(t.TempDir(), "readonly")
+	if err := os.Mkdir(dir, 0555); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	defer os.Chmod(dir, 0755)
+
+	if err := EnsureWritableDir(dir); !errors.Is(err, ErrDirNotWritable) {
+		t.Errorf("EnsureWritableDir() error = %v, want ErrDirNotWritable", err)
+	}
+
+	if err := EnsureWritableDir(filepath.Join(dir, "child")); !errors.Is(err, os.ErrPermission) {
+		t.Errorf("EnsureWritableDir() error = %v, want os.ErrPermission", err)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.py")
+
+	err := WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		_, err := io.WriteString(w, "complete")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WriteFileAtomic() error = %v", err)
+	}
+
+	// A failed write keeps the previous content and leaves no temporary file
+	writeErr := errors.New("write failed")
+	err = WriteFileAtomic(path, 0644, func(w io.Writer) error {
+		io.WriteString(w, "partial")
+		return writeErr
+	})
+	if !errors.Is(err, writeErr) {
+		t.Errorf("WriteFileAtomic() error = %v, want %v", err, writeErr)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	if string(content) != "complete" {
+		t.Errorf("Expected previous content to survive, got %q", content)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Failed to read directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only the output file, found %d entries", len(entries))
+	}
+}
+
+func TestNewlineWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		crlf  bool
+		want  string
+	}{
+		{"lf unchanged", "a\nb\n", false, "a\nb\n"},
+		{"crlf to lf", "a\r\nb\r\n", false, "a\nb\n"},
+		{"mixed to lf", "a\r\nb\nc", false, "a\nb\nc"},
+		{"mixed to crlf", "a\r\nb\nc", true, "a\r\nb\r\nc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			n, err := NewlineWriter(&out, tt.crlf).Write([]byte(tt.input))
+			if err != nil || n != len(tt.input) {
+				t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(tt.input))
+			}
+			if out.String() != tt.want {
+				t.Errorf("Write() wrote %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestGetCacheDir(t *testing.T) {
+	origCache := os.Getenv(CacheDirEnv)
+	defer os.Setenv(CacheDirEnv, origCache)
+
+	os.Setenv(CacheDirEnv, "/custom/cache")
+	if dir := GetCacheDir(); dir != "/custom/cache" {
+		t.Errorf("GetCacheDir() = %s, want /custom/cache", dir)
+	}
+
+	os.Unsetenv(CacheDirEnv)
+	if dir := GetCacheDir(); filepath.Base(dir) != "cp2p" && filepath.Base(dir) != "cp2p-cache" {
+		t.Errorf("GetCacheDir() = %s, want a cp2p directory", dir)
+	}
+}
+
+func TestFileSHA256(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("abc"), 0644); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	got, err := FileSHA256(path)
+	if err != nil {
+		t.Fatalf("FileSHA256() error = %v", err)
+	}
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; got != want {
+		t.Errorf("FileSHA256() = %s, want %s", got, want)
+	}
+
+	if _, err := FileSHA256(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}